@@ -3,14 +3,21 @@ module github.com/utr1903/newrelic-kubernetes-endpoint-scraper
 go 1.18
 
 require (
+	github.com/antchfx/xmlquery v1.3.17
+	github.com/antchfx/xpath v1.2.4
+	github.com/jmespath/go-jmespath v0.4.0
+	github.com/klauspost/compress v1.16.7
 	github.com/sirupsen/logrus v1.9.0
 	github.com/stretchr/testify v1.8.1
+	golang.org/x/net v0.7.0
 	gopkg.in/yaml.v2 v2.4.0
 )
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/golang/groupcache v0.0.0-20210331224755-41bb18bfe9da // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	golang.org/x/sys v0.0.0-20220715151400-c0bba94af5f8 // indirect
+	golang.org/x/sys v0.5.0 // indirect
+	golang.org/x/text v0.7.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -1,28 +1,54 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
 
 	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/config"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/delta"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/discover"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/enrich"
 	forwarder "github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/forward"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/parse"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/rate"
 	scraper "github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/scrape"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/state"
 )
 
 func main() {
 
+	// Print the documentation of the endpoint types if requested
+	parserDocs := flag.Bool("parser-docs", false, "print the documentation of the endpoint types")
+	flag.Parse()
+	if *parserDocs {
+		err := parse.WriteDocs(os.Stdout)
+		if err != nil {
+			panic(err)
+		}
+		return
+	}
+
 	// Parse and create config
 	cfg, err := config.NewConfig()
 	if err != nil {
 		panic(err)
 	}
 
-	// Scrape endpoints
-	scraper := scraper.NewScraper(cfg)
-	evs := scraper.Run()
+	// Run continuously if daemon mode is enabled
+	if cfg.Daemon.Enabled {
+		runDaemon(cfg)
+		return
+	}
 
-	// Forward endpoint values to New Relic
-	forwarder := forwarder.NewForwarder(cfg, evs)
-	err = forwarder.Run()
+	// Scrape and forward once
+	err = run(cfg, state.NewStore(cfg), enrich.NewCache())
 	if err != nil {
 		panic(err)
 	}
@@ -33,3 +59,83 @@ func main() {
 		fmt.Println(err)
 	}
 }
+
+func run(
+	cfg *config.Config,
+	store state.Store,
+	cache *enrich.Cache,
+) error {
+
+	// Add the endpoints of the discovery rules
+	cfg = discover.NewDiscoverer(cfg).Run()
+
+	// Scrape endpoints
+	scraper := scraper.NewScraper(cfg)
+	evs := scraper.Run()
+
+	// Compute the rates of the counters
+	evs = rate.NewCalculator(cfg, store).Run(evs)
+
+	// Drop the values which have not changed
+	evs, commitDelta := delta.NewDetector(cfg, store).Run(evs)
+
+	// Add the Kubernetes metadata of the targets
+	evs = enrich.NewEnricher(cfg, cache).Run(evs)
+
+	// Send endpoint values to all sinks
+	sinks := forwarder.NewSinks(cfg, evs)
+	err := forwarder.RunSinks(cfg, sinks)
+	if err != nil {
+		return err
+	}
+
+	// Remember the forwarded values only once they are sent
+	commitDelta()
+	return nil
+}
+
+func runDaemon(
+	cfg *config.Config,
+) {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Watch the config file for changes
+	watcher := config.NewWatcher(cfg)
+	go watcher.Run(ctx.Done())
+
+	// Keep the state across the runs
+	var store state.Store
+	var stateInput config.StateInput
+
+	// Keep the Kubernetes metadata of the targets across the runs
+	cache := enrich.NewCache()
+
+	for {
+		// Always work with the latest valid config
+		cfg := watcher.Config()
+
+		// Switch the state if its backend has changed
+		if store == nil || *cfg.State != stateInput {
+			store = state.NewStore(cfg)
+			stateInput = *cfg.State
+		}
+
+		err := run(cfg, store, cache)
+		if err != nil {
+			cfg.Logger.Log(logrus.ErrorLevel, err.Error())
+		}
+
+		// Send the app logs to New Relic
+		err = cfg.Logger.Flush()
+		if err != nil {
+			fmt.Println(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(cfg.Daemon.Interval):
+		}
+	}
+}
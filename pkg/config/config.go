@@ -4,35 +4,114 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"net/http"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
 
 	yaml "gopkg.in/yaml.v2"
 
 	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/logging"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/parse"
 )
 
 type Endpoint struct {
-	Type string `yaml:"type"`
-	Name string `yaml:"name"`
-	URL  string `yaml:"url"`
+	Type         string                 `yaml:"type"`
+	Name         string                 `yaml:"name"`
+	URL          string                 `yaml:"url"`
+	Socket       *SocketInput           `yaml:"socket,omitempty"`
+	Exec         *ExecInput             `yaml:"exec,omitempty"`
+	Kubernetes   *KubernetesInput       `yaml:"kubernetes,omitempty"`
+	Options      map[string]interface{} `yaml:"options,omitempty"`
+	Destinations []string               `yaml:"destinations,omitempty"`
+	Delta        *DeltaInput            `yaml:"delta,omitempty"`
+	Counters     []CounterInput         `yaml:"counters,omitempty"`
+	Identity     []string               `yaml:"identity,omitempty"`
+	Derive       []DeriveInput          `yaml:"derive,omitempty"`
 }
 
 type NewRelicInput struct {
-	LogLevel       string `default:"ERROR" yaml:"logLevel"`
-	LogForwarding  bool   `default:"true" yaml:"logForwarding"`
-	EventsEndpoint string
-	LogsEndpoint   string
-	LicenseKey     string
+	LogLevel      string `default:"ERROR" yaml:"logLevel"`
+	LogForwarding bool   `default:"true" yaml:"logForwarding"`
+	LicenseKey    string
+
+	// Region can be: US, EU, FedRAMP. If not defined, it is derived
+	// from the license key.
+	Region string `yaml:"region"`
+
+	// Full URLs which override the endpoints of the region
+	EventsEndpoint  string `yaml:"eventsEndpoint"`
+	LogsEndpoint    string `yaml:"logsEndpoint"`
+	MetricsEndpoint string `yaml:"metricsEndpoint"`
+
+	// Further accounts which endpoints can be routed to
+	Destinations []NewRelicDestinationInput `yaml:"destinations"`
+	Accounts     map[string]*NewRelicInput  `yaml:"-"`
+}
+
+type DaemonInput struct {
+	Enabled        bool          `default:"false" yaml:"enabled"`
+	Interval       time.Duration `default:"60s" yaml:"interval"`
+	ReloadInterval time.Duration `default:"10s" yaml:"reloadInterval"`
+}
+
+// Directory which the batches that could not be sent to New Relic
+// are written into. They are replayed on the next run.
+type SpoolInput struct {
+	Enabled   bool          `default:"false" yaml:"enabled"`
+	Directory string        `yaml:"directory"`
+	MaxAge    time.Duration `default:"24h" yaml:"maxAge"`
+	MaxBytes  int64         `default:"52428800" yaml:"maxBytes"`
+}
+
+// Limits of the responses of the scraped endpoints
+type ScrapeInput struct {
+	// Maximum size of a response body after decompression
+	MaxBodySize int64 `default:"10485760" yaml:"maxBodySize"`
+}
+
+type OtlpInput struct {
+	Enabled  bool              `default:"false" yaml:"enabled"`
+	Endpoint string            `yaml:"endpoint"`
+	Protocol string            `default:"http/protobuf" yaml:"protocol"`
+	Signal   string            `default:"metrics" yaml:"signal"`
+	Headers  map[string]string `yaml:"headers"`
 }
 
 type Config struct {
-	Newrelic  *NewRelicInput `yaml:"newrelic"`
-	Endpoints []Endpoint     `yaml:"endpoints"`
+	Newrelic  *NewRelicInput   `yaml:"newrelic"`
+	Daemon    *DaemonInput     `yaml:"daemon"`
+	Scrape    *ScrapeInput     `yaml:"scrape"`
+	Otlp      *OtlpInput       `yaml:"otlp"`
+	Spool     *SpoolInput      `yaml:"spool"`
+	State     *StateInput      `yaml:"state"`
+	Proxy     *ProxyInput      `yaml:"proxy"`
+	Sinks     []SinkInput      `yaml:"sinks"`
+	Endpoints []Endpoint       `yaml:"endpoints"`
+	Discovery []DiscoveryInput `yaml:"discovery"`
+	Enrich    *EnrichInput     `yaml:"enrich"`
 	Logger    *logging.Logger
+
+	// Transport of the outbound clients, nil if no proxy is defined
+	Transport http.RoundTripper `yaml:"-"`
 }
 
+const (
+	defaultDaemonInterval       = 60 * time.Second
+	defaultDaemonReloadInterval = 10 * time.Second
+	defaultSpoolMaxAge          = 24 * time.Hour
+	defaultSpoolMaxBytes        = 50 * 1024 * 1024
+
+	DefaultScrapeMaxBodySize = 10 * 1024 * 1024
+
+	OtlpProtocolProtobuf = "http/protobuf"
+	OtlpProtocolJson     = "http/json"
+	OtlpSignalMetrics    = "metrics"
+	OtlpSignalLogs       = "logs"
+)
+
 var getEnv = func(
 	name string,
 ) string {
@@ -97,15 +176,17 @@ func parseConfigFile() (
 	}
 	cfg.Newrelic.LicenseKey = licenseKey
 
-	// Set New Relic events endpoint
-	eventsEndpoint, err := setNewRelicEventsEndpoint(cfg.Newrelic.LicenseKey)
+	// Set New Relic endpoints
+	err = setNewRelicEndpoints(cfg.Newrelic)
 	if err != nil {
 		return nil, err
 	}
-	cfg.Newrelic.EventsEndpoint = eventsEndpoint
 
-	// Set New Relic logs endpoints
-	cfg.Newrelic.LogsEndpoint = setNewRelicLogsEndpoint(cfg.Newrelic.LicenseKey)
+	// Create transport of the outbound clients
+	err = checkProxy(&cfg)
+	if err != nil {
+		return nil, err
+	}
 
 	// Create logger
 	if cfg.Newrelic.LogForwarding {
@@ -113,6 +194,7 @@ func parseConfigFile() (
 			cfg.Newrelic.LogLevel,
 			cfg.Newrelic.LicenseKey,
 			cfg.Newrelic.LogsEndpoint,
+			cfg.Transport,
 		)
 	} else {
 		cfg.Logger = logging.NewLogger(
@@ -120,12 +202,66 @@ func parseConfigFile() (
 		)
 	}
 
+	// Check if daemon mode is defined correctly
+	err = checkDaemon(&cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check if scrape limits are defined correctly
+	err = checkScrape(&cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check if metadata enrichment is defined correctly
+	err = checkEnrich(&cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check if state is defined correctly
+	err = checkState(&cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check if spool is defined correctly
+	err = checkSpool(&cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check if OTLP export is defined correctly
+	err = checkOtlp(&cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check if New Relic destinations are defined correctly
+	err = checkDestinations(&cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check if sinks are defined correctly
+	err = checkSinks(&cfg)
+	if err != nil {
+		return nil, err
+	}
+
 	// Check if endpoints are defined correctly
 	err = checkEndpoints(&cfg)
 	if err != nil {
 		return nil, err
 	}
 
+	// Check if discovery rules are defined correctly
+	err = checkDiscovery(&cfg)
+	if err != nil {
+		return nil, err
+	}
+
 	return &cfg, nil
 }
 
@@ -142,8 +278,49 @@ func parseNewRelicLicenseKey() (
 	return nrLicenseKey, nil
 }
 
+func setNewRelicEndpoints(
+	nr *NewRelicInput,
+) error {
+
+	// Determine the region
+	region, err := parseNewRelicRegion(nr.Region, nr.LicenseKey)
+	if err != nil {
+		fmt.Println(err.Error())
+		return err
+	}
+	nr.Region = region
+
+	// Check the overrides
+	for _, endpoint := range []string{nr.EventsEndpoint, nr.LogsEndpoint, nr.MetricsEndpoint} {
+		if endpoint == "" {
+			continue
+		}
+		err = checkNewRelicEndpointOverride(endpoint)
+		if err != nil {
+			fmt.Println(err.Error())
+			return err
+		}
+	}
+
+	// Use the endpoints of the region if not overridden
+	if nr.EventsEndpoint == "" {
+		nr.EventsEndpoint, err = setNewRelicEventsEndpoint(region)
+		if err != nil {
+			return err
+		}
+	}
+	if nr.LogsEndpoint == "" {
+		nr.LogsEndpoint = setNewRelicLogsEndpoint(region)
+	}
+	if nr.MetricsEndpoint == "" {
+		nr.MetricsEndpoint = setNewRelicMetricsEndpoint(region)
+	}
+
+	return nil
+}
+
 func setNewRelicEventsEndpoint(
-	licenseKey string,
+	region string,
 ) (
 	string,
 	error,
@@ -155,42 +332,236 @@ func setNewRelicEventsEndpoint(
 		return "", errors.New(logging.CONFIG__ACCOUNT_ID_IS_NOT_PROVIDED)
 	}
 
-	if licenseKey[0:2] == "eu" {
-		return "https://insights-collector.eu01.nr-data.net/v1/accounts/" + nrAccountId + "/events", nil
-	} else {
-		return "https://insights-collector.nr-data.net/v1/accounts/" + nrAccountId + "/events", nil
-	}
+	return newRelicEventsEndpoint(region, nrAccountId), nil
 }
 
 func setNewRelicLogsEndpoint(
-	licenseKey string,
+	region string,
 ) string {
-	if licenseKey[0:2] == "eu" {
-		return "https://log-api.eu.newrelic.com/log/v1"
-	} else {
-		return "https://log-api.newrelic.com/log/v1"
+	return newRelicRegions[region].logsEndpoint
+}
+
+func setNewRelicMetricsEndpoint(
+	region string,
+) string {
+	return newRelicRegions[region].metricsEndpoint
+}
+
+func checkDaemon(
+	cfg *Config,
+) error {
+	if cfg.Daemon == nil {
+		cfg.Daemon = &DaemonInput{}
+	}
+
+	if cfg.Daemon.Interval < 0 || cfg.Daemon.ReloadInterval < 0 {
+		cfg.Logger.Log(logrus.ErrorLevel, logging.CONFIG__DAEMON_INTERVAL_IS_INVALID)
+		return errors.New(logging.CONFIG__DAEMON_INTERVAL_IS_INVALID)
+	}
+
+	if cfg.Daemon.Interval == 0 {
+		cfg.Daemon.Interval = defaultDaemonInterval
+	}
+	if cfg.Daemon.ReloadInterval == 0 {
+		cfg.Daemon.ReloadInterval = defaultDaemonReloadInterval
+	}
+
+	return nil
+}
+
+func checkScrape(
+	cfg *Config,
+) error {
+	if cfg.Scrape == nil {
+		cfg.Scrape = &ScrapeInput{}
+	}
+
+	if cfg.Scrape.MaxBodySize < 0 {
+		cfg.Logger.Log(logrus.ErrorLevel, logging.CONFIG__SCRAPE_MAX_BODY_SIZE_IS_INVALID)
+		return errors.New(logging.CONFIG__SCRAPE_MAX_BODY_SIZE_IS_INVALID)
+	}
+
+	if cfg.Scrape.MaxBodySize == 0 {
+		cfg.Scrape.MaxBodySize = DefaultScrapeMaxBodySize
 	}
+
+	return nil
+}
+
+func checkSpool(
+	cfg *Config,
+) error {
+	if cfg.Spool == nil {
+		cfg.Spool = &SpoolInput{}
+	}
+	if !cfg.Spool.Enabled {
+		return nil
+	}
+
+	if cfg.Spool.Directory == "" || cfg.Spool.MaxAge < 0 || cfg.Spool.MaxBytes < 0 {
+		cfg.Logger.Log(logrus.ErrorLevel, logging.CONFIG__SPOOL_IS_INVALID)
+		return errors.New(logging.CONFIG__SPOOL_IS_INVALID)
+	}
+
+	if cfg.Spool.MaxAge == 0 {
+		cfg.Spool.MaxAge = defaultSpoolMaxAge
+	}
+	if cfg.Spool.MaxBytes == 0 {
+		cfg.Spool.MaxBytes = defaultSpoolMaxBytes
+	}
+
+	return nil
+}
+
+func checkOtlp(
+	cfg *Config,
+) error {
+	if cfg.Otlp == nil {
+		cfg.Otlp = &OtlpInput{}
+	}
+	return checkOtlpInput(cfg, cfg.Otlp)
+}
+
+func checkOtlpInput(
+	cfg *Config,
+	otlp *OtlpInput,
+) error {
+	if otlp.Protocol == "" {
+		otlp.Protocol = OtlpProtocolProtobuf
+	}
+	if otlp.Protocol != OtlpProtocolProtobuf && otlp.Protocol != OtlpProtocolJson {
+		cfg.Logger.Log(logrus.ErrorLevel, logging.CONFIG__OTLP_PROTOCOL_IS_NOT_SUPPORTED)
+		return errors.New(logging.CONFIG__OTLP_PROTOCOL_IS_NOT_SUPPORTED)
+	}
+
+	if otlp.Signal == "" {
+		otlp.Signal = OtlpSignalMetrics
+	}
+	if otlp.Signal != OtlpSignalMetrics && otlp.Signal != OtlpSignalLogs {
+		cfg.Logger.Log(logrus.ErrorLevel, logging.CONFIG__OTLP_SIGNAL_IS_NOT_SUPPORTED)
+		return errors.New(logging.CONFIG__OTLP_SIGNAL_IS_NOT_SUPPORTED)
+	}
+
+	if otlp.Headers == nil {
+		otlp.Headers = make(map[string]string)
+	}
+
+	// Send to the OTLP endpoint of New Relic by default
+	if otlp.Endpoint == "" {
+		otlp.Endpoint = cfg.Newrelic.MetricsEndpoint
+		otlp.Headers["api-key"] = cfg.Newrelic.LicenseKey
+	}
+
+	return nil
 }
 
 func checkEndpoints(
 	cfg *Config,
 ) error {
-	if cfg.Endpoints == nil || len(cfg.Endpoints) == 0 {
+	if len(cfg.Endpoints) == 0 && len(cfg.Discovery) == 0 {
 		cfg.Logger.Log(logrus.ErrorLevel, logging.CONFIG__NO_ENDPOINT_IS_DEFINED)
 		return errors.New(logging.CONFIG__NO_ENDPOINT_IS_DEFINED)
 	}
 
-	for _, endpoint := range cfg.Endpoints {
-		if endpoint.Type == "" || endpoint.Name == "" || endpoint.URL == "" {
-			cfg.Logger.Log(logrus.ErrorLevel, logging.CONFIG__ENDPOINT_INFO_IS_MISSING)
-			return errors.New(logging.CONFIG__ENDPOINT_INFO_IS_MISSING)
+	for i := range cfg.Endpoints {
+		if err := checkEndpoint(cfg, &cfg.Endpoints[i], false); err != nil {
+			return err
 		}
+	}
+
+	return nil
+}
+
+// Checks an endpoint of the config. The name and the URL of a
+// discovery template are checked once they are rendered.
+func checkEndpoint(
+	cfg *Config,
+	endpoint *Endpoint,
+	template bool,
+) error {
+	// Exec endpoints are identified by their pods and command,
+	// kubernetes endpoints by their objects
+	if endpoint.Exec != nil && endpoint.URL == "" {
+		endpoint.URL = endpoint.Exec.URL()
+	}
+	if endpoint.Kubernetes != nil && endpoint.URL == "" {
+		endpoint.URL = endpoint.Kubernetes.URL()
+	}
+
+	if endpoint.Type == "" || endpoint.Name == "" || endpoint.URL == "" {
+		cfg.Logger.Log(logrus.ErrorLevel, logging.CONFIG__ENDPOINT_INFO_IS_MISSING)
+		return errors.New(logging.CONFIG__ENDPOINT_INFO_IS_MISSING)
+	}
+
+	if _, ok := parse.Lookup(endpoint.Type); !ok {
+		cfg.Logger.LogWithFields(logrus.ErrorLevel, logging.CONFIG__ENDPOINT_TYPE_IS_NOT_SUPPORTED,
+			map[string]string{
+				"endpointType":   endpoint.Type,
+				"endpointName":   endpoint.Name,
+				"supportedTypes": strings.Join(parse.Types(), ","),
+			})
+		return errors.New(logging.CONFIG__ENDPOINT_TYPE_IS_NOT_SUPPORTED)
+	}
 
-		if endpoint.Type != "kvp" {
-			cfg.Logger.Log(logrus.ErrorLevel, logging.CONFIG__ENDPOINT_TYPE_IS_NOT_SUPPORTED)
-			return errors.New(logging.CONFIG__ENDPOINT_TYPE_IS_NOT_SUPPORTED)
+	// Check if the endpoint can be scraped from its URL
+	check := checkSource
+	if template {
+		check = checkSourceTemplate
+	}
+	if err := check(endpoint); err != nil {
+		cfg.Logger.LogWithFields(logrus.ErrorLevel, err.Error(),
+			map[string]string{
+				"endpointName": endpoint.Name,
+				"endpointUrl":  endpoint.URL,
+			})
+		return err
+	}
+
+	// Check if the destinations of the endpoint exist
+	for _, destination := range endpoint.GetDestinations() {
+		if _, ok := cfg.GetDestination(destination); !ok {
+			cfg.Logger.LogWithFields(logrus.ErrorLevel, logging.CONFIG__ENDPOINT_DESTINATION_IS_NOT_DEFINED,
+				map[string]string{
+					"endpointName":    endpoint.Name,
+					"destinationName": destination,
+				})
+			return errors.New(logging.CONFIG__ENDPOINT_DESTINATION_IS_NOT_DEFINED)
 		}
 	}
 
+	// Check if the delta mode is defined correctly
+	if err := checkDelta(cfg, endpoint); err != nil {
+		cfg.Logger.LogWithFields(logrus.ErrorLevel, err.Error(),
+			map[string]string{
+				"endpointName": endpoint.Name,
+			})
+		return err
+	}
+
+	// Check if the counters are defined correctly
+	if err := checkCounters(cfg, endpoint); err != nil {
+		cfg.Logger.LogWithFields(logrus.ErrorLevel, err.Error(),
+			map[string]string{
+				"endpointName": endpoint.Name,
+			})
+		return err
+	}
+
+	// Check if the derived attributes can be computed
+	if err := checkDerive(cfg, endpoint); err != nil {
+		return err
+	}
+
+	// Let the parser of the type validate its options
+	if _, err := parse.New(endpoint.Type, endpoint.Options); err != nil {
+		cfg.Logger.LogWithFields(logrus.ErrorLevel, logging.CONFIG__ENDPOINT_OPTIONS_ARE_INVALID,
+			map[string]string{
+				"endpointType": endpoint.Type,
+				"endpointName": endpoint.Name,
+				"error":        err.Error(),
+			})
+		return errors.New(logging.CONFIG__ENDPOINT_OPTIONS_ARE_INVALID)
+	}
+
 	return nil
 }
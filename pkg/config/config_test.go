@@ -46,13 +46,12 @@ func Test_NewRelicAccountIdIsNotDefined(t *testing.T) {
 		getEnv = getEnvMock
 	}()
 
-	licenseKey := ""
 	accountId := ""
 	getEnv = func(string) string {
 		return accountId
 	}
 
-	eventsEndpoint, err := setNewRelicEventsEndpoint(licenseKey)
+	eventsEndpoint, err := setNewRelicEventsEndpoint(RegionUs)
 	assert.Equal(t, "", eventsEndpoint)
 	assert.NotNil(t, err)
 	assert.Equal(t, logging.CONFIG__ACCOUNT_ID_IS_NOT_PROVIDED, err.Error())
@@ -70,14 +69,17 @@ func Test_NewRelicAccountIsInEu(t *testing.T) {
 		return nrAccountId
 	}
 
-	eventsEndpoint, err := setNewRelicEventsEndpoint(licenseKey)
+	region, err := parseNewRelicRegion("", licenseKey)
+	assert.Nil(t, err)
+
+	eventsEndpoint, err := setNewRelicEventsEndpoint(region)
 	assert.Nil(t, err)
 	assert.Equal(t,
 		"https://insights-collector.eu01.nr-data.net/v1/accounts/"+nrAccountId+"/events",
 		eventsEndpoint,
 	)
 
-	logsEndpoint := setNewRelicLogsEndpoint(licenseKey)
+	logsEndpoint := setNewRelicLogsEndpoint(region)
 	assert.Equal(t,
 		"https://log-api.eu.newrelic.com/log/v1",
 		logsEndpoint,
@@ -96,20 +98,91 @@ func Test_NewRelicAccountIsInUs(t *testing.T) {
 		return nrAccountId
 	}
 
-	eventsEndpoint, err := setNewRelicEventsEndpoint(licenseKey)
+	region, err := parseNewRelicRegion("", licenseKey)
+	assert.Nil(t, err)
+
+	eventsEndpoint, err := setNewRelicEventsEndpoint(region)
 	assert.Nil(t, err)
 	assert.Equal(t,
 		"https://insights-collector.nr-data.net/v1/accounts/"+nrAccountId+"/events",
 		eventsEndpoint,
 	)
 
-	logsEndpoint := setNewRelicLogsEndpoint(licenseKey)
+	logsEndpoint := setNewRelicLogsEndpoint(region)
 	assert.Equal(t,
 		"https://log-api.newrelic.com/log/v1",
 		logsEndpoint,
 	)
 }
 
+func Test_NewRelicAccountIsInFedramp(t *testing.T) {
+	getEnvMock := getEnv
+	defer func() {
+		getEnv = getEnvMock
+	}()
+
+	nrAccountId := "ACCOUNT_ID"
+	getEnv = func(string) string {
+		return nrAccountId
+	}
+
+	nr := &NewRelicInput{
+		LicenseKey: "eu_LICENSE_KEY",
+		Region:     "FedRAMP",
+	}
+	err := setNewRelicEndpoints(nr)
+	assert.Nil(t, err)
+	assert.Equal(t, RegionFedramp, nr.Region)
+	assert.Equal(t,
+		"https://gov-insights-collector.newrelic.com/v1/accounts/"+nrAccountId+"/events",
+		nr.EventsEndpoint,
+	)
+	assert.Equal(t, "https://gov-log-api.newrelic.com/log/v1", nr.LogsEndpoint)
+	assert.Equal(t, "https://gov-otlp.nr-data.net:4318", nr.MetricsEndpoint)
+}
+
+func Test_NewRelicRegionIsInvalid(t *testing.T) {
+	err := setNewRelicEndpoints(&NewRelicInput{LicenseKey: "LICENSE_KEY", Region: "APAC"})
+	assert.NotNil(t, err)
+	assert.Equal(t, logging.CONFIG__REGION_IS_NOT_SUPPORTED, err.Error())
+
+	// A region cannot be derived from a too short license key
+	err = setNewRelicEndpoints(&NewRelicInput{LicenseKey: "e"})
+	assert.NotNil(t, err)
+	assert.Equal(t, logging.CONFIG__REGION_COULD_NOT_BE_DERIVED, err.Error())
+}
+
+func Test_NewRelicEndpointsAreOverridden(t *testing.T) {
+	getEnvMock := getEnv
+	defer func() {
+		getEnv = getEnvMock
+	}()
+
+	// The account ID is not needed for an events endpoint override
+	getEnv = func(string) string {
+		return ""
+	}
+
+	nr := &NewRelicInput{
+		LicenseKey:     "e",
+		Region:         "us",
+		EventsEndpoint: "http://localhost:8080/events",
+		LogsEndpoint:   "http://localhost:8080/logs",
+	}
+	err := setNewRelicEndpoints(nr)
+	assert.Nil(t, err)
+	assert.Equal(t, "http://localhost:8080/events", nr.EventsEndpoint)
+	assert.Equal(t, "http://localhost:8080/logs", nr.LogsEndpoint)
+	assert.Equal(t, "https://otlp.nr-data.net:4318", nr.MetricsEndpoint)
+
+	err = setNewRelicEndpoints(&NewRelicInput{
+		LicenseKey:     "us_LICENSE_KEY",
+		EventsEndpoint: "localhost:8080/events",
+	})
+	assert.NotNil(t, err)
+	assert.Equal(t, logging.CONFIG__NEW_RELIC_ENDPOINT_IS_INVALID, err.Error())
+}
+
 func Test_ConfigFilePathIsNotDefined(t *testing.T) {
 	getEnvMock := getEnv
 	defer func() {
@@ -366,7 +439,7 @@ func Test_EndpointTypeIsNotSupported(t *testing.T) {
 			Logger: nil,
 			Endpoints: []Endpoint{
 				{
-					Type: "yaml",
+					Type: "toml",
 					Name: "Name",
 					URL:  "URL",
 				},
@@ -429,3 +502,163 @@ func Test_ConfigFileIsValid(t *testing.T) {
 
 	assert.NotPanics(t, func() { NewConfig() })
 }
+
+func Test_EndpointOptionsAreInvalid(t *testing.T) {
+	getEnvMock := getEnv
+	defer func() {
+		getEnv = getEnvMock
+	}()
+
+	getEnv = func(string) string {
+		return "CONFIG_PATH"
+	}
+
+	readFileMock := readFile
+	defer func() {
+		readFile = readFileMock
+	}()
+
+	readFile = func(string) ([]byte, error) {
+		cfg := &Config{
+			Newrelic: &NewRelicInput{
+				LogLevel:       "ERROR",
+				EventsEndpoint: "",
+				LicenseKey:     "",
+			},
+			Logger: nil,
+			Endpoints: []Endpoint{
+				{
+					Type: "kvp",
+					Name: "Name",
+					URL:  "URL",
+					Options: map[string]interface{}{
+						"unknown": "value",
+					},
+				},
+			},
+		}
+
+		bytes, err := yaml.Marshal(cfg)
+		if err != nil {
+			t.Log(err)
+		}
+
+		return bytes, nil
+	}
+
+	cfg, err := parseConfigFile()
+	assert.Nil(t, cfg)
+	assert.NotNil(t, err)
+	assert.Equal(t, logging.CONFIG__ENDPOINT_OPTIONS_ARE_INVALID, err.Error())
+}
+
+func Test_SpoolIsChecked(t *testing.T) {
+	cfg := createSinksConfig(nil)
+	cfg.Spool = &SpoolInput{Enabled: true}
+	err := checkSpool(cfg)
+	assert.NotNil(t, err)
+	assert.Equal(t, logging.CONFIG__SPOOL_IS_INVALID, err.Error())
+
+	cfg.Spool = &SpoolInput{Enabled: true, Directory: "/var/spool/scraper"}
+	err = checkSpool(cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, defaultSpoolMaxAge, cfg.Spool.MaxAge)
+	assert.Equal(t, int64(defaultSpoolMaxBytes), cfg.Spool.MaxBytes)
+}
+
+func Test_ScrapeIsChecked(t *testing.T) {
+	cfg := createSinksConfig(nil)
+	err := checkScrape(cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(DefaultScrapeMaxBodySize), cfg.Scrape.MaxBodySize)
+
+	cfg.Scrape = &ScrapeInput{MaxBodySize: -1}
+	err = checkScrape(cfg)
+	assert.NotNil(t, err)
+	assert.Equal(t, logging.CONFIG__SCRAPE_MAX_BODY_SIZE_IS_INVALID, err.Error())
+}
+
+func Test_EndpointDeltaIsChecked(t *testing.T) {
+	cfg := createSinksConfig(nil)
+	cfg.Daemon = &DaemonInput{}
+	err := checkState(cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, StateBackendMemory, cfg.State.Backend)
+
+	// The memory state is lost after every run of the cron job
+	endpoint := &Endpoint{Delta: &DeltaInput{}}
+	err = checkDelta(cfg, endpoint)
+	assert.NotNil(t, err)
+	assert.Equal(t, logging.CONFIG__ENDPOINT_STATE_IS_NOT_PERSISTENT, err.Error())
+
+	cfg.Daemon.Enabled = true
+	err = checkDelta(cfg, endpoint)
+	assert.Nil(t, err)
+	assert.Equal(t, DeltaModeKeys, endpoint.Delta.Mode)
+	assert.Equal(t, defaultDeltaHeartbeat, endpoint.Delta.Heartbeat)
+
+	endpoint = &Endpoint{Delta: &DeltaInput{Mode: "always"}}
+	err = checkDelta(cfg, endpoint)
+	assert.NotNil(t, err)
+	assert.Equal(t, logging.CONFIG__ENDPOINT_DELTA_MODE_IS_NOT_SUPPORTED, err.Error())
+
+	cfg.State = &StateInput{Backend: StateBackendConfigMap}
+	err = checkState(cfg)
+	assert.NotNil(t, err)
+	assert.Equal(t, logging.CONFIG__STATE_CONFIG_MAP_IS_NOT_DEFINED, err.Error())
+}
+
+func Test_EndpointCountersAreChecked(t *testing.T) {
+	cfg := createSinksConfig(nil)
+	cfg.Daemon = &DaemonInput{Enabled: true}
+	cfg.State = &StateInput{Backend: StateBackendMemory}
+
+	endpoint := &Endpoint{Counters: []CounterInput{{Key: "*_total"}}}
+	err := checkCounters(cfg, endpoint)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{CounterComputeRate, CounterComputeDelta}, endpoint.Counters[0].Compute)
+
+	endpoint = &Endpoint{Counters: []CounterInput{{Key: "[_total"}}}
+	err = checkCounters(cfg, endpoint)
+	assert.NotNil(t, err)
+	assert.Equal(t, logging.CONFIG__ENDPOINT_COUNTER_KEY_IS_INVALID, err.Error())
+
+	endpoint = &Endpoint{Counters: []CounterInput{{Key: "requests_total", Compute: []string{"avg"}}}}
+	err = checkCounters(cfg, endpoint)
+	assert.NotNil(t, err)
+	assert.Equal(t, logging.CONFIG__ENDPOINT_COUNTER_COMPUTE_IS_NOT_SUPPORTED, err.Error())
+}
+
+func Test_EndpointDeriveIsChecked(t *testing.T) {
+	cfg := createSinksConfig(nil)
+
+	endpoint := &Endpoint{Derive: []DeriveInput{
+		{Name: "utilization", Expression: "active / max * 100", Type: "number"},
+		{Name: "healthy", Expression: "status == 'OK'"},
+	}}
+	assert.Nil(t, checkDerive(cfg, endpoint))
+
+	// The expressions are compiled only once
+	assert.NotNil(t, endpoint.Derive[0].expression)
+	expression, err := endpoint.Derive[0].GetExpression()
+	assert.Nil(t, err)
+	assert.Same(t, endpoint.Derive[0].expression, expression)
+
+	endpoint = &Endpoint{Derive: []DeriveInput{
+		{Name: "healthy", Expression: "true"},
+		{Name: "healthy", Expression: "false"},
+	}}
+	err = checkDerive(cfg, endpoint)
+	assert.NotNil(t, err)
+	assert.Equal(t, logging.CONFIG__ENDPOINT_DERIVE_NAME_IS_INVALID, err.Error())
+
+	endpoint = &Endpoint{Derive: []DeriveInput{{Name: "utilization", Expression: "active /"}}}
+	err = checkDerive(cfg, endpoint)
+	assert.NotNil(t, err)
+	assert.Equal(t, logging.CONFIG__ENDPOINT_DERIVE_EXPRESSION_IS_INVALID, err.Error())
+
+	endpoint = &Endpoint{Derive: []DeriveInput{{Name: "utilization", Expression: "1", Type: "int"}}}
+	err = checkDerive(cfg, endpoint)
+	assert.NotNil(t, err)
+	assert.Equal(t, logging.CONFIG__ENDPOINT_DERIVE_TYPE_IS_NOT_SUPPORTED, err.Error())
+}
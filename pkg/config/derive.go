@@ -0,0 +1,73 @@
+package config
+
+import (
+	"errors"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/expr"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/logging"
+)
+
+// Attribute which is computed from the parsed values of an endpoint,
+// e.g. "active / max * 100". The attributes are derived in the order
+// which they are defined, so an expression can use the attributes
+// which are derived before it. The result can be converted into a
+// type: string, number or bool.
+type DeriveInput struct {
+	Name       string `yaml:"name"`
+	Expression string `yaml:"expression"`
+	Type       string `yaml:"type"`
+
+	// Compiled once when the config is checked
+	expression *expr.Expression
+}
+
+// Returns the compiled expression of the attribute
+func (derive *DeriveInput) GetExpression() (
+	*expr.Expression,
+	error,
+) {
+	if derive.expression == nil {
+		expression, err := expr.Compile(derive.Expression)
+		if err != nil {
+			return nil, err
+		}
+		derive.expression = expression
+	}
+	return derive.expression, nil
+}
+
+func checkDerive(
+	cfg *Config,
+	endpoint *Endpoint,
+) error {
+	names := map[string]bool{}
+	for i := range endpoint.Derive {
+		derive := &endpoint.Derive[i]
+		var err error
+		var reason string
+		if derive.Name == "" || names[derive.Name] {
+			err = errors.New(logging.CONFIG__ENDPOINT_DERIVE_NAME_IS_INVALID)
+		} else if _, compileErr := derive.GetExpression(); compileErr != nil || derive.Expression == "" {
+			err = errors.New(logging.CONFIG__ENDPOINT_DERIVE_EXPRESSION_IS_INVALID)
+			if compileErr != nil {
+				reason = compileErr.Error()
+			}
+		} else if _, kindErr := expr.ParseKind(derive.Type); kindErr != nil && derive.Type != "" {
+			err = errors.New(logging.CONFIG__ENDPOINT_DERIVE_TYPE_IS_NOT_SUPPORTED)
+		}
+
+		if err != nil {
+			cfg.Logger.LogWithFields(logrus.ErrorLevel, err.Error(),
+				map[string]string{
+					"endpointName": endpoint.Name,
+					"deriveName":   derive.Name,
+					"error":        reason,
+				})
+			return err
+		}
+		names[derive.Name] = true
+	}
+	return nil
+}
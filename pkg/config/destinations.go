@@ -0,0 +1,129 @@
+package config
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/logging"
+)
+
+// Name of the destination which is defined by the main
+// New Relic account (NEW_RELIC_ACCOUNT_ID & NEW_RELIC_LICENSE_KEY)
+const DefaultDestination = "default"
+
+// Named New Relic account which endpoints can be routed to.
+// The license key is read either from an environment variable
+// or from a file (e.g. a mounted secret).
+type NewRelicDestinationInput struct {
+	Name           string `yaml:"name"`
+	AccountId      string `yaml:"accountId"`
+	LicenseKeyEnv  string `yaml:"licenseKeyEnv"`
+	LicenseKeyFile string `yaml:"licenseKeyFile"`
+	Region         string `yaml:"region"`
+	EventsEndpoint string `yaml:"eventsEndpoint"`
+}
+
+// Returns the resolved account of the given destination
+func (cfg *Config) GetDestination(
+	name string,
+) (
+	*NewRelicInput,
+	bool,
+) {
+	if name == DefaultDestination {
+		return cfg.Newrelic, true
+	}
+	account, ok := cfg.Newrelic.Accounts[name]
+	return account, ok
+}
+
+// Returns the destinations which the endpoint is routed to
+func (endpoint *Endpoint) GetDestinations() []string {
+	if len(endpoint.Destinations) == 0 {
+		return []string{DefaultDestination}
+	}
+	return endpoint.Destinations
+}
+
+func checkDestinations(
+	cfg *Config,
+) error {
+	cfg.Newrelic.Accounts = make(map[string]*NewRelicInput)
+
+	for _, destination := range cfg.Newrelic.Destinations {
+		account, err := resolveDestination(cfg, destination)
+		if err != nil {
+			cfg.Logger.LogWithFields(logrus.ErrorLevel, err.Error(),
+				map[string]string{
+					"destinationName": destination.Name,
+				})
+			return err
+		}
+		cfg.Newrelic.Accounts[destination.Name] = account
+	}
+
+	return nil
+}
+
+func resolveDestination(
+	cfg *Config,
+	destination NewRelicDestinationInput,
+) (
+	*NewRelicInput,
+	error,
+) {
+	if destination.Name == "" || destination.Name == DefaultDestination {
+		return nil, errors.New(logging.CONFIG__DESTINATION_NAME_IS_INVALID)
+	}
+	if _, exists := cfg.Newrelic.Accounts[destination.Name]; exists {
+		return nil, errors.New(logging.CONFIG__DESTINATION_NAME_IS_INVALID)
+	}
+
+	if destination.AccountId == "" {
+		return nil, errors.New(logging.CONFIG__DESTINATION_ACCOUNT_ID_IS_NOT_PROVIDED)
+	}
+
+	// Read the license key from its source
+	var licenseKey string
+	switch {
+	case destination.LicenseKeyEnv != "":
+		licenseKey = getEnv(destination.LicenseKeyEnv)
+	case destination.LicenseKeyFile != "":
+		content, err := readFile(destination.LicenseKeyFile)
+		if err != nil {
+			return nil, errors.New(logging.CONFIG__DESTINATION_LICENSE_KEY_IS_NOT_PROVIDED)
+		}
+		licenseKey = strings.TrimSpace(string(content))
+	}
+	if len(licenseKey) < 2 {
+		return nil, errors.New(logging.CONFIG__DESTINATION_LICENSE_KEY_IS_NOT_PROVIDED)
+	}
+
+	// Determine the region from the license key if not given
+	region, err := parseNewRelicRegion(destination.Region, licenseKey)
+	if err != nil {
+		return nil, err
+	}
+
+	account := *cfg.Newrelic
+	account.LicenseKey = licenseKey
+	account.Region = region
+	if destination.EventsEndpoint != "" {
+		if err := checkNewRelicEndpointOverride(destination.EventsEndpoint); err != nil {
+			return nil, err
+		}
+		account.EventsEndpoint = destination.EventsEndpoint
+	} else {
+		account.EventsEndpoint = newRelicEventsEndpoint(region, destination.AccountId)
+	}
+
+	// The other endpoints of the main account belong to its region
+	account.LogsEndpoint = setNewRelicLogsEndpoint(region)
+	account.MetricsEndpoint = setNewRelicMetricsEndpoint(region)
+	account.Destinations = nil
+	account.Accounts = nil
+
+	return &account, nil
+}
@@ -0,0 +1,159 @@
+package config
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/logging"
+)
+
+func Test_DestinationsAreResolved(t *testing.T) {
+	getEnvMock := getEnv
+	defer func() {
+		getEnv = getEnvMock
+	}()
+
+	getEnv = func(name string) string {
+		if name == "TEAM_A_LICENSE_KEY" {
+			return "eu_TEAM_A_KEY"
+		}
+		return ""
+	}
+
+	readFileMock := readFile
+	defer func() {
+		readFile = readFileMock
+	}()
+
+	readFile = func(path string) ([]byte, error) {
+		if path == "/secrets/team-b" {
+			return []byte("us_TEAM_B_KEY\n"), nil
+		}
+		return nil, errors.New("not found")
+	}
+
+	cfg := createSinksConfig(nil)
+	cfg.Newrelic.Destinations = []NewRelicDestinationInput{
+		{Name: "team-a", AccountId: "1", LicenseKeyEnv: "TEAM_A_LICENSE_KEY"},
+		{Name: "team-b", AccountId: "2", LicenseKeyFile: "/secrets/team-b", Region: "eu"},
+	}
+
+	err := checkDestinations(cfg)
+	assert.Nil(t, err)
+
+	account, ok := cfg.GetDestination("team-a")
+	assert.True(t, ok)
+	assert.Equal(t, "eu_TEAM_A_KEY", account.LicenseKey)
+	assert.Equal(t, "https://insights-collector.eu01.nr-data.net/v1/accounts/1/events", account.EventsEndpoint)
+
+	account, ok = cfg.GetDestination("team-b")
+	assert.True(t, ok)
+	assert.Equal(t, "us_TEAM_B_KEY", account.LicenseKey)
+	assert.Equal(t, "https://insights-collector.eu01.nr-data.net/v1/accounts/2/events", account.EventsEndpoint)
+
+	account, ok = cfg.GetDestination(DefaultDestination)
+	assert.True(t, ok)
+	assert.Equal(t, cfg.Newrelic, account)
+
+	_, ok = cfg.GetDestination("team-c")
+	assert.False(t, ok)
+}
+
+func Test_DestinationsUseTheEndpointsOfTheirRegion(t *testing.T) {
+	getEnvMock := getEnv
+	defer func() {
+		getEnv = getEnvMock
+	}()
+
+	getEnv = func(string) string {
+		return "eu_LICENSE_KEY"
+	}
+
+	cfg := createSinksConfig(nil)
+	cfg.Newrelic.Region = RegionUs
+	cfg.Newrelic.LogsEndpoint = "https://log-api.newrelic.com/log/v1"
+	cfg.Newrelic.MetricsEndpoint = "https://otlp.nr-data.net:4318"
+	cfg.Newrelic.Destinations = []NewRelicDestinationInput{
+		{Name: "team-eu", AccountId: "1", LicenseKeyEnv: "KEY"},
+		{Name: "team-gov", AccountId: "2", LicenseKeyEnv: "KEY", Region: "fedramp"},
+	}
+
+	err := checkDestinations(cfg)
+	assert.Nil(t, err)
+
+	account, _ := cfg.GetDestination("team-eu")
+	assert.Equal(t, RegionEu, account.Region)
+	assert.Equal(t, "https://log-api.eu.newrelic.com/log/v1", account.LogsEndpoint)
+	assert.Equal(t, "https://otlp.eu01.nr-data.net:4318", account.MetricsEndpoint)
+
+	account, _ = cfg.GetDestination("team-gov")
+	assert.Equal(t, RegionFedramp, account.Region)
+	assert.Equal(t, "https://gov-log-api.newrelic.com/log/v1", account.LogsEndpoint)
+	assert.Equal(t, "https://gov-otlp.nr-data.net:4318", account.MetricsEndpoint)
+
+	// The main account keeps its endpoints
+	assert.Equal(t, "https://log-api.newrelic.com/log/v1", cfg.Newrelic.LogsEndpoint)
+}
+
+func Test_DestinationsAreInvalid(t *testing.T) {
+	getEnvMock := getEnv
+	defer func() {
+		getEnv = getEnvMock
+	}()
+
+	getEnv = func(string) string {
+		return "us_LICENSE_KEY"
+	}
+
+	destinations := map[string][]NewRelicDestinationInput{
+		logging.CONFIG__DESTINATION_NAME_IS_INVALID: {
+			{Name: DefaultDestination, AccountId: "1", LicenseKeyEnv: "KEY"},
+		},
+		logging.CONFIG__DESTINATION_ACCOUNT_ID_IS_NOT_PROVIDED: {
+			{Name: "team", LicenseKeyEnv: "KEY"},
+		},
+		logging.CONFIG__DESTINATION_LICENSE_KEY_IS_NOT_PROVIDED: {
+			{Name: "team", AccountId: "1"},
+		},
+		logging.CONFIG__REGION_IS_NOT_SUPPORTED: {
+			{Name: "team", AccountId: "1", LicenseKeyEnv: "KEY", Region: "APAC"},
+		},
+	}
+
+	for expected, input := range destinations {
+		cfg := createSinksConfig(nil)
+		cfg.Newrelic.Destinations = input
+
+		err := checkDestinations(cfg)
+		assert.NotNil(t, err)
+		assert.Equal(t, expected, err.Error())
+	}
+
+	// Names must be unique
+	cfg := createSinksConfig(nil)
+	cfg.Newrelic.Destinations = []NewRelicDestinationInput{
+		{Name: "team", AccountId: "1", LicenseKeyEnv: "KEY"},
+		{Name: "team", AccountId: "2", LicenseKeyEnv: "KEY"},
+	}
+	err := checkDestinations(cfg)
+	assert.NotNil(t, err)
+	assert.Equal(t, logging.CONFIG__DESTINATION_NAME_IS_INVALID, err.Error())
+}
+
+func Test_EndpointDestinationIsNotDefined(t *testing.T) {
+	cfg := createSinksConfig(nil)
+	cfg.Newrelic.Accounts = map[string]*NewRelicInput{}
+	cfg.Endpoints = []Endpoint{
+		{
+			Type:         "kvp",
+			Name:         "Name",
+			URL:          "URL",
+			Destinations: []string{"team"},
+		},
+	}
+
+	err := checkEndpoints(cfg)
+	assert.NotNil(t, err)
+	assert.Equal(t, logging.CONFIG__ENDPOINT_DESTINATION_IS_NOT_DEFINED, err.Error())
+}
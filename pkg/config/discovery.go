@@ -0,0 +1,120 @@
+package config
+
+import (
+	"errors"
+	"path"
+	"text/template"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/logging"
+)
+
+// Rule which creates an endpoint for every running pod that matches
+// it. The name and the URL of the endpoint are templates which are
+// rendered with the pod, e.g. "{{ .Labels.app }}Status" and
+// "http://{{ .IP }}:8080/status".
+type DiscoveryInput struct {
+	// Patterns of the namespaces which are allowed and denied, e.g.
+	// "team-a-*". All namespaces are allowed if empty.
+	Namespaces        []string `yaml:"namespaces"`
+	ExcludeNamespaces []string `yaml:"excludeNamespaces"`
+
+	// Label selector, e.g. "app.kubernetes.io/part-of=shop", and
+	// field selector, e.g. "spec.nodeName=node-1", of the pods
+	Selector      string `yaml:"selector"`
+	FieldSelector string `yaml:"fieldSelector"`
+
+	// Template of the endpoints
+	Endpoint Endpoint `yaml:"endpoint"`
+}
+
+// Pod which the templates of a discovery rule are rendered with
+type DiscoveryTarget struct {
+	Name        string
+	Namespace   string
+	IP          string
+	NodeName    string
+	Labels      map[string]string
+	Annotations map[string]string
+}
+
+// Returns the templates of the endpoint name and URL. Missing labels
+// or annotations fail the rendering instead of creating empty names.
+func (discovery *DiscoveryInput) ParseTemplates() (
+	*template.Template,
+	*template.Template,
+	error,
+) {
+	name, err := template.New("name").Option("missingkey=error").Parse(discovery.Endpoint.Name)
+	if err != nil {
+		return nil, nil, err
+	}
+	url, err := template.New("url").Option("missingkey=error").Parse(discovery.Endpoint.URL)
+	if err != nil {
+		return nil, nil, err
+	}
+	return name, url, nil
+}
+
+// Returns whether the pods of the namespace are discovered
+func (discovery *DiscoveryInput) AllowsNamespace(
+	namespace string,
+) bool {
+	for _, pattern := range discovery.ExcludeNamespaces {
+		if ok, _ := path.Match(pattern, namespace); ok {
+			return false
+		}
+	}
+	if len(discovery.Namespaces) == 0 {
+		return true
+	}
+	for _, pattern := range discovery.Namespaces {
+		if ok, _ := path.Match(pattern, namespace); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func checkDiscovery(
+	cfg *Config,
+) error {
+	for i := range cfg.Discovery {
+		discovery := &cfg.Discovery[i]
+
+		var err error
+		if discovery.Endpoint.Exec != nil || discovery.Endpoint.Kubernetes != nil {
+			err = errors.New(logging.CONFIG__DISCOVERY_ENDPOINT_IS_INVALID)
+		} else if _, _, templateErr := discovery.ParseTemplates(); templateErr != nil {
+			err = errors.New(logging.CONFIG__DISCOVERY_TEMPLATE_IS_INVALID)
+		} else if !isValidPatterns(discovery.Namespaces) || !isValidPatterns(discovery.ExcludeNamespaces) {
+			err = errors.New(logging.CONFIG__DISCOVERY_NAMESPACE_IS_INVALID)
+		}
+		if err != nil {
+			cfg.Logger.LogWithFields(logrus.ErrorLevel, err.Error(),
+				map[string]string{
+					"endpointName": discovery.Endpoint.Name,
+				})
+			return err
+		}
+
+		// The template has to be a valid endpoint itself
+		if err := checkEndpoint(cfg, &discovery.Endpoint, true); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func isValidPatterns(
+	patterns []string,
+) bool {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil || pattern == "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,92 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/logging"
+)
+
+func Test_DiscoveryIsChecked(t *testing.T) {
+	endpoint := Endpoint{
+		Type: "json",
+		Name: "{{ .Labels.app }}Status",
+		URL:  "http://{{ .IP }}:8080/status",
+	}
+
+	// Discovery rules replace static endpoints
+	cfg := createSinksConfig(nil)
+	cfg.Endpoints = nil
+	cfg.Discovery = []DiscoveryInput{{Namespaces: []string{"team-a-*"}, Endpoint: endpoint}}
+	assert.Nil(t, checkEndpoints(cfg))
+	assert.Nil(t, checkDiscovery(cfg))
+
+	invalid := endpoint
+	invalid.Name = "{{ .Labels.app "
+	cfg.Discovery = []DiscoveryInput{{Endpoint: invalid}}
+	err := checkDiscovery(cfg)
+	assert.NotNil(t, err)
+	assert.Equal(t, logging.CONFIG__DISCOVERY_TEMPLATE_IS_INVALID, err.Error())
+
+	cfg.Discovery = []DiscoveryInput{{ExcludeNamespaces: []string{"team-["}, Endpoint: endpoint}}
+	err = checkDiscovery(cfg)
+	assert.NotNil(t, err)
+	assert.Equal(t, logging.CONFIG__DISCOVERY_NAMESPACE_IS_INVALID, err.Error())
+
+	invalid = endpoint
+	invalid.Exec = &ExecInput{Selector: "app=redis", Command: []string{"redis-cli", "info"}}
+	cfg.Discovery = []DiscoveryInput{{Endpoint: invalid}}
+	err = checkDiscovery(cfg)
+	assert.NotNil(t, err)
+	assert.Equal(t, logging.CONFIG__DISCOVERY_ENDPOINT_IS_INVALID, err.Error())
+
+	// The template is checked like a static endpoint
+	invalid = endpoint
+	invalid.Type = "toml"
+	cfg.Discovery = []DiscoveryInput{{Endpoint: invalid}}
+	err = checkDiscovery(cfg)
+	assert.NotNil(t, err)
+	assert.Equal(t, logging.CONFIG__ENDPOINT_TYPE_IS_NOT_SUPPORTED, err.Error())
+
+	// The URL can only be parsed once it is rendered
+	valid := endpoint
+	valid.URL = "tcp://{{ .IP }}:9000"
+	valid.Socket = &SocketInput{Send: "stats\r\n"}
+	cfg.Discovery = []DiscoveryInput{{Endpoint: valid}}
+	assert.Nil(t, checkDiscovery(cfg))
+
+	invalid = endpoint
+	invalid.URL = "ftp://{{ .IP }}/status"
+	cfg.Discovery = []DiscoveryInput{{Endpoint: invalid}}
+	err = checkDiscovery(cfg)
+	assert.NotNil(t, err)
+	assert.Equal(t, logging.CONFIG__ENDPOINT_URL_SCHEME_IS_NOT_SUPPORTED, err.Error())
+}
+
+func Test_DiscoveredEndpointIsChecked(t *testing.T) {
+	err := CheckDiscoveredEndpoint(&Endpoint{Type: "kvp", Name: "cartStats", URL: "tcp://10.0.0.1:9000"})
+	assert.Nil(t, err)
+
+	err = CheckDiscoveredEndpoint(&Endpoint{Type: "kvp", Name: "", URL: "tcp://10.0.0.1:9000"})
+	assert.NotNil(t, err)
+	assert.Equal(t, logging.CONFIG__ENDPOINT_INFO_IS_MISSING, err.Error())
+
+	err = CheckDiscoveredEndpoint(&Endpoint{Type: "kvp", Name: "cartStats", URL: "tcp://10.0.0.1"})
+	assert.NotNil(t, err)
+	assert.Equal(t, logging.CONFIG__ENDPOINT_URL_IS_INVALID, err.Error())
+}
+
+func Test_DiscoveryNamespacesAreFiltered(t *testing.T) {
+	discovery := &DiscoveryInput{
+		Namespaces:        []string{"team-a", "team-b-*"},
+		ExcludeNamespaces: []string{"*-dev"},
+	}
+	assert.True(t, discovery.AllowsNamespace("team-a"))
+	assert.True(t, discovery.AllowsNamespace("team-b-prod"))
+	assert.False(t, discovery.AllowsNamespace("team-b-dev"))
+	assert.False(t, discovery.AllowsNamespace("team-c"))
+
+	discovery = &DiscoveryInput{ExcludeNamespaces: []string{"kube-system"}}
+	assert.True(t, discovery.AllowsNamespace("team-c"))
+	assert.False(t, discovery.AllowsNamespace("kube-system"))
+}
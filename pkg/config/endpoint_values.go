@@ -4,49 +4,100 @@ import (
 	"sync"
 )
 
+// Identifies an endpoint within the endpoint values
+type EndpointKey struct {
+	Type string
+	Name string
+	URL  string
+}
+
 // Object to store all values of all endpoints
 type EndpointValues struct {
 	// To avoid multi-thread read/write into the map
 	mux *sync.RWMutex
 
+	// Endpoints in the order which they are added
+	endpoints []Endpoint
+
 	// Map to store all values according to endpoints
-	// -> Key: endpoint itself
-	// -> Val: attributes which the endpoint has exposed
-	Values map[Endpoint](map[string]string)
+	// -> Key: identifier of the endpoint
+	// -> Val: records of attributes which the endpoint has exposed
+	Values map[EndpointKey]([]map[string]string)
+
+	// Map to store the types of the attributes which are no strings
+	// -> Key: identifier of the endpoint
+	// -> Val: type (number, bool) per attribute
+	Types map[EndpointKey]map[string]string
 }
 
 func NewEndpointValues() *EndpointValues {
 	return &EndpointValues{
-		mux:    &sync.RWMutex{},
-		Values: make(map[Endpoint](map[string]string)),
+		mux:       &sync.RWMutex{},
+		endpoints: make([]Endpoint, 0),
+		Values:    make(map[EndpointKey]([]map[string]string)),
+		Types:     make(map[EndpointKey]map[string]string),
+	}
+}
+
+func NewEndpointKey(
+	endpoint Endpoint,
+) EndpointKey {
+	return EndpointKey{
+		Type: endpoint.Type,
+		Name: endpoint.Name,
+		URL:  endpoint.URL,
 	}
 }
 
 func (evs *EndpointValues) AddEndpointValues(
 	endpoint Endpoint,
-	values map[string]string,
+	values []map[string]string,
 ) {
+	key := NewEndpointKey(endpoint)
+
 	evs.mux.Lock()
-	evs.Values[endpoint] = values
+	if _, ok := evs.Values[key]; !ok {
+		evs.endpoints = append(evs.endpoints, endpoint)
+	}
+	evs.Values[key] = values
 	evs.mux.Unlock()
 }
 
 func (evs *EndpointValues) GetEndpoints() []Endpoint {
-	endpoints := make([]Endpoint, len(evs.Values))
-
-	i := 0
-	for endpoint := range evs.Values {
-		endpoints[i] = endpoint
-		i++
-	}
+	evs.mux.RLock()
+	endpoints := make([]Endpoint, len(evs.endpoints))
+	copy(endpoints, evs.endpoints)
+	evs.mux.RUnlock()
 	return endpoints
 }
 
 func (evs *EndpointValues) GetEndpointValues(
 	endpoint Endpoint,
-) map[string]string {
+) []map[string]string {
 	evs.mux.RLock()
-	values := evs.Values[endpoint]
+	values := evs.Values[NewEndpointKey(endpoint)]
 	evs.mux.RUnlock()
 	return values
 }
+
+func (evs *EndpointValues) AddEndpointTypes(
+	endpoint Endpoint,
+	types map[string]string,
+) {
+	if len(types) == 0 {
+		return
+	}
+
+	evs.mux.Lock()
+	evs.Types[NewEndpointKey(endpoint)] = types
+	evs.mux.Unlock()
+}
+
+func (evs *EndpointValues) GetEndpointTypes(
+	endpoint Endpoint,
+) map[string]string {
+	evs.mux.RLock()
+	types := evs.Types[NewEndpointKey(endpoint)]
+	evs.mux.RUnlock()
+	return types
+}
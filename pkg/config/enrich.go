@@ -0,0 +1,98 @@
+package config
+
+import (
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/logging"
+)
+
+const (
+	EnrichAttributeNamespace = "namespace"
+	EnrichAttributePod       = "pod"
+	EnrichAttributeService   = "service"
+	EnrichAttributeNode      = "node"
+	EnrichAttributeOwner     = "owner"
+
+	defaultEnrichCacheTTL = 5 * time.Minute
+)
+
+// Kubernetes metadata which is added to the events of the endpoints
+// whose URL refers to a pod or a service of the cluster
+type EnrichInput struct {
+	Enabled bool `default:"false" yaml:"enabled"`
+
+	// Metadata which is added, all of namespace, pod, service, node
+	// and owner if empty
+	Attributes []string `yaml:"attributes"`
+
+	// Patterns of the labels which are added, e.g. "app.kubernetes.io/*"
+	Labels []string `yaml:"labels"`
+
+	// How long the metadata of a target is kept before it is looked up
+	// again
+	CacheTTL time.Duration `default:"5m" yaml:"cacheTtl"`
+}
+
+// Returns whether the metadata is added to the events
+func (enrich *EnrichInput) HasAttribute(
+	attribute string,
+) bool {
+	for _, a := range enrich.Attributes {
+		if a == attribute {
+			return true
+		}
+	}
+	return false
+}
+
+func checkEnrich(
+	cfg *Config,
+) error {
+	if cfg.Enrich == nil {
+		cfg.Enrich = &EnrichInput{}
+	}
+
+	if len(cfg.Enrich.Attributes) == 0 {
+		cfg.Enrich.Attributes = []string{
+			EnrichAttributeNamespace,
+			EnrichAttributePod,
+			EnrichAttributeService,
+			EnrichAttributeNode,
+			EnrichAttributeOwner,
+		}
+	}
+
+	for _, attribute := range cfg.Enrich.Attributes {
+		switch attribute {
+		case EnrichAttributeNamespace,
+			EnrichAttributePod,
+			EnrichAttributeService,
+			EnrichAttributeNode,
+			EnrichAttributeOwner:
+		default:
+			cfg.Logger.LogWithFields(logrus.ErrorLevel, logging.CONFIG__ENRICH_ATTRIBUTE_IS_NOT_SUPPORTED,
+				map[string]string{
+					"attribute": attribute,
+				})
+			return errors.New(logging.CONFIG__ENRICH_ATTRIBUTE_IS_NOT_SUPPORTED)
+		}
+	}
+
+	if !isValidPatterns(cfg.Enrich.Labels) {
+		cfg.Logger.Log(logrus.ErrorLevel, logging.CONFIG__ENRICH_LABEL_IS_INVALID)
+		return errors.New(logging.CONFIG__ENRICH_LABEL_IS_INVALID)
+	}
+
+	if cfg.Enrich.CacheTTL < 0 {
+		cfg.Logger.Log(logrus.ErrorLevel, logging.CONFIG__ENRICH_CACHE_TTL_IS_INVALID)
+		return errors.New(logging.CONFIG__ENRICH_CACHE_TTL_IS_INVALID)
+	}
+	if cfg.Enrich.CacheTTL == 0 {
+		cfg.Enrich.CacheTTL = defaultEnrichCacheTTL
+	}
+
+	return nil
+}
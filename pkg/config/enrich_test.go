@@ -0,0 +1,38 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/logging"
+)
+
+func Test_EnrichIsChecked(t *testing.T) {
+	cfg := createSinksConfig(nil)
+	cfg.Enrich = nil
+	assert.Nil(t, checkEnrich(cfg))
+	assert.False(t, cfg.Enrich.Enabled)
+	assert.Equal(t, 5*time.Minute, cfg.Enrich.CacheTTL)
+	assert.True(t, cfg.Enrich.HasAttribute(EnrichAttributeOwner))
+
+	cfg.Enrich = &EnrichInput{Enabled: true, Attributes: []string{"namespace", "owner"}, Labels: []string{"app.kubernetes.io/*"}}
+	assert.Nil(t, checkEnrich(cfg))
+	assert.True(t, cfg.Enrich.HasAttribute(EnrichAttributeNamespace))
+	assert.False(t, cfg.Enrich.HasAttribute(EnrichAttributeNode))
+
+	cfg.Enrich = &EnrichInput{Attributes: []string{"deployment"}}
+	err := checkEnrich(cfg)
+	assert.NotNil(t, err)
+	assert.Equal(t, logging.CONFIG__ENRICH_ATTRIBUTE_IS_NOT_SUPPORTED, err.Error())
+
+	cfg.Enrich = &EnrichInput{Labels: []string{"app["}}
+	err = checkEnrich(cfg)
+	assert.NotNil(t, err)
+	assert.Equal(t, logging.CONFIG__ENRICH_LABEL_IS_INVALID, err.Error())
+
+	cfg.Enrich = &EnrichInput{CacheTTL: -time.Second}
+	err = checkEnrich(cfg)
+	assert.NotNil(t, err)
+	assert.Equal(t, logging.CONFIG__ENRICH_CACHE_TTL_IS_INVALID, err.Error())
+}
@@ -0,0 +1,134 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/logging"
+)
+
+// Egress proxy which the outbound clients (New Relic, OTLP,
+// webhook) send their requests through. The in-cluster scrape
+// client always connects directly.
+type ProxyInput struct {
+	URL         string   `yaml:"url"`
+	Username    string   `yaml:"username"`
+	PasswordEnv string   `yaml:"passwordEnv"`
+	NoProxy     []string `yaml:"noProxy"`
+	CaFile      string   `yaml:"caFile"`
+}
+
+// Creates the transport of the outbound clients. If no proxy is
+// defined, the default transport is used.
+func checkProxy(
+	cfg *Config,
+) error {
+	if cfg.Proxy == nil || cfg.Proxy.URL == "" {
+		return nil
+	}
+
+	transport, err := newProxyTransport(cfg.Proxy)
+	if err != nil {
+		fmt.Println(err.Error())
+		return err
+	}
+	cfg.Transport = transport
+
+	return nil
+}
+
+func newProxyTransport(
+	proxy *ProxyInput,
+) (
+	*http.Transport,
+	error,
+) {
+
+	// Parse proxy URL
+	proxyUrl, err := url.Parse(proxy.URL)
+	if err != nil || (proxyUrl.Scheme != "http" && proxyUrl.Scheme != "https") || proxyUrl.Host == "" {
+		return nil, errors.New(logging.CONFIG__PROXY_URL_IS_INVALID)
+	}
+
+	// Add credentials
+	if proxy.Username != "" {
+		password := ""
+		if proxy.PasswordEnv != "" {
+			password = getEnv(proxy.PasswordEnv)
+		}
+		proxyUrl.User = url.UserPassword(proxy.Username, password)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = func(req *http.Request) (*url.URL, error) {
+		if matchesNoProxy(proxy.NoProxy, req.URL.Hostname()) {
+			return nil, nil
+		}
+		return proxyUrl, nil
+	}
+
+	// Trust the CA of a TLS intercepting proxy
+	if proxy.CaFile != "" {
+		ca, err := readFile(proxy.CaFile)
+		if err != nil {
+			return nil, errors.New(logging.CONFIG__PROXY_CA_COULD_NOT_BE_READ)
+		}
+
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, errors.New(logging.CONFIG__PROXY_CA_COULD_NOT_BE_READ)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+
+	return transport, nil
+}
+
+// Checks whether the host is to be reached directly. An entry is
+// either "*", an IP, a CIDR or a domain which also matches all of
+// its subdomains.
+func matchesNoProxy(
+	noProxy []string,
+	host string,
+) bool {
+	host = strings.ToLower(host)
+	ip := net.ParseIP(host)
+
+	for _, entry := range noProxy {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		if entry == "*" {
+			return true
+		}
+
+		if ip != nil {
+			if _, cidr, err := net.ParseCIDR(entry); err == nil {
+				if cidr.Contains(ip) {
+					return true
+				}
+				continue
+			}
+			if entryIp := net.ParseIP(entry); entryIp != nil && entryIp.Equal(ip) {
+				return true
+			}
+			continue
+		}
+
+		domain := strings.TrimPrefix(entry, ".")
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,90 @@
+package config
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/logging"
+)
+
+func Test_NoTransportIsCreatedWithoutProxy(t *testing.T) {
+	cfg := createSinksConfig(nil)
+
+	err := checkProxy(cfg)
+	assert.Nil(t, err)
+	assert.Nil(t, cfg.Transport)
+}
+
+func Test_ProxyIsUsedExceptForNoProxy(t *testing.T) {
+	getEnvMock := getEnv
+	defer func() {
+		getEnv = getEnvMock
+	}()
+
+	getEnv = func(name string) string {
+		if name == "PROXY_PASSWORD" {
+			return "secret"
+		}
+		return ""
+	}
+
+	cfg := createSinksConfig(nil)
+	cfg.Proxy = &ProxyInput{
+		URL:         "http://proxy.corp:3128",
+		Username:    "scraper",
+		PasswordEnv: "PROXY_PASSWORD",
+		NoProxy:     []string{".cluster.local", "10.0.0.0/8", "192.168.1.1"},
+	}
+
+	err := checkProxy(cfg)
+	assert.Nil(t, err)
+
+	transport := cfg.Transport.(*http.Transport)
+	proxied := map[string]bool{
+		"https://insights-collector.nr-data.net/v1/accounts/1/events": true,
+		"http://collector.monitoring.svc.cluster.local:4318":          false,
+		"http://cluster.local": false,
+		"http://10.1.2.3:8080": false,
+		"http://192.168.1.1":   false,
+		"http://192.168.1.2":   true,
+	}
+
+	for target, expected := range proxied {
+		req, _ := http.NewRequest(http.MethodGet, target, nil)
+		proxyUrl, err := transport.Proxy(req)
+		assert.Nil(t, err)
+
+		if expected {
+			assert.NotNil(t, proxyUrl, target)
+			assert.Equal(t, "proxy.corp:3128", proxyUrl.Host)
+			assert.Equal(t, "scraper:secret", proxyUrl.User.String())
+		} else {
+			assert.Nil(t, proxyUrl, target)
+		}
+	}
+}
+
+func Test_ProxyIsInvalid(t *testing.T) {
+	readFileMock := readFile
+	defer func() {
+		readFile = readFileMock
+	}()
+
+	readFile = func(string) ([]byte, error) {
+		return nil, errors.New("not found")
+	}
+
+	cfg := createSinksConfig(nil)
+	cfg.Proxy = &ProxyInput{URL: "proxy.corp:3128"}
+	err := checkProxy(cfg)
+	assert.NotNil(t, err)
+	assert.Equal(t, logging.CONFIG__PROXY_URL_IS_INVALID, err.Error())
+
+	cfg = createSinksConfig(nil)
+	cfg.Proxy = &ProxyInput{URL: "http://proxy.corp:3128", CaFile: "/etc/proxy/ca.crt"}
+	err = checkProxy(cfg)
+	assert.NotNil(t, err)
+	assert.Equal(t, logging.CONFIG__PROXY_CA_COULD_NOT_BE_READ, err.Error())
+}
@@ -0,0 +1,86 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/logging"
+)
+
+const (
+	RegionUs      = "US"
+	RegionEu      = "EU"
+	RegionFedramp = "FEDRAMP"
+)
+
+// Ingest endpoints of a New Relic region
+type newRelicRegion struct {
+	// Formatted with the account ID
+	eventsEndpoint  string
+	logsEndpoint    string
+	metricsEndpoint string
+}
+
+var newRelicRegions = map[string]newRelicRegion{
+	RegionUs: {
+		eventsEndpoint:  "https://insights-collector.nr-data.net/v1/accounts/%s/events",
+		logsEndpoint:    "https://log-api.newrelic.com/log/v1",
+		metricsEndpoint: "https://otlp.nr-data.net:4318",
+	},
+	RegionEu: {
+		eventsEndpoint:  "https://insights-collector.eu01.nr-data.net/v1/accounts/%s/events",
+		logsEndpoint:    "https://log-api.eu.newrelic.com/log/v1",
+		metricsEndpoint: "https://otlp.eu01.nr-data.net:4318",
+	},
+	RegionFedramp: {
+		eventsEndpoint:  "https://gov-insights-collector.newrelic.com/v1/accounts/%s/events",
+		logsEndpoint:    "https://gov-log-api.newrelic.com/log/v1",
+		metricsEndpoint: "https://gov-otlp.nr-data.net:4318",
+	},
+}
+
+// Returns the given region or derives it from the license key
+// if it is not given
+func parseNewRelicRegion(
+	region string,
+	licenseKey string,
+) (
+	string,
+	error,
+) {
+	if region == "" {
+		if len(licenseKey) < 2 {
+			return "", errors.New(logging.CONFIG__REGION_COULD_NOT_BE_DERIVED)
+		}
+		if strings.ToLower(licenseKey[0:2]) == "eu" {
+			return RegionEu, nil
+		}
+		return RegionUs, nil
+	}
+
+	region = strings.ToUpper(region)
+	if _, ok := newRelicRegions[region]; !ok {
+		return "", errors.New(logging.CONFIG__REGION_IS_NOT_SUPPORTED)
+	}
+	return region, nil
+}
+
+func newRelicEventsEndpoint(
+	region string,
+	accountId string,
+) string {
+	return fmt.Sprintf(newRelicRegions[region].eventsEndpoint, accountId)
+}
+
+// Checks whether an endpoint override is an absolute HTTP URL
+func checkNewRelicEndpointOverride(
+	endpoint string,
+) error {
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New(logging.CONFIG__NEW_RELIC_ENDPOINT_IS_INVALID)
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package config
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/logging"
+)
+
+const (
+	SinkTypeNewrelic = "newrelic"
+	SinkTypeOtlp     = "otlp"
+	SinkTypeStdout   = "stdout"
+	SinkTypeFile     = "file"
+	SinkTypeWebhook  = "webhook"
+)
+
+// Destination of newrelic.destinations which a "newrelic" sink sends
+// all events to, regardless of the destinations of their endpoints.
+// If it is not defined, the events are sent to the destinations of
+// their endpoints.
+type NewRelicSinkInput struct {
+	Destination string `yaml:"destination"`
+}
+
+type FileSinkInput struct {
+	Path string `yaml:"path"`
+}
+
+type WebhookSinkInput struct {
+	URL     string            `yaml:"url"`
+	Headers map[string]string `yaml:"headers"`
+}
+
+// Destination which the endpoint values are sent to. Only the
+// block which belongs to the type is used.
+type SinkInput struct {
+	Type     string             `yaml:"type"`
+	Name     string             `yaml:"name"`
+	Newrelic *NewRelicSinkInput `yaml:"newrelic"`
+	Otlp     *OtlpInput         `yaml:"otlp"`
+	File     *FileSinkInput     `yaml:"file"`
+	Webhook  *WebhookSinkInput  `yaml:"webhook"`
+
+	// Resolved New Relic account of a "newrelic" sink. If nil,
+	// the events are routed to the destinations of their endpoints.
+	Account *NewRelicInput `yaml:"-"`
+}
+
+func checkSinks(
+	cfg *Config,
+) error {
+
+	// Keep the single destination of the older configs
+	if len(cfg.Sinks) == 0 {
+		if cfg.Otlp.Enabled {
+			cfg.Sinks = []SinkInput{{Type: SinkTypeOtlp, Otlp: cfg.Otlp}}
+		} else {
+			cfg.Sinks = []SinkInput{{Type: SinkTypeNewrelic}}
+		}
+	}
+
+	for i := range cfg.Sinks {
+		sink := &cfg.Sinks[i]
+
+		if sink.Name == "" {
+			sink.Name = sink.Type + "-" + strconv.Itoa(i)
+		}
+
+		var err error
+		switch sink.Type {
+		case SinkTypeNewrelic:
+			err = checkNewRelicSink(cfg, sink)
+		case SinkTypeOtlp:
+			if sink.Otlp == nil {
+				sink.Otlp = &OtlpInput{}
+			}
+			err = checkOtlpInput(cfg, sink.Otlp)
+		case SinkTypeStdout:
+		case SinkTypeFile:
+			if sink.File == nil || sink.File.Path == "" {
+				err = errors.New(logging.CONFIG__SINK_FILE_PATH_IS_NOT_DEFINED)
+			}
+		case SinkTypeWebhook:
+			if sink.Webhook == nil || sink.Webhook.URL == "" {
+				err = errors.New(logging.CONFIG__SINK_WEBHOOK_URL_IS_NOT_DEFINED)
+			}
+		default:
+			err = errors.New(logging.CONFIG__SINK_TYPE_IS_NOT_SUPPORTED)
+		}
+
+		if err != nil {
+			cfg.Logger.LogWithFields(logrus.ErrorLevel, err.Error(),
+				map[string]string{
+					"sinkType": sink.Type,
+					"sinkName": sink.Name,
+				})
+			return err
+		}
+	}
+
+	return nil
+}
+
+func checkNewRelicSink(
+	cfg *Config,
+	sink *SinkInput,
+) error {
+
+	// Route the events to the destinations of their endpoints
+	if sink.Newrelic == nil || sink.Newrelic.Destination == "" {
+		return nil
+	}
+
+	// The accounts are defined by the destinations only
+	account, ok := cfg.GetDestination(sink.Newrelic.Destination)
+	if !ok {
+		return errors.New(logging.CONFIG__SINK_DESTINATION_IS_NOT_DEFINED)
+	}
+	sink.Account = account
+
+	return nil
+}
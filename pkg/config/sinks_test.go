@@ -0,0 +1,89 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/logging"
+)
+
+func Test_NewRelicSinkIsCreatedByDefault(t *testing.T) {
+	cfg := createSinksConfig(nil)
+
+	err := checkSinks(cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(cfg.Sinks))
+	assert.Equal(t, SinkTypeNewrelic, cfg.Sinks[0].Type)
+	assert.Nil(t, cfg.Sinks[0].Account)
+}
+
+func Test_OtlpSinkIsCreatedIfOtlpIsEnabled(t *testing.T) {
+	cfg := createSinksConfig(nil)
+	cfg.Otlp.Enabled = true
+
+	err := checkSinks(cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(cfg.Sinks))
+	assert.Equal(t, SinkTypeOtlp, cfg.Sinks[0].Type)
+	assert.Equal(t, cfg.Otlp, cfg.Sinks[0].Otlp)
+}
+
+func Test_SinkTypeIsNotSupported(t *testing.T) {
+	cfg := createSinksConfig([]SinkInput{{Type: "kafka"}})
+
+	err := checkSinks(cfg)
+	assert.NotNil(t, err)
+	assert.Equal(t, logging.CONFIG__SINK_TYPE_IS_NOT_SUPPORTED, err.Error())
+}
+
+func Test_SinksAreInvalid(t *testing.T) {
+	cfg := createSinksConfig([]SinkInput{{Type: SinkTypeFile}})
+	err := checkSinks(cfg)
+	assert.NotNil(t, err)
+	assert.Equal(t, logging.CONFIG__SINK_FILE_PATH_IS_NOT_DEFINED, err.Error())
+
+	cfg = createSinksConfig([]SinkInput{{Type: SinkTypeWebhook}})
+	err = checkSinks(cfg)
+	assert.NotNil(t, err)
+	assert.Equal(t, logging.CONFIG__SINK_WEBHOOK_URL_IS_NOT_DEFINED, err.Error())
+}
+
+func Test_NewRelicSinkUsesDestination(t *testing.T) {
+	cfg := createSinksConfig([]SinkInput{
+		{
+			Type:     SinkTypeNewrelic,
+			Newrelic: &NewRelicSinkInput{Destination: "second"},
+		},
+	})
+	second := &NewRelicInput{
+		LicenseKey:     "eu_SECOND_KEY",
+		EventsEndpoint: "https://insights-collector.eu01.nr-data.net/v1/accounts/2/events",
+	}
+	cfg.Newrelic.Accounts = map[string]*NewRelicInput{"second": second}
+
+	err := checkSinks(cfg)
+	assert.Nil(t, err)
+	assert.Same(t, second, cfg.Sinks[0].Account)
+	assert.Equal(t, "newrelic-0", cfg.Sinks[0].Name)
+
+	// The accounts are defined by the destinations only
+	cfg.Sinks[0].Newrelic.Destination = "third"
+	err = checkSinks(cfg)
+	assert.NotNil(t, err)
+	assert.Equal(t, logging.CONFIG__SINK_DESTINATION_IS_NOT_DEFINED, err.Error())
+}
+
+func createSinksConfig(
+	sinks []SinkInput,
+) *Config {
+	return &Config{
+		Newrelic: &NewRelicInput{
+			LogLevel:       "ERROR",
+			LicenseKey:     "us_LICENSE_KEY",
+			EventsEndpoint: "EVENTS_ENDPOINT",
+		},
+		Otlp:   &OtlpInput{},
+		Sinks:  sinks,
+		Logger: logging.NewLogger("ERROR"),
+	}
+}
@@ -0,0 +1,213 @@
+package config
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/logging"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/parse"
+)
+
+// Schemes of the endpoint URLs
+const (
+	SourceSchemeHttp  = "http"
+	SourceSchemeHttps = "https"
+	SourceSchemeFile  = "file"
+	SourceSchemeTcp   = "tcp"
+	SourceSchemeUnix  = "unix"
+	SourceSchemeExec  = "exec"
+
+	SourceSchemeKubernetes = "kubernetes"
+)
+
+// Conversation with a tcp or unix socket endpoint, e.g. a daemon
+// which answers a "stats" command
+type SocketInput struct {
+	// String which is sent after connecting, e.g. "stats\r\n"
+	Send string `yaml:"send"`
+
+	// The response ends with this string, e.g. "END\r\n". If empty,
+	// the response is read until the endpoint closes the connection.
+	ReadUntil string `yaml:"readUntil"`
+}
+
+// Command which is run in the containers of the selected pods via
+// the exec subresource of the Kubernetes API, e.g. "redis-cli info".
+// The standard output of every pod is parsed separately.
+type ExecInput struct {
+	// Namespace of the pods, the namespace of the scraper if empty
+	Namespace string `yaml:"namespace"`
+
+	// Label selector of the pods, e.g. "app=redis"
+	Selector string `yaml:"selector"`
+
+	// Container of the pods, can be empty if they have only one
+	Container string `yaml:"container"`
+
+	Command []string `yaml:"command"`
+}
+
+// Returns the URL which identifies the exec endpoint in its events
+// and its state
+func (exec *ExecInput) URL() string {
+	query := url.Values{
+		"selector": []string{exec.Selector},
+		"command":  exec.Command,
+	}
+	if exec.Container != "" {
+		query.Set("container", exec.Container)
+	}
+	return SourceSchemeExec + "://" + exec.Namespace + "?" + query.Encode()
+}
+
+// Objects of the Kubernetes API, e.g. the deployments with a label.
+// Every object is parsed separately as JSON, so the field paths are
+// extracted with the options of the endpoint type.
+type KubernetesInput struct {
+	// API version and kind, e.g. "apps/v1" and "Deployment"
+	APIVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+
+	// Namespace of the objects, the namespace of the scraper if empty.
+	// It is ignored for cluster scoped kinds.
+	Namespace string `yaml:"namespace"`
+
+	// Either the name of an object or a label selector, all objects
+	// of the namespace are fetched if both are empty
+	Name     string `yaml:"name"`
+	Selector string `yaml:"selector"`
+}
+
+// Returns the URL which identifies the kubernetes endpoint in its
+// events and its state
+func (kubernetes *KubernetesInput) URL() string {
+	query := url.Values{
+		"apiVersion": []string{kubernetes.APIVersion},
+		"kind":       []string{kubernetes.Kind},
+	}
+	if kubernetes.Name != "" {
+		query.Set("name", kubernetes.Name)
+	}
+	if kubernetes.Selector != "" {
+		query.Set("selector", kubernetes.Selector)
+	}
+	return SourceSchemeKubernetes + "://" + kubernetes.Namespace + "?" + query.Encode()
+}
+
+// Returns the scheme of the endpoint URL, which is http if the URL
+// has none
+func (endpoint *Endpoint) GetScheme() string {
+	if endpoint.Exec != nil {
+		return SourceSchemeExec
+	}
+	if endpoint.Kubernetes != nil {
+		return SourceSchemeKubernetes
+	}
+	i := strings.Index(endpoint.URL, "://")
+	if i == -1 {
+		return SourceSchemeHttp
+	}
+	return strings.ToLower(endpoint.URL[:i])
+}
+
+func checkSource(
+	endpoint *Endpoint,
+) error {
+	if endpoint.Exec != nil && endpoint.Kubernetes != nil {
+		return errors.New(logging.CONFIG__ENDPOINT_SOURCE_IS_AMBIGUOUS)
+	}
+
+	scheme := endpoint.GetScheme()
+	switch scheme {
+	case SourceSchemeHttp, SourceSchemeHttps:
+		if endpoint.Socket != nil {
+			return errors.New(logging.CONFIG__ENDPOINT_SOCKET_REQUIRES_SOCKET_URL)
+		}
+		return nil
+	case SourceSchemeExec:
+		if endpoint.Socket != nil {
+			return errors.New(logging.CONFIG__ENDPOINT_SOCKET_REQUIRES_SOCKET_URL)
+		}
+		if endpoint.Exec.Selector == "" || len(endpoint.Exec.Command) == 0 {
+			return errors.New(logging.CONFIG__ENDPOINT_EXEC_IS_INVALID)
+		}
+		return nil
+	case SourceSchemeKubernetes:
+		if endpoint.Socket != nil {
+			return errors.New(logging.CONFIG__ENDPOINT_SOCKET_REQUIRES_SOCKET_URL)
+		}
+		kubernetes := endpoint.Kubernetes
+		if kubernetes.APIVersion == "" || kubernetes.Kind == "" || (kubernetes.Name != "" && kubernetes.Selector != "") {
+			return errors.New(logging.CONFIG__ENDPOINT_KUBERNETES_IS_INVALID)
+		}
+
+		// The objects are always JSON documents
+		if def, ok := parse.Lookup(endpoint.Type); !ok || !def.Structured {
+			return errors.New(logging.CONFIG__ENDPOINT_KUBERNETES_TYPE_IS_NOT_SUPPORTED)
+		}
+		return nil
+	case SourceSchemeFile, SourceSchemeTcp, SourceSchemeUnix:
+	default:
+		return errors.New(logging.CONFIG__ENDPOINT_URL_SCHEME_IS_NOT_SUPPORTED)
+	}
+
+	u, err := url.Parse(endpoint.URL)
+	if err != nil {
+		return errors.New(logging.CONFIG__ENDPOINT_URL_IS_INVALID)
+	}
+
+	switch scheme {
+	case SourceSchemeFile:
+		if endpoint.Socket != nil {
+			return errors.New(logging.CONFIG__ENDPOINT_SOCKET_REQUIRES_SOCKET_URL)
+		}
+		if (u.Host != "" && u.Host != "localhost") || !strings.HasPrefix(u.Path, "/") {
+			return errors.New(logging.CONFIG__ENDPOINT_URL_IS_INVALID)
+		}
+	case SourceSchemeTcp:
+		if _, port, err := net.SplitHostPort(u.Host); err != nil || port == "" {
+			return errors.New(logging.CONFIG__ENDPOINT_URL_IS_INVALID)
+		}
+	case SourceSchemeUnix:
+		if u.Host != "" || !strings.HasPrefix(u.Path, "/") {
+			return errors.New(logging.CONFIG__ENDPOINT_URL_IS_INVALID)
+		}
+	}
+
+	return nil
+}
+
+// Checks the source of a discovery endpoint whose URL is a template,
+// e.g. "tcp://{{ .IP }}:9000". Only the scheme can be checked before
+// the template is rendered for a pod.
+func checkSourceTemplate(
+	endpoint *Endpoint,
+) error {
+	scheme := endpoint.GetScheme()
+	if strings.Contains(scheme, "{{") {
+		return nil
+	}
+
+	switch scheme {
+	case SourceSchemeHttp, SourceSchemeHttps, SourceSchemeFile:
+		if endpoint.Socket != nil {
+			return errors.New(logging.CONFIG__ENDPOINT_SOCKET_REQUIRES_SOCKET_URL)
+		}
+	case SourceSchemeTcp, SourceSchemeUnix:
+	default:
+		return errors.New(logging.CONFIG__ENDPOINT_URL_SCHEME_IS_NOT_SUPPORTED)
+	}
+	return nil
+}
+
+// Checks an endpoint which is rendered from a discovery template
+func CheckDiscoveredEndpoint(
+	endpoint *Endpoint,
+) error {
+	if endpoint.Name == "" || endpoint.URL == "" {
+		return errors.New(logging.CONFIG__ENDPOINT_INFO_IS_MISSING)
+	}
+	return checkSource(endpoint)
+}
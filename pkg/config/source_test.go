@@ -0,0 +1,105 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/logging"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/parse"
+)
+
+func Test_EndpointSourceIsChecked(t *testing.T) {
+	socket := &SocketInput{Send: "stats\r\n", ReadUntil: "END\r\n"}
+
+	tests := []struct {
+		url    string
+		socket *SocketInput
+		err    string
+	}{
+		{url: "http://app:8080/metrics"},
+		{url: "HTTPS://app/metrics"},
+		{url: "file:///var/status/app.json"},
+		{url: "file://localhost/var/status/app.json"},
+		{url: "tcp://memcached:11211", socket: socket},
+		{url: "unix:///var/run/app.sock", socket: socket},
+		{url: "ftp://app/status", err: logging.CONFIG__ENDPOINT_URL_SCHEME_IS_NOT_SUPPORTED},
+		{url: "file://var/status/app.json", err: logging.CONFIG__ENDPOINT_URL_IS_INVALID},
+		{url: "tcp://memcached", err: logging.CONFIG__ENDPOINT_URL_IS_INVALID},
+		{url: "unix://app.sock", err: logging.CONFIG__ENDPOINT_URL_IS_INVALID},
+		{url: "http://app:8080/metrics", socket: socket, err: logging.CONFIG__ENDPOINT_SOCKET_REQUIRES_SOCKET_URL},
+		{url: "file:///var/status/app.json", socket: socket, err: logging.CONFIG__ENDPOINT_SOCKET_REQUIRES_SOCKET_URL},
+	}
+
+	for _, test := range tests {
+		err := checkSource(&Endpoint{URL: test.url, Socket: test.socket})
+		if test.err == "" {
+			assert.Nil(t, err, test.url)
+		} else {
+			assert.NotNil(t, err, test.url)
+			assert.Equal(t, test.err, err.Error(), test.url)
+		}
+	}
+}
+
+func Test_EndpointExecIsChecked(t *testing.T) {
+	exec := &ExecInput{Selector: "app=redis", Command: []string{"redis-cli", "info"}}
+	err := checkSource(&Endpoint{Exec: exec})
+	assert.Nil(t, err)
+
+	err = checkSource(&Endpoint{Exec: &ExecInput{Selector: "app=redis"}})
+	assert.NotNil(t, err)
+	assert.Equal(t, logging.CONFIG__ENDPOINT_EXEC_IS_INVALID, err.Error())
+
+	err = checkSource(&Endpoint{Exec: exec, Socket: &SocketInput{}})
+	assert.NotNil(t, err)
+	assert.Equal(t, logging.CONFIG__ENDPOINT_SOCKET_REQUIRES_SOCKET_URL, err.Error())
+
+	// The URL identifies the pods and the command
+	cfg := createSinksConfig(nil)
+	cfg.Endpoints = []Endpoint{{Type: "kvp", Name: "Redis", Exec: exec}}
+	err = checkEndpoints(cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, "exec://?command=redis-cli&command=info&selector=app%3Dredis", cfg.Endpoints[0].URL)
+}
+
+func Test_EndpointKubernetesIsChecked(t *testing.T) {
+	kubernetes := &KubernetesInput{APIVersion: "apps/v1", Kind: "Deployment", Selector: "tier=backend"}
+	err := checkSource(&Endpoint{Type: "json", Kubernetes: kubernetes})
+	assert.Nil(t, err)
+
+	// The objects are JSON documents
+	err = checkSource(&Endpoint{Type: "csv", Kubernetes: kubernetes})
+	assert.NotNil(t, err)
+	assert.Equal(t, logging.CONFIG__ENDPOINT_KUBERNETES_TYPE_IS_NOT_SUPPORTED, err.Error())
+
+	// Custom types which parse JSON documents can read them as well
+	parse.Register(parse.Definition{
+		Type:       "structured-test",
+		Structured: true,
+		New: func(options map[string]interface{}) (parse.Parser, error) {
+			return nil, nil
+		},
+	})
+	err = checkSource(&Endpoint{Type: "structured-test", Kubernetes: kubernetes})
+	assert.Nil(t, err)
+
+	err = checkSource(&Endpoint{Kubernetes: &KubernetesInput{APIVersion: "v1", Kind: "ConfigMap", Name: "app", Selector: "app=shop"}})
+	assert.NotNil(t, err)
+	assert.Equal(t, logging.CONFIG__ENDPOINT_KUBERNETES_IS_INVALID, err.Error())
+
+	err = checkSource(&Endpoint{Kubernetes: &KubernetesInput{Kind: "ConfigMap"}})
+	assert.NotNil(t, err)
+	assert.Equal(t, logging.CONFIG__ENDPOINT_KUBERNETES_IS_INVALID, err.Error())
+
+	exec := &ExecInput{Selector: "app=redis", Command: []string{"redis-cli", "info"}}
+	err = checkSource(&Endpoint{Kubernetes: kubernetes, Exec: exec})
+	assert.NotNil(t, err)
+	assert.Equal(t, logging.CONFIG__ENDPOINT_SOURCE_IS_AMBIGUOUS, err.Error())
+
+	// The URL identifies the objects
+	cfg := createSinksConfig(nil)
+	cfg.Endpoints = []Endpoint{{Type: "json", Name: "Deployments", Kubernetes: kubernetes}}
+	err = checkEndpoints(cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, "kubernetes://?apiVersion=apps%2Fv1&kind=Deployment&selector=tier%3Dbackend", cfg.Endpoints[0].URL)
+}
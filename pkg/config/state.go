@@ -0,0 +1,146 @@
+package config
+
+import (
+	"errors"
+	"path"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/logging"
+)
+
+const (
+	StateBackendMemory    = "memory"
+	StateBackendFile      = "file"
+	StateBackendConfigMap = "configmap"
+
+	DeltaModeKeys     = "keys"
+	DeltaModeSnapshot = "snapshot"
+
+	CounterComputeRate  = "rate"
+	CounterComputeDelta = "delta"
+
+	defaultDeltaHeartbeat = time.Hour
+)
+
+// Storage of the values which have to survive between runs, e.g.
+// the last forwarded values of the endpoints in delta mode
+type StateInput struct {
+	Backend   string `default:"memory" yaml:"backend"`
+	Path      string `yaml:"path"`
+	ConfigMap string `yaml:"configMap"`
+}
+
+// Forwards only the values of an endpoint which have changed since
+// the last run. All values are forwarded once per heartbeat.
+type DeltaInput struct {
+	Mode      string        `default:"keys" yaml:"mode"`
+	Heartbeat time.Duration `default:"1h" yaml:"heartbeat"`
+}
+
+// Monotonic counter of an endpoint whose rate per second and delta
+// between consecutive scrapes are computed. The key may contain the
+// wildcards of path.Match, e.g. "*_total".
+type CounterInput struct {
+	Key     string   `yaml:"key"`
+	Compute []string `yaml:"compute"`
+}
+
+func checkState(
+	cfg *Config,
+) error {
+	if cfg.State == nil {
+		cfg.State = &StateInput{}
+	}
+	if cfg.State.Backend == "" {
+		cfg.State.Backend = StateBackendMemory
+	}
+
+	var err error
+	switch cfg.State.Backend {
+	case StateBackendMemory:
+	case StateBackendFile:
+		if cfg.State.Path == "" {
+			err = errors.New(logging.CONFIG__STATE_FILE_PATH_IS_NOT_DEFINED)
+		}
+	case StateBackendConfigMap:
+		if cfg.State.ConfigMap == "" {
+			err = errors.New(logging.CONFIG__STATE_CONFIG_MAP_IS_NOT_DEFINED)
+		}
+	default:
+		err = errors.New(logging.CONFIG__STATE_BACKEND_IS_NOT_SUPPORTED)
+	}
+
+	if err != nil {
+		cfg.Logger.LogWithFields(logrus.ErrorLevel, err.Error(),
+			map[string]string{
+				"stateBackend": cfg.State.Backend,
+			})
+		return err
+	}
+
+	return nil
+}
+
+func checkDelta(
+	cfg *Config,
+	endpoint *Endpoint,
+) error {
+	if endpoint.Delta == nil {
+		return nil
+	}
+
+	if endpoint.Delta.Mode == "" {
+		endpoint.Delta.Mode = DeltaModeKeys
+	}
+	if endpoint.Delta.Mode != DeltaModeKeys && endpoint.Delta.Mode != DeltaModeSnapshot {
+		return errors.New(logging.CONFIG__ENDPOINT_DELTA_MODE_IS_NOT_SUPPORTED)
+	}
+
+	if endpoint.Delta.Heartbeat < 0 {
+		return errors.New(logging.CONFIG__ENDPOINT_DELTA_HEARTBEAT_IS_INVALID)
+	}
+	if endpoint.Delta.Heartbeat == 0 {
+		endpoint.Delta.Heartbeat = defaultDeltaHeartbeat
+	}
+
+	return checkPersistentState(cfg)
+}
+
+func checkCounters(
+	cfg *Config,
+	endpoint *Endpoint,
+) error {
+	if len(endpoint.Counters) == 0 {
+		return nil
+	}
+
+	for i := range endpoint.Counters {
+		counter := &endpoint.Counters[i]
+		if _, err := path.Match(counter.Key, ""); err != nil || counter.Key == "" {
+			return errors.New(logging.CONFIG__ENDPOINT_COUNTER_KEY_IS_INVALID)
+		}
+
+		if len(counter.Compute) == 0 {
+			counter.Compute = []string{CounterComputeRate, CounterComputeDelta}
+		}
+		for _, compute := range counter.Compute {
+			if compute != CounterComputeRate && compute != CounterComputeDelta {
+				return errors.New(logging.CONFIG__ENDPOINT_COUNTER_COMPUTE_IS_NOT_SUPPORTED)
+			}
+		}
+	}
+
+	return checkPersistentState(cfg)
+}
+
+// The memory is lost after every run of the cron job
+func checkPersistentState(
+	cfg *Config,
+) error {
+	if !cfg.Daemon.Enabled && cfg.State.Backend == StateBackendMemory {
+		return errors.New(logging.CONFIG__ENDPOINT_STATE_IS_NOT_PERSISTENT)
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+package config
+
+import (
+	"crypto/sha256"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/logging"
+)
+
+// Object which watches the config file and swaps in
+// the new config whenever the file content changes
+type Watcher struct {
+	// To avoid multi-thread read/write of the current config
+	mux *sync.RWMutex
+
+	// Currently active config
+	cfg *Config
+
+	// Hash of the last seen config file content
+	hash [sha256.Size]byte
+}
+
+// Difference between two endpoint sets. The endpoints are
+// identified by their name and URL.
+type EndpointsDiff struct {
+	Added   []string
+	Removed []string
+	Changed []string
+}
+
+// Creates new watcher for the config file at CONFIG_PATH
+func NewWatcher(
+	cfg *Config,
+) *Watcher {
+	w := &Watcher{
+		mux: &sync.RWMutex{},
+		cfg: cfg,
+	}
+
+	// Remember the content which the given config is parsed from
+	if content, err := readFile(getEnv("CONFIG_PATH")); err == nil {
+		w.hash = sha256.Sum256(content)
+	}
+
+	return w
+}
+
+// Returns the currently active config
+func (w *Watcher) Config() *Config {
+	w.mux.RLock()
+	cfg := w.cfg
+	w.mux.RUnlock()
+	return cfg
+}
+
+// Polls the config file until stop is closed. Polling the content
+// instead of watching inotify events also catches the symlink swaps
+// which Kubernetes performs when a mounted ConfigMap is updated.
+func (w *Watcher) Run(
+	stop <-chan struct{},
+) {
+	for {
+		select {
+		case <-stop:
+			return
+		case <-time.After(w.Config().Daemon.ReloadInterval):
+			w.Reload()
+		}
+	}
+}
+
+// Re-reads the config file and swaps in the new config if the
+// content has changed and is valid. Returns whether the config
+// is swapped.
+func (w *Watcher) Reload() bool {
+	current := w.Config()
+
+	content, err := readFile(getEnv("CONFIG_PATH"))
+	if err != nil {
+		current.Logger.LogWithFields(logrus.ErrorLevel, logging.CONFIG__CONFIG_FILE_COULD_NOT_BE_RELOADED,
+			map[string]string{
+				"error": logging.CONFIG__CONFIG_FILE_COULD_NOT_BE_READ,
+			})
+		return false
+	}
+
+	// Nothing to do if the content is the same
+	hash := sha256.Sum256(content)
+	if hash == w.hash {
+		return false
+	}
+	w.hash = hash
+
+	// Parse & validate the new config
+	cfg, err := parseConfigFile()
+	if err != nil {
+		current.Logger.LogWithFields(logrus.ErrorLevel, logging.CONFIG__CONFIG_FILE_COULD_NOT_BE_RELOADED,
+			map[string]string{
+				"error": err.Error(),
+			})
+		return false
+	}
+
+	// Send the logs of the previous config before it is replaced
+	current.Logger.Flush()
+
+	w.mux.Lock()
+	w.cfg = cfg
+	w.mux.Unlock()
+
+	diff := DiffEndpoints(current.Endpoints, cfg.Endpoints)
+	cfg.Logger.LogWithFields(logrus.InfoLevel, logging.CONFIG__CONFIG_FILE_IS_RELOADED,
+		map[string]string{
+			"endpointsAdded":   strings.Join(diff.Added, ","),
+			"endpointsRemoved": strings.Join(diff.Removed, ","),
+			"endpointsChanged": strings.Join(diff.Changed, ","),
+			"settingsChanged":  strings.Join(diffSettings(current, cfg), ","),
+		})
+
+	return true
+}
+
+// Compares the old and new endpoints
+func DiffEndpoints(
+	old []Endpoint,
+	new []Endpoint,
+) *EndpointsDiff {
+	diff := &EndpointsDiff{
+		Added:   []string{},
+		Removed: []string{},
+		Changed: []string{},
+	}
+
+	olds := make(map[string]Endpoint, len(old))
+	for _, endpoint := range old {
+		olds[endpointId(endpoint)] = endpoint
+	}
+
+	news := make(map[string]Endpoint, len(new))
+	for _, endpoint := range new {
+		news[endpointId(endpoint)] = endpoint
+	}
+
+	for id, endpoint := range news {
+		oldEndpoint, ok := olds[id]
+		if !ok {
+			diff.Added = append(diff.Added, id)
+			continue
+		}
+		if !reflect.DeepEqual(oldEndpoint, endpoint) {
+			diff.Changed = append(diff.Changed, id)
+		}
+	}
+
+	for id := range olds {
+		if _, ok := news[id]; !ok {
+			diff.Removed = append(diff.Removed, id)
+		}
+	}
+
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Strings(diff.Changed)
+	return diff
+}
+
+func endpointId(
+	endpoint Endpoint,
+) string {
+	return endpoint.Name + "@" + endpoint.URL
+}
+
+// Returns the names of the top level settings which have changed
+func diffSettings(
+	old *Config,
+	new *Config,
+) []string {
+	changed := []string{}
+	if !reflect.DeepEqual(old.Newrelic, new.Newrelic) {
+		changed = append(changed, "newrelic")
+	}
+	if !reflect.DeepEqual(old.Daemon, new.Daemon) {
+		changed = append(changed, "daemon")
+	}
+	return changed
+}
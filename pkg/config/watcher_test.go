@@ -0,0 +1,109 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+)
+
+func Test_ConfigIsNotReloadedIfFileHasNotChanged(t *testing.T) {
+	content := createConfigFileContent(t, "MyEndpoint1")
+	defer mockConfigFile(&content)()
+
+	cfg, err := NewConfig()
+	assert.Nil(t, err)
+
+	watcher := NewWatcher(cfg)
+	assert.False(t, watcher.Reload())
+	assert.Equal(t, cfg, watcher.Config())
+}
+
+func Test_ConfigIsReloadedIfFileHasChanged(t *testing.T) {
+	content := createConfigFileContent(t, "MyEndpoint1")
+	defer mockConfigFile(&content)()
+
+	cfg, err := NewConfig()
+	assert.Nil(t, err)
+
+	watcher := NewWatcher(cfg)
+	content = createConfigFileContent(t, "MyEndpoint2")
+
+	assert.True(t, watcher.Reload())
+	assert.Equal(t, "MyEndpoint2", watcher.Config().Endpoints[0].Name)
+}
+
+func Test_ConfigIsNotReloadedIfNewConfigIsInvalid(t *testing.T) {
+	content := createConfigFileContent(t, "MyEndpoint1")
+	defer mockConfigFile(&content)()
+
+	cfg, err := NewConfig()
+	assert.Nil(t, err)
+
+	watcher := NewWatcher(cfg)
+	content = []byte("false config")
+
+	assert.False(t, watcher.Reload())
+	assert.Equal(t, cfg, watcher.Config())
+}
+
+func Test_EndpointsDiffIsCalculated(t *testing.T) {
+	old := []Endpoint{
+		{Type: "kvp", Name: "Kept", URL: "URL1"},
+		{Type: "kvp", Name: "Changed", URL: "URL2"},
+		{Type: "kvp", Name: "Removed", URL: "URL3"},
+	}
+	new := []Endpoint{
+		{Type: "kvp", Name: "Kept", URL: "URL1"},
+		{Type: "json", Name: "Changed", URL: "URL2"},
+		{Type: "kvp", Name: "Added", URL: "URL4"},
+	}
+
+	diff := DiffEndpoints(old, new)
+	assert.Equal(t, []string{"Added@URL4"}, diff.Added)
+	assert.Equal(t, []string{"Removed@URL3"}, diff.Removed)
+	assert.Equal(t, []string{"Changed@URL2"}, diff.Changed)
+}
+
+func mockConfigFile(
+	content *[]byte,
+) func() {
+	getEnvMock := getEnv
+	readFileMock := readFile
+
+	getEnv = func(string) string {
+		return "CONFIG_PATH"
+	}
+	readFile = func(string) ([]byte, error) {
+		return *content, nil
+	}
+
+	return func() {
+		getEnv = getEnvMock
+		readFile = readFileMock
+	}
+}
+
+func createConfigFileContent(
+	t *testing.T,
+	endpointName string,
+) []byte {
+	cfg := &Config{
+		Newrelic: &NewRelicInput{
+			LogLevel: "ERROR",
+		},
+		Endpoints: []Endpoint{
+			{
+				Type: "kvp",
+				Name: endpointName,
+				URL:  "URL",
+			},
+		},
+	}
+
+	bytes, err := yaml.Marshal(cfg)
+	if err != nil {
+		t.Log(err)
+	}
+	return bytes
+}
@@ -0,0 +1,243 @@
+package delta
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/config"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/state"
+)
+
+// Prefix of the state keys of the detector
+const keyPrefix = "delta."
+
+// Last forwarded values of an endpoint. The records are stored by
+// their identity.
+type endpointState struct {
+	Records   map[string]map[string]string `json:"records"`
+	Heartbeat time.Time                    `json:"heartbeat"`
+}
+
+// Drops the values of the endpoints in delta mode which have not
+// changed since the last run
+type Detector struct {
+	config *config.Config
+	store  state.Store
+	now    func() time.Time
+}
+
+func NewDetector(
+	cfg *config.Config,
+	store state.Store,
+) *Detector {
+	return &Detector{
+		config: cfg,
+		store:  store,
+		now:    time.Now,
+	}
+}
+
+// Returns the values which are to be forwarded and a function which
+// saves them as the last values. It is to be called only once the
+// values are sent, so that they are forwarded again otherwise.
+func (d *Detector) Run(
+	evs *config.EndpointValues,
+) (
+	*config.EndpointValues,
+	func(),
+) {
+
+	// Leave the state untouched if no endpoint uses the delta mode
+	if !d.isEnabled() {
+		return evs, func() {}
+	}
+
+	// Forward everything if the last values are not known
+	data, err := d.store.Load()
+	if err != nil {
+		d.config.Logger.LogWithFields(logrus.ErrorLevel, err.Error(),
+			map[string]string{
+				"stateBackend": d.config.State.Backend,
+			})
+		return evs, func() {}
+	}
+
+	now := d.now()
+	filtered := config.NewEndpointValues()
+	for _, endpoint := range evs.GetEndpoints() {
+		records := evs.GetEndpointValues(endpoint)
+		filtered.AddEndpointTypes(endpoint, evs.GetEndpointTypes(endpoint))
+		if endpoint.Delta == nil {
+			filtered.AddEndpointValues(endpoint, records)
+			continue
+		}
+
+		key := state.EndpointKey(keyPrefix, endpoint)
+		last := decodeState(data[key])
+
+		keys := state.RecordKeys(endpoint.Identity, records)
+		changed, heartbeat := detectChanges(endpoint, last, keys, records, now)
+		d.config.Logger.LogWithFields(logrus.DebugLevel, "Changes are detected.",
+			map[string]string{
+				"endpointName": endpoint.Name,
+				"records":      strconv.Itoa(len(records)),
+				"changed":      strconv.Itoa(len(changed)),
+				"heartbeat":    strconv.FormatBool(heartbeat),
+			})
+		if len(changed) > 0 {
+			filtered.AddEndpointValues(endpoint, changed)
+		}
+
+		// Remember the current values
+		current := endpointState{
+			Records:   make(map[string]map[string]string, len(records)),
+			Heartbeat: last.Heartbeat,
+		}
+		for i, record := range records {
+			current.Records[keys[i]] = record
+		}
+		if heartbeat {
+			current.Heartbeat = now
+		}
+		data[key] = encodeState(current)
+	}
+
+	d.removeStaleStates(data)
+
+	commit := func() {
+		err := d.store.Save(data)
+		if err != nil {
+			d.config.Logger.LogWithFields(logrus.ErrorLevel, err.Error(),
+				map[string]string{
+					"stateBackend": d.config.State.Backend,
+				})
+		}
+	}
+
+	return filtered, commit
+}
+
+func (d *Detector) isEnabled() bool {
+	for _, endpoint := range d.config.Endpoints {
+		if endpoint.Delta != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Removes the states of the endpoints which are not in delta mode
+// anymore so that the state does not grow
+func (d *Detector) removeStaleStates(
+	data map[string]string,
+) {
+	keys := map[string]bool{}
+	for _, endpoint := range d.config.Endpoints {
+		if endpoint.Delta != nil {
+			keys[state.EndpointKey(keyPrefix, endpoint)] = true
+		}
+	}
+
+	for key := range data {
+		if strings.HasPrefix(key, keyPrefix) && !keys[key] {
+			delete(data, key)
+		}
+	}
+}
+
+// Returns the records which are to be forwarded and whether all of
+// them are forwarded because the heartbeat is due. The records are
+// compared with the last ones of the same identity.
+func detectChanges(
+	endpoint config.Endpoint,
+	last *endpointState,
+	keys []string,
+	records []map[string]string,
+	now time.Time,
+) (
+	[]map[string]string,
+	bool,
+) {
+	delta := endpoint.Delta
+	if last.Heartbeat.IsZero() || now.Sub(last.Heartbeat) >= delta.Heartbeat {
+		return records, true
+	}
+
+	changed := make([]map[string]string, 0)
+	for i, record := range records {
+		lastRecord := last.Records[keys[i]]
+
+		switch delta.Mode {
+		case config.DeltaModeSnapshot:
+			if !equalRecords(lastRecord, record) {
+				changed = append(changed, record)
+			}
+		default:
+			changedKeys := map[string]string{}
+			for key, value := range record {
+				if lastValue, ok := lastRecord[key]; !ok || lastValue != value {
+					changedKeys[key] = value
+				}
+			}
+			if len(changedKeys) == 0 {
+				continue
+			}
+
+			// Without an identity, the changed keys of one of several
+			// records could not be told apart
+			if len(endpoint.Identity) == 0 && len(records) > 1 {
+				changed = append(changed, record)
+				continue
+			}
+
+			// Keep the keys which tell which record has changed
+			for key, value := range state.RecordIdentity(endpoint.Identity, record) {
+				changedKeys[key] = value
+			}
+			changed = append(changed, changedKeys)
+		}
+	}
+
+	return changed, false
+}
+
+func equalRecords(
+	a map[string]string,
+	b map[string]string,
+) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for key, value := range a {
+		if other, ok := b[key]; !ok || other != value {
+			return false
+		}
+	}
+	return true
+}
+
+func decodeState(
+	value string,
+) *endpointState {
+	s := &endpointState{}
+	if value == "" {
+		return s
+	}
+
+	// Start over if the state is corrupt
+	if err := json.Unmarshal([]byte(value), s); err != nil {
+		return &endpointState{}
+	}
+	return s
+}
+
+func encodeState(
+	s endpointState,
+) string {
+	value, _ := json.Marshal(s)
+	return string(value)
+}
@@ -0,0 +1,232 @@
+package delta
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/config"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/logging"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/state"
+)
+
+func Test_OnlyChangedKeysAreForwarded(t *testing.T) {
+	cfg := createConfig(config.DeltaModeKeys)
+	detector := NewDetector(cfg, state.NewMemoryStore())
+
+	// All values are forwarded on the first run
+	evs := run(detector, createEndpointValues(cfg, map[string]string{"version": "1", "replicas": "3"}))
+	assert.Equal(t, []map[string]string{{"version": "1", "replicas": "3"}},
+		evs.GetEndpointValues(cfg.Endpoints[0]))
+
+	// Nothing has changed
+	evs = run(detector, createEndpointValues(cfg, map[string]string{"version": "1", "replicas": "3"}))
+	assert.Equal(t, 0, len(evs.GetEndpoints()))
+
+	// Only the changed key is forwarded
+	evs = run(detector, createEndpointValues(cfg, map[string]string{"version": "1", "replicas": "4"}))
+	assert.Equal(t, []map[string]string{{"replicas": "4"}},
+		evs.GetEndpointValues(cfg.Endpoints[0]))
+}
+
+func Test_ChangedStringsAreForwardedAlone(t *testing.T) {
+	cfg := createConfig(config.DeltaModeKeys)
+	detector := NewDetector(cfg, state.NewMemoryStore())
+
+	run(detector, createEndpointValues(cfg, map[string]string{
+		"mode": "active", "owner": "team-a", "replicas": "3", "version": "1.2.0",
+	}))
+
+	// The record is still the same one although a string has changed
+	evs := run(detector, createEndpointValues(cfg, map[string]string{
+		"mode": "standby", "owner": "team-a", "replicas": "3", "version": "1.2.0",
+	}))
+	assert.Equal(t, []map[string]string{{"mode": "standby"}},
+		evs.GetEndpointValues(cfg.Endpoints[0]))
+}
+
+func Test_ChangedRecordsWithoutIdentityAreForwardedWhole(t *testing.T) {
+	cfg := createConfig(config.DeltaModeKeys)
+	detector := NewDetector(cfg, state.NewMemoryStore())
+
+	evs := config.NewEndpointValues()
+	evs.AddEndpointValues(cfg.Endpoints[0], []map[string]string{
+		{"queue": "orders", "depth": "1"},
+		{"queue": "payments", "depth": "2"},
+	})
+	run(detector, evs)
+
+	// The records are matched by their order
+	evs = config.NewEndpointValues()
+	evs.AddEndpointValues(cfg.Endpoints[0], []map[string]string{
+		{"queue": "orders", "depth": "1"},
+		{"queue": "payments", "depth": "5"},
+	})
+	evs = run(detector, evs)
+	assert.Equal(t, []map[string]string{{"queue": "payments", "depth": "5"}},
+		evs.GetEndpointValues(cfg.Endpoints[0]))
+}
+
+func Test_ChangedKeysKeepTheIdentityOfTheirRecord(t *testing.T) {
+	cfg := createConfig(config.DeltaModeKeys)
+	cfg.Endpoints[0].Identity = []string{"queue"}
+	detector := NewDetector(cfg, state.NewMemoryStore())
+
+	evs := config.NewEndpointValues()
+	evs.AddEndpointValues(cfg.Endpoints[0], []map[string]string{
+		{"queue": "orders", "depth": "1"},
+		{"queue": "payments", "depth": "2"},
+	})
+	run(detector, evs)
+
+	// The records are matched by their identity and not by their order
+	evs = config.NewEndpointValues()
+	evs.AddEndpointValues(cfg.Endpoints[0], []map[string]string{
+		{"queue": "payments", "depth": "5"},
+		{"queue": "orders", "depth": "1"},
+	})
+	evs = run(detector, evs)
+	assert.Equal(t, []map[string]string{{"queue": "payments", "depth": "5"}},
+		evs.GetEndpointValues(cfg.Endpoints[0]))
+}
+
+func Test_ConfiguredIdentityIsKept(t *testing.T) {
+	cfg := createConfig(config.DeltaModeKeys)
+	cfg.Endpoints[0].Identity = []string{"name"}
+	detector := NewDetector(cfg, state.NewMemoryStore())
+
+	run(detector, createEndpointValues(cfg, map[string]string{"name": "db", "status": "OK", "region": "eu"}))
+
+	// The changed string is not part of the identity
+	evs := run(detector, createEndpointValues(cfg, map[string]string{"name": "db", "status": "FAILED", "region": "eu"}))
+	assert.Equal(t, []map[string]string{{"name": "db", "status": "FAILED"}},
+		evs.GetEndpointValues(cfg.Endpoints[0]))
+}
+
+func Test_ChangedSnapshotsAreForwarded(t *testing.T) {
+	cfg := createConfig(config.DeltaModeSnapshot)
+	detector := NewDetector(cfg, state.NewMemoryStore())
+
+	run(detector, createEndpointValues(cfg, map[string]string{"version": "1", "replicas": "3"}))
+
+	evs := run(detector, createEndpointValues(cfg, map[string]string{"version": "1", "replicas": "3"}))
+	assert.Equal(t, 0, len(evs.GetEndpoints()))
+
+	// The whole record is forwarded, also if a key is removed
+	evs = run(detector, createEndpointValues(cfg, map[string]string{"version": "1"}))
+	assert.Equal(t, []map[string]string{{"version": "1"}},
+		evs.GetEndpointValues(cfg.Endpoints[0]))
+}
+
+func Test_AllValuesAreForwardedOnHeartbeat(t *testing.T) {
+	now := time.Now()
+	cfg := createConfig(config.DeltaModeKeys)
+	detector := NewDetector(cfg, state.NewMemoryStore())
+	detector.now = func() time.Time { return now }
+
+	run(detector, createEndpointValues(cfg, map[string]string{"version": "1"}))
+
+	now = now.Add(30 * time.Minute)
+	evs := run(detector, createEndpointValues(cfg, map[string]string{"version": "1"}))
+	assert.Equal(t, 0, len(evs.GetEndpoints()))
+
+	now = now.Add(30 * time.Minute)
+	evs = run(detector, createEndpointValues(cfg, map[string]string{"version": "1"}))
+	assert.Equal(t, []map[string]string{{"version": "1"}},
+		evs.GetEndpointValues(cfg.Endpoints[0]))
+}
+
+func Test_EndpointsWithoutDeltaAreForwarded(t *testing.T) {
+	cfg := createConfig(config.DeltaModeKeys)
+	cfg.Endpoints = append(cfg.Endpoints, config.Endpoint{
+		Type: "kvp",
+		Name: "Always",
+		URL:  "always",
+	})
+	store := state.NewMemoryStore()
+	detector := NewDetector(cfg, store)
+
+	for i := 0; i < 2; i++ {
+		evs := config.NewEndpointValues()
+		evs.AddEndpointValues(cfg.Endpoints[0], []map[string]string{{"version": "1"}})
+		evs.AddEndpointValues(cfg.Endpoints[1], []map[string]string{{"version": "1"}})
+
+		evs = run(detector, evs)
+		assert.Equal(t, []map[string]string{{"version": "1"}},
+			evs.GetEndpointValues(cfg.Endpoints[1]))
+	}
+
+	// Only the endpoint in delta mode has a state
+	data, _ := store.Load()
+	assert.Equal(t, 1, len(data))
+
+	// The state is removed if the endpoint leaves the delta mode
+	cfg.Endpoints[0].Delta = nil
+	cfg.Endpoints[1].Delta = &config.DeltaInput{Mode: config.DeltaModeKeys, Heartbeat: time.Hour}
+	evs := config.NewEndpointValues()
+	evs.AddEndpointValues(cfg.Endpoints[1], []map[string]string{{"version": "1"}})
+	run(detector, evs)
+
+	data, _ = store.Load()
+	assert.Equal(t, 1, len(data))
+	_, ok := data[state.EndpointKey(keyPrefix, cfg.Endpoints[1])]
+	assert.True(t, ok)
+}
+
+func Test_StateIsSavedOnlyOnCommit(t *testing.T) {
+	cfg := createConfig(config.DeltaModeKeys)
+	detector := NewDetector(cfg, state.NewMemoryStore())
+
+	// The values could not be sent
+	evs, _ := detector.Run(createEndpointValues(cfg, map[string]string{"version": "1"}))
+	assert.Equal(t, []map[string]string{{"version": "1"}},
+		evs.GetEndpointValues(cfg.Endpoints[0]))
+
+	// So they are forwarded again
+	evs, commit := detector.Run(createEndpointValues(cfg, map[string]string{"version": "1"}))
+	assert.Equal(t, []map[string]string{{"version": "1"}},
+		evs.GetEndpointValues(cfg.Endpoints[0]))
+	commit()
+
+	evs = run(detector, createEndpointValues(cfg, map[string]string{"version": "1"}))
+	assert.Equal(t, 0, len(evs.GetEndpoints()))
+}
+
+// Runs the detector and saves the state as if the values were sent
+func run(
+	detector *Detector,
+	evs *config.EndpointValues,
+) *config.EndpointValues {
+	evs, commit := detector.Run(evs)
+	commit()
+	return evs
+}
+
+func createConfig(
+	mode string,
+) *config.Config {
+	return &config.Config{
+		State: &config.StateInput{Backend: config.StateBackendMemory},
+		Endpoints: []config.Endpoint{
+			{
+				Type: "kvp",
+				Name: "Settings",
+				URL:  "settings",
+				Delta: &config.DeltaInput{
+					Mode:      mode,
+					Heartbeat: time.Hour,
+				},
+			},
+		},
+		Logger: logging.NewLogger("ERROR"),
+	}
+}
+
+func createEndpointValues(
+	cfg *config.Config,
+	values map[string]string,
+) *config.EndpointValues {
+	evs := config.NewEndpointValues()
+	evs.AddEndpointValues(cfg.Endpoints[0], []map[string]string{values})
+	return evs
+}
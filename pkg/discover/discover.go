@@ -0,0 +1,197 @@
+package discover
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/config"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/kube"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/logging"
+)
+
+// Object which creates the endpoints of the discovery rules
+type Discoverer struct {
+	config *config.Config
+}
+
+// Creates new discoverer for the rules of the config
+func NewDiscoverer(
+	cfg *config.Config,
+) *Discoverer {
+	return &Discoverer{
+		config: cfg,
+	}
+}
+
+// Returns a copy of the config which contains the static and the
+// discovered endpoints. Discovered endpoints with the type and the
+// URL of another endpoint are dropped, static endpoints come first.
+func (d *Discoverer) Run() *config.Config {
+	if len(d.config.Discovery) == 0 {
+		return d.config
+	}
+
+	client, err := kube.NewDefaultClient()
+	if err != nil {
+		d.config.Logger.LogWithFields(logrus.ErrorLevel, logging.DISCOVER__KUBE_CLIENT_COULD_NOT_BE_CREATED,
+			map[string]string{
+				"error": err.Error(),
+			})
+		return d.config
+	}
+
+	endpoints := make([]config.Endpoint, 0, len(d.config.Endpoints))
+	keys := map[string]bool{}
+	for _, endpoint := range d.config.Endpoints {
+		endpoints = append(endpoints, endpoint)
+		keys[endpointKey(endpoint)] = true
+	}
+
+	for _, discovery := range d.config.Discovery {
+		for _, endpoint := range d.discover(client, discovery) {
+			key := endpointKey(endpoint)
+			if keys[key] {
+				d.config.Logger.LogWithFields(logrus.DebugLevel, logging.DISCOVER__ENDPOINT_IS_DUPLICATE,
+					map[string]string{
+						"endpointName": endpoint.Name,
+						"endpointUrl":  endpoint.URL,
+					})
+				continue
+			}
+			endpoints = append(endpoints, endpoint)
+			keys[key] = true
+		}
+	}
+
+	d.config.Logger.LogWithFields(logrus.DebugLevel, "Endpoints are discovered.",
+		map[string]string{
+			"staticEndpoints":     strconv.Itoa(len(d.config.Endpoints)),
+			"discoveredEndpoints": strconv.Itoa(len(endpoints) - len(d.config.Endpoints)),
+		})
+
+	cfg := *d.config
+	cfg.Endpoints = endpoints
+	return &cfg
+}
+
+// Creates the endpoints of the running pods which match the rule
+func (d *Discoverer) discover(
+	client *kube.Client,
+	discovery config.DiscoveryInput,
+) []config.Endpoint {
+	nameTemplate, urlTemplate, err := discovery.ParseTemplates()
+	if err != nil {
+		d.logError(logging.DISCOVER__TEMPLATE_COULD_NOT_BE_RENDERED, discovery, nil, err)
+		return nil
+	}
+
+	pods := make([]kube.Pod, 0)
+	for _, namespace := range listedNamespaces(discovery) {
+		namespacePods, err := client.ListPods(namespace, discovery.Selector, discovery.FieldSelector)
+		if err != nil {
+			d.logError(logging.DISCOVER__PODS_COULD_NOT_BE_LISTED, discovery, nil, err)
+			continue
+		}
+		pods = append(pods, namespacePods...)
+	}
+
+	endpoints := make([]config.Endpoint, 0, len(pods))
+	for _, pod := range pods {
+		if pod.Status.Phase != kube.PodPhaseRunning || pod.Status.PodIP == "" {
+			continue
+		}
+		if !discovery.AllowsNamespace(pod.Metadata.Namespace) {
+			continue
+		}
+
+		target := config.DiscoveryTarget{
+			Name:        pod.Metadata.Name,
+			Namespace:   pod.Metadata.Namespace,
+			IP:          pod.Status.PodIP,
+			NodeName:    pod.Spec.NodeName,
+			Labels:      pod.Metadata.Labels,
+			Annotations: pod.Metadata.Annotations,
+		}
+
+		name, err := render(nameTemplate, target)
+		if err != nil {
+			d.logError(logging.DISCOVER__TEMPLATE_COULD_NOT_BE_RENDERED, discovery, &pod, err)
+			continue
+		}
+		url, err := render(urlTemplate, target)
+		if err != nil {
+			d.logError(logging.DISCOVER__TEMPLATE_COULD_NOT_BE_RENDERED, discovery, &pod, err)
+			continue
+		}
+
+		endpoint := discovery.Endpoint
+		endpoint.Name = name
+		endpoint.URL = url
+
+		// The rendered name and URL are only known now
+		if err := config.CheckDiscoveredEndpoint(&endpoint); err != nil {
+			d.logError(logging.DISCOVER__ENDPOINT_IS_INVALID, discovery, &pod, err)
+			continue
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+	return endpoints
+}
+
+// Returns the namespaces which are listed separately. The pods of all
+// namespaces are listed at once if the rule allows any pattern.
+func listedNamespaces(
+	discovery config.DiscoveryInput,
+) []string {
+	if len(discovery.Namespaces) == 0 {
+		return []string{""}
+	}
+	for _, namespace := range discovery.Namespaces {
+		if strings.ContainsAny(namespace, "*?[\\") {
+			return []string{""}
+		}
+	}
+	return discovery.Namespaces
+}
+
+func render(
+	t *template.Template,
+	target config.DiscoveryTarget,
+) (
+	string,
+	error,
+) {
+	var b bytes.Buffer
+	if err := t.Execute(&b, target); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+func (d *Discoverer) logError(
+	message string,
+	discovery config.DiscoveryInput,
+	pod *kube.Pod,
+	err error,
+) {
+	fields := map[string]string{
+		"endpointName": discovery.Endpoint.Name,
+		"error":        err.Error(),
+	}
+	if pod != nil {
+		fields["podName"] = pod.Metadata.Name
+		fields["podNamespace"] = pod.Metadata.Namespace
+	}
+	d.config.Logger.LogWithFields(logrus.ErrorLevel, message, fields)
+}
+
+// Identifies the endpoints which scrape the same source the same way
+func endpointKey(
+	endpoint config.Endpoint,
+) string {
+	return endpoint.Type + "@" + endpoint.URL
+}
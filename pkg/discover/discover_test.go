@@ -0,0 +1,155 @@
+package discover
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/config"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/kube"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/logging"
+)
+
+const pods = `{"items":[
+	{"metadata":{"name":"cart-0","namespace":"team-a","labels":{"app":"cart"}},"status":{"phase":"Running","podIP":"10.0.0.1"}},
+	{"metadata":{"name":"cart-1","namespace":"team-a","labels":{"app":"cart"}},"status":{"phase":"Pending"}},
+	{"metadata":{"name":"search-0","namespace":"team-a-dev","labels":{"app":"search"}},"status":{"phase":"Running","podIP":"10.0.0.2"}},
+	{"metadata":{"name":"billing-0","namespace":"team-b","labels":{"app":"billing"}},"status":{"phase":"Running","podIP":"10.0.0.3"}},
+	{"metadata":{"name":"sidecar-0","namespace":"team-a"},"status":{"phase":"Running","podIP":"10.0.0.4"}}
+]}`
+
+func mockKubeClient(
+	t *testing.T,
+) func() {
+	apiMock := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/api/v1/pods", r.URL.Path)
+			assert.Equal(t, "status.phase=Running", r.URL.Query().Get("fieldSelector"))
+			w.Write([]byte(pods))
+		}))
+
+	newDefaultClientMock := kube.NewDefaultClient
+	kube.NewDefaultClient = func() (*kube.Client, error) {
+		return kube.NewClient(apiMock.URL, "", "default", apiMock.Client()), nil
+	}
+
+	return func() {
+		kube.NewDefaultClient = newDefaultClientMock
+		apiMock.Close()
+	}
+}
+
+func createConfig(
+	discovery []config.DiscoveryInput,
+) *config.Config {
+	return &config.Config{
+		Endpoints: []config.Endpoint{
+			{
+				Type: "json",
+				Name: "CartStatic",
+				URL:  "http://10.0.0.1:8080/status",
+			},
+		},
+		Discovery: discovery,
+		Logger:    logging.NewLogger("ERROR"),
+	}
+}
+
+func Test_EndpointsAreDiscovered(t *testing.T) {
+	defer mockKubeClient(t)()
+
+	cfg := createConfig([]config.DiscoveryInput{
+		{
+			Namespaces:        []string{"team-a*"},
+			ExcludeNamespaces: []string{"*-dev"},
+			FieldSelector:     "status.phase=Running",
+			Endpoint: config.Endpoint{
+				Type: "json",
+				Name: "{{ .Labels.app }}Status",
+				URL:  "http://{{ .IP }}:8080/status",
+			},
+		},
+		{
+			FieldSelector: "status.phase=Running",
+			Endpoint: config.Endpoint{
+				Type: "kvp",
+				Name: "{{ .Namespace }}Info",
+				URL:  "http://{{ .IP }}:8080/info",
+			},
+		},
+	})
+	discovered := NewDiscoverer(cfg).Run()
+
+	// The static config is not changed
+	assert.Equal(t, 1, len(cfg.Endpoints))
+
+	names := []string{}
+	urls := []string{}
+	for _, endpoint := range discovered.Endpoints {
+		names = append(names, endpoint.Name)
+		urls = append(urls, endpoint.URL)
+	}
+
+	// The cart pod is already scraped by the static endpoint, the
+	// sidecar has no app label
+	assert.Equal(t, []string{
+		"CartStatic",
+		"team-aInfo",
+		"team-a-devInfo",
+		"team-bInfo",
+		"team-aInfo",
+	}, names)
+	assert.Equal(t, []string{
+		"http://10.0.0.1:8080/status",
+		"http://10.0.0.1:8080/info",
+		"http://10.0.0.2:8080/info",
+		"http://10.0.0.3:8080/info",
+		"http://10.0.0.4:8080/info",
+	}, urls)
+}
+
+func Test_InvalidDiscoveredEndpointsAreSkipped(t *testing.T) {
+	defer mockKubeClient(t)()
+
+	cfg := createConfig([]config.DiscoveryInput{
+		{
+			FieldSelector: "status.phase=Running",
+			Endpoint: config.Endpoint{
+				Type: "kvp",
+				Name: "{{ index .Annotations \"scraper/name\" }}",
+				URL:  "http://{{ .IP }}:8080/info",
+			},
+		},
+		{
+			FieldSelector: "status.phase=Running",
+			Endpoint: config.Endpoint{
+				Type: "kvp",
+				Name: "{{ .Name }}Stats",
+				URL:  "tcp://{{ .IP }}",
+			},
+		},
+	})
+	discovered := NewDiscoverer(cfg).Run()
+
+	// The names are empty and the URLs have no port
+	assert.Equal(t, 1, len(discovered.Endpoints))
+	assert.Equal(t, "CartStatic", discovered.Endpoints[0].Name)
+}
+
+func Test_NamespacesAreListedSeparately(t *testing.T) {
+	namespaces := listedNamespaces(config.DiscoveryInput{Namespaces: []string{"team-a", "team-b"}})
+	assert.Equal(t, []string{"team-a", "team-b"}, namespaces)
+
+	namespaces = listedNamespaces(config.DiscoveryInput{Namespaces: []string{"team-a", "team-b-*"}})
+	assert.Equal(t, []string{""}, namespaces)
+
+	namespaces = listedNamespaces(config.DiscoveryInput{})
+	assert.Equal(t, []string{""}, namespaces)
+}
+
+func Test_ConfigWithoutDiscoveryIsKept(t *testing.T) {
+	cfg := createConfig(nil)
+	assert.Same(t, cfg, NewDiscoverer(cfg).Run())
+}
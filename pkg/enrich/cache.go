@@ -0,0 +1,69 @@
+package enrich
+
+import (
+	"sync"
+	"time"
+)
+
+// Looked up metadata of a target, nil if the target is not in the
+// cluster or the lookup has failed
+type cacheEntry struct {
+	metadata *metadata
+	err      error
+	expires  time.Time
+}
+
+// Metadata of the targets which is kept across the runs so that the
+// API server is asked once per target and cache TTL
+type Cache struct {
+	mux     *sync.Mutex
+	entries map[string]cacheEntry
+}
+
+func NewCache() *Cache {
+	return &Cache{
+		mux:     &sync.Mutex{},
+		entries: map[string]cacheEntry{},
+	}
+}
+
+func (c *Cache) get(
+	host string,
+	now time.Time,
+) (
+	*metadata,
+	error,
+	bool,
+) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	entry, ok := c.entries[host]
+	if !ok || !now.Before(entry.expires) {
+		return nil, nil, false
+	}
+	return entry.metadata, entry.err, true
+}
+
+func (c *Cache) set(
+	host string,
+	m *metadata,
+	err error,
+	now time.Time,
+	ttl time.Duration,
+) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	// Drop the expired targets which are not scraped anymore
+	for h, entry := range c.entries {
+		if !now.Before(entry.expires) {
+			delete(c.entries, h)
+		}
+	}
+	c.entries[host] = cacheEntry{
+		metadata: m,
+		err:      err,
+		expires:  now.Add(ttl),
+	}
+}
@@ -0,0 +1,429 @@
+package enrich
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/config"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/kube"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/logging"
+)
+
+const (
+	// Prefix of the attributes which are added to the events
+	attributePrefix = "k8s."
+
+	// How long a failed lookup is kept, so that an unreachable API
+	// server is not asked for every endpoint of the target
+	errorCacheTTL = 30 * time.Second
+)
+
+// Kubernetes metadata of a pod or a service
+type metadata struct {
+	Namespace string
+	Pod       string
+	Service   string
+	Node      string
+	OwnerKind string
+	OwnerName string
+	Labels    map[string]string
+}
+
+// Adds the Kubernetes metadata of the targets to the values of the
+// endpoints whose URL refers to a pod or a service of the cluster
+type Enricher struct {
+	config *config.Config
+	cache  *Cache
+	now    func() time.Time
+
+	// Client of the Kubernetes API, created for the first lookup
+	client    *kube.Client
+	clientErr error
+
+	// Controllers of the ReplicaSets which are already looked up
+	replicaSets map[string]kube.OwnerReference
+
+	// Services by their cluster IP, listed for the first IP lookup
+	services map[string]*kube.Service
+}
+
+func NewEnricher(
+	cfg *config.Config,
+	cache *Cache,
+) *Enricher {
+	return &Enricher{
+		config: cfg,
+		cache:  cache,
+		now:    time.Now,
+	}
+}
+
+// Returns the values with the metadata of their targets. Attributes
+// of the records are not overwritten.
+func (e *Enricher) Run(
+	evs *config.EndpointValues,
+) *config.EndpointValues {
+	if e.config.Enrich == nil || !e.config.Enrich.Enabled {
+		return evs
+	}
+
+	e.replicaSets = map[string]kube.OwnerReference{}
+	e.services = nil
+
+	enriched := config.NewEndpointValues()
+	for _, endpoint := range evs.GetEndpoints() {
+		records := evs.GetEndpointValues(endpoint)
+		enriched.AddEndpointTypes(endpoint, evs.GetEndpointTypes(endpoint))
+
+		attributes := e.attributes(endpoint)
+		if len(attributes) == 0 {
+			enriched.AddEndpointValues(endpoint, records)
+			continue
+		}
+
+		for _, record := range records {
+			for key, value := range attributes {
+				if _, ok := record[key]; !ok {
+					record[key] = value
+				}
+			}
+		}
+		enriched.AddEndpointValues(endpoint, records)
+
+		e.config.Logger.LogWithFields(logrus.DebugLevel, "Endpoint values are enriched.",
+			map[string]string{
+				"endpointName": endpoint.Name,
+				"attributes":   strconv.Itoa(len(attributes)),
+			})
+	}
+	return enriched
+}
+
+// Returns the selected metadata of the target of the endpoint as
+// event attributes
+func (e *Enricher) attributes(
+	endpoint config.Endpoint,
+) map[string]string {
+	host := targetHost(endpoint)
+	if host == "" {
+		return nil
+	}
+
+	m, err, ok := e.cache.get(host, e.now())
+	if !ok {
+		m, err = e.lookup(host)
+
+		// Failed lookups are retried sooner
+		ttl := e.config.Enrich.CacheTTL
+		if err != nil {
+			e.config.Logger.LogWithFields(logrus.ErrorLevel, logging.ENRICH__TARGET_COULD_NOT_BE_LOOKED_UP,
+				map[string]string{
+					"endpointName": endpoint.Name,
+					"endpointUrl":  endpoint.URL,
+					"error":        err.Error(),
+				})
+			if ttl > errorCacheTTL {
+				ttl = errorCacheTTL
+			}
+		}
+		e.cache.set(host, m, err, e.now(), ttl)
+	}
+	if err != nil || m == nil {
+		return nil
+	}
+
+	enrich := e.config.Enrich
+	attributes := map[string]string{}
+	add := func(attribute string, key string, value string) {
+		if value != "" && enrich.HasAttribute(attribute) {
+			attributes[attributePrefix+key] = value
+		}
+	}
+	add(config.EnrichAttributeNamespace, "namespace", m.Namespace)
+	add(config.EnrichAttributePod, "podName", m.Pod)
+	add(config.EnrichAttributeService, "serviceName", m.Service)
+	add(config.EnrichAttributeNode, "nodeName", m.Node)
+	add(config.EnrichAttributeOwner, "ownerKind", m.OwnerKind)
+	add(config.EnrichAttributeOwner, "ownerName", m.OwnerName)
+
+	for name, value := range m.Labels {
+		for _, pattern := range enrich.Labels {
+			if ok, _ := path.Match(pattern, name); ok {
+				attributes[attributePrefix+"label."+name] = value
+				break
+			}
+		}
+	}
+	return attributes
+}
+
+// Returns the host of the endpoint URL, empty if the source is not
+// reached over the network
+func targetHost(
+	endpoint config.Endpoint,
+) string {
+	switch endpoint.GetScheme() {
+	case config.SourceSchemeHttp, config.SourceSchemeHttps, config.SourceSchemeTcp:
+	default:
+		return ""
+	}
+
+	raw := endpoint.URL
+	if !strings.Contains(raw, "://") {
+		raw = config.SourceSchemeHttp + "://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "localhost" {
+		return ""
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return ""
+	}
+	return host
+}
+
+// Creates the client of the Kubernetes API once per run
+func (e *Enricher) connect() error {
+	if e.client == nil && e.clientErr == nil {
+		e.client, e.clientErr = kube.NewDefaultClient()
+		if e.clientErr != nil {
+			e.clientErr = fmt.Errorf("%s: %v", logging.ENRICH__KUBE_CLIENT_COULD_NOT_BE_CREATED, e.clientErr)
+		}
+	}
+	return e.clientErr
+}
+
+// Returns the metadata of the pod or the service which the host
+// refers to, nil if it is not in the cluster
+func (e *Enricher) lookup(
+	host string,
+) (
+	*metadata,
+	error,
+) {
+	if err := e.connect(); err != nil {
+		return nil, err
+	}
+
+	if net.ParseIP(host) != nil {
+		return e.lookupIP(host)
+	}
+
+	name, namespace, ok := parseServiceHost(host, e.client.Namespace())
+	if !ok {
+		return nil, nil
+	}
+	service, err := e.client.GetService(namespace, name)
+	if err != nil {
+		if kube.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return e.serviceMetadata(service)
+}
+
+// Returns the metadata of the running pod or the service with the IP.
+// Pods on the host network share the IP of their node and are not
+// distinguished.
+func (e *Enricher) lookupIP(
+	ip string,
+) (
+	*metadata,
+	error,
+) {
+	pods, err := e.client.ListPods("", "", "status.podIP="+ip+",status.phase="+kube.PodPhaseRunning)
+	if err != nil {
+		return nil, err
+	}
+	if len(pods) == 1 {
+		return e.podMetadata(&pods[0])
+	}
+	if len(pods) > 1 {
+		return nil, nil
+	}
+
+	service, err := e.serviceByIP(ip)
+	if err != nil || service == nil {
+		return nil, err
+	}
+	return e.serviceMetadata(service)
+}
+
+// Returns the service with the cluster IP. The services are listed
+// once per run, so that every IP does not list all of them again.
+func (e *Enricher) serviceByIP(
+	ip string,
+) (
+	*kube.Service,
+	error,
+) {
+	if e.services == nil {
+		services, err := e.client.ListServices("")
+		if err != nil {
+			return nil, err
+		}
+		e.services = make(map[string]*kube.Service, len(services))
+		for i := range services {
+			e.services[services[i].Spec.ClusterIP] = &services[i]
+		}
+	}
+	return e.services[ip], nil
+}
+
+func (e *Enricher) podMetadata(
+	pod *kube.Pod,
+) (
+	*metadata,
+	error,
+) {
+	ownerKind, ownerName, err := e.owner(pod)
+	if err != nil {
+		return nil, err
+	}
+	return &metadata{
+		Namespace: pod.Metadata.Namespace,
+		Pod:       pod.Metadata.Name,
+		Node:      pod.Spec.NodeName,
+		OwnerKind: ownerKind,
+		OwnerName: ownerName,
+		Labels:    pod.Metadata.Labels,
+	}, nil
+}
+
+// Returns the metadata of the service. The owner is added if all of
+// its running pods belong to the same workload.
+func (e *Enricher) serviceMetadata(
+	service *kube.Service,
+) (
+	*metadata,
+	error,
+) {
+	m := &metadata{
+		Namespace: service.Metadata.Namespace,
+		Service:   service.Metadata.Name,
+		Labels:    service.Metadata.Labels,
+	}
+	if len(service.Spec.Selector) == 0 {
+		return m, nil
+	}
+
+	pods, err := e.client.ListPods(service.Metadata.Namespace, selectorString(service.Spec.Selector),
+		"status.phase="+kube.PodPhaseRunning)
+	if err != nil {
+		return nil, err
+	}
+	for i := range pods {
+		ownerKind, ownerName, err := e.owner(&pods[i])
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 && (ownerKind != m.OwnerKind || ownerName != m.OwnerName) {
+			m.OwnerKind, m.OwnerName = "", ""
+			break
+		}
+		m.OwnerKind, m.OwnerName = ownerKind, ownerName
+	}
+	return m, nil
+}
+
+// Returns the workload which controls the pod. Pods of a ReplicaSet
+// belong to the Deployment which controls the ReplicaSet.
+func (e *Enricher) owner(
+	pod *kube.Pod,
+) (
+	string,
+	string,
+	error,
+) {
+	owner, ok := pod.Metadata.GetController()
+	if !ok {
+		return "", "", nil
+	}
+	if owner.Kind != "ReplicaSet" {
+		return owner.Kind, owner.Name, nil
+	}
+
+	controller, err := e.replicaSetController(pod.Metadata.Namespace, owner.Name)
+	if err != nil {
+		return "", "", err
+	}
+	if controller.Kind == "Deployment" {
+		return controller.Kind, controller.Name, nil
+	}
+	return owner.Kind, owner.Name, nil
+}
+
+// Returns the controller of the ReplicaSet, empty if it has none
+func (e *Enricher) replicaSetController(
+	namespace string,
+	name string,
+) (
+	kube.OwnerReference,
+	error,
+) {
+	key := namespace + "/" + name
+	if controller, ok := e.replicaSets[key]; ok {
+		return controller, nil
+	}
+
+	controller := kube.OwnerReference{}
+	replicaSets, err := e.client.GetObjects("apps/v1", "ReplicaSet", namespace, name, "")
+	if err != nil && !kube.IsNotFound(err) {
+		return controller, err
+	}
+	if err == nil {
+		controller, _ = replicaSets[0].Metadata.GetController()
+	}
+	e.replicaSets[key] = controller
+	return controller, nil
+}
+
+// Returns the name and the namespace of the service which the host
+// refers to, e.g. "redis", "redis.cache", "redis.cache.svc" or
+// "redis.cache.svc.cluster.local". Single names are resolved in the
+// namespace of the scraper.
+func parseServiceHost(
+	host string,
+	namespace string,
+) (
+	string,
+	string,
+	bool,
+) {
+	labels := strings.Split(host, ".")
+	switch {
+	case len(labels) == 1:
+		return labels[0], namespace, namespace != ""
+	case len(labels) == 2:
+		return labels[0], labels[1], true
+	case labels[2] == "svc":
+		return labels[0], labels[1], true
+	}
+	return "", "", false
+}
+
+// Returns the label selector of the service selector
+func selectorString(
+	selector map[string]string,
+) string {
+	labels := make([]string, 0, len(selector))
+	for name, value := range selector {
+		labels = append(labels, name+"="+value)
+	}
+	sort.Strings(labels)
+	return strings.Join(labels, ",")
+}
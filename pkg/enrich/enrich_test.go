@@ -0,0 +1,341 @@
+package enrich
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/config"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/kube"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/logging"
+)
+
+const cartPod = `{"metadata":{"name":"cart-7d9f-x2x","namespace":"shop","labels":{"app":"cart","pod-template-hash":"7d9f"},` +
+	`"ownerReferences":[{"apiVersion":"apps/v1","kind":"ReplicaSet","name":"cart-7d9f","controller":true}]},` +
+	`"spec":{"nodeName":"node-1"},"status":{"phase":"Running","podIP":"10.0.0.1"}}`
+
+const cartService = `{"metadata":{"name":"cart","namespace":"shop","labels":{"app":"cart"}},` +
+	`"spec":{"clusterIP":"10.96.0.10","selector":{"app":"cart"}}}`
+
+// API server with a Deployment behind a service and a StatefulSet pod
+func mockKubeClient(
+	t *testing.T,
+	requests map[string]int,
+) func() {
+	apiMock := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			requests[r.URL.Path]++
+			switch r.URL.Path {
+			case "/api/v1/pods":
+				switch r.URL.Query().Get("fieldSelector") {
+				case "status.podIP=10.0.0.1,status.phase=Running":
+					w.Write([]byte(`{"items":[` + cartPod + `]}`))
+				case "status.podIP=10.0.0.2,status.phase=Running":
+					w.Write([]byte(`{"items":[{"metadata":{"name":"redis-0","namespace":"cache",` +
+						`"ownerReferences":[{"apiVersion":"apps/v1","kind":"StatefulSet","name":"redis","controller":true}]},` +
+						`"spec":{"nodeName":"node-2"},"status":{"phase":"Running","podIP":"10.0.0.2"}}]}`))
+				default:
+					w.Write([]byte(`{"items":[]}`))
+				}
+			case "/api/v1/services":
+				w.Write([]byte(`{"items":[` + cartService + `]}`))
+			case "/api/v1/namespaces/shop/services/cart":
+				w.Write([]byte(cartService))
+			case "/api/v1/namespaces/shop/pods":
+				assert.Equal(t, "app=cart", r.URL.Query().Get("labelSelector"))
+				w.Write([]byte(`{"items":[` + cartPod + `]}`))
+			case "/apis/apps/v1":
+				w.Write([]byte(`{"groupVersion":"apps/v1","resources":[{"name":"replicasets","kind":"ReplicaSet","namespaced":true}]}`))
+			case "/apis/apps/v1/namespaces/shop/replicasets/cart-7d9f":
+				w.Write([]byte(`{"metadata":{"name":"cart-7d9f","namespace":"shop",` +
+					`"ownerReferences":[{"apiVersion":"apps/v1","kind":"Deployment","name":"cart","controller":true}]}}`))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+
+	newDefaultClientMock := kube.NewDefaultClient
+	kube.NewDefaultClient = func() (*kube.Client, error) {
+		return kube.NewClient(apiMock.URL, "", "shop", apiMock.Client()), nil
+	}
+
+	return func() {
+		kube.NewDefaultClient = newDefaultClientMock
+		apiMock.Close()
+	}
+}
+
+func createConfig(
+	enrich *config.EnrichInput,
+) *config.Config {
+	return &config.Config{
+		Enrich: enrich,
+		Logger: logging.NewLogger("ERROR"),
+	}
+}
+
+func createEndpointValues(
+	urls ...string,
+) *config.EndpointValues {
+	evs := config.NewEndpointValues()
+	for _, url := range urls {
+		evs.AddEndpointValues(config.Endpoint{Type: "json", Name: url, URL: url},
+			[]map[string]string{{"status": "ok"}})
+	}
+	return evs
+}
+
+func Test_PodAndServiceTargetsAreEnriched(t *testing.T) {
+	requests := map[string]int{}
+	defer mockKubeClient(t, requests)()
+
+	cfg := createConfig(&config.EnrichInput{
+		Enabled: true,
+		Attributes: []string{
+			config.EnrichAttributeNamespace,
+			config.EnrichAttributePod,
+			config.EnrichAttributeService,
+			config.EnrichAttributeNode,
+			config.EnrichAttributeOwner,
+		},
+		Labels:   []string{"app"},
+		CacheTTL: time.Minute,
+	})
+	evs := createEndpointValues(
+		"http://10.0.0.1:8080/status",
+		"http://cart.shop.svc.cluster.local:8080/status",
+		"tcp://10.0.0.2:6379",
+		"https://example.org/status",
+		"file:///var/run/status.json",
+	)
+	endpoints := evs.GetEndpoints()
+	evs = NewEnricher(cfg, NewCache()).Run(evs)
+
+	assert.Equal(t, map[string]string{
+		"status":        "ok",
+		"k8s.namespace": "shop",
+		"k8s.podName":   "cart-7d9f-x2x",
+		"k8s.nodeName":  "node-1",
+		"k8s.ownerKind": "Deployment",
+		"k8s.ownerName": "cart",
+		"k8s.label.app": "cart",
+	}, evs.GetEndpointValues(endpoints[0])[0])
+
+	assert.Equal(t, map[string]string{
+		"status":          "ok",
+		"k8s.namespace":   "shop",
+		"k8s.serviceName": "cart",
+		"k8s.ownerKind":   "Deployment",
+		"k8s.ownerName":   "cart",
+		"k8s.label.app":   "cart",
+	}, evs.GetEndpointValues(endpoints[1])[0])
+
+	assert.Equal(t, map[string]string{
+		"status":        "ok",
+		"k8s.namespace": "cache",
+		"k8s.podName":   "redis-0",
+		"k8s.nodeName":  "node-2",
+		"k8s.ownerKind": "StatefulSet",
+		"k8s.ownerName": "redis",
+	}, evs.GetEndpointValues(endpoints[2])[0])
+
+	// Targets outside of the cluster are kept as they are
+	assert.Equal(t, map[string]string{"status": "ok"}, evs.GetEndpointValues(endpoints[3])[0])
+	assert.Equal(t, map[string]string{"status": "ok"}, evs.GetEndpointValues(endpoints[4])[0])
+
+	// The ReplicaSet is looked up once for the pod and the service
+	assert.Equal(t, 1, requests["/apis/apps/v1/namespaces/shop/replicasets/cart-7d9f"])
+}
+
+func Test_MetadataIsCached(t *testing.T) {
+	requests := map[string]int{}
+	defer mockKubeClient(t, requests)()
+
+	cfg := createConfig(&config.EnrichInput{
+		Enabled:    true,
+		Attributes: []string{config.EnrichAttributeNamespace},
+		CacheTTL:   time.Minute,
+	})
+	cache := NewCache()
+	now := time.Now()
+
+	run := func() map[string]string {
+		evs := createEndpointValues("http://10.0.0.2:6379", "http://192.168.0.1/status")
+		enricher := NewEnricher(cfg, cache)
+		enricher.now = func() time.Time { return now }
+		evs = enricher.Run(evs)
+		return evs.GetEndpointValues(evs.GetEndpoints()[0])[0]
+	}
+
+	assert.Equal(t, map[string]string{"status": "ok", "k8s.namespace": "cache"}, run())
+	run()
+	assert.Equal(t, 2, requests["/api/v1/pods"])
+	assert.Equal(t, 1, requests["/api/v1/services"])
+
+	// The targets are looked up again after the TTL
+	now = now.Add(time.Minute)
+	run()
+	assert.Equal(t, 4, requests["/api/v1/pods"])
+	assert.Equal(t, 2, requests["/api/v1/services"])
+}
+
+func Test_ServicesAreListedOncePerRun(t *testing.T) {
+	requests := map[string]int{}
+	defer mockKubeClient(t, requests)()
+
+	cfg := createConfig(&config.EnrichInput{
+		Enabled:    true,
+		Attributes: []string{config.EnrichAttributeService},
+		CacheTTL:   time.Minute,
+	})
+	evs := createEndpointValues(
+		"http://10.96.0.10:8080/status",
+		"http://192.168.0.1/status",
+		"http://192.168.0.2/status",
+	)
+	endpoints := evs.GetEndpoints()
+	evs = NewEnricher(cfg, NewCache()).Run(evs)
+
+	assert.Equal(t, "cart", evs.GetEndpointValues(endpoints[0])[0]["k8s.serviceName"])
+	assert.Equal(t, 3, requests["/api/v1/pods"])
+	assert.Equal(t, 1, requests["/api/v1/services"])
+}
+
+func Test_ClientIsCreatedOnCacheMiss(t *testing.T) {
+	defer mockKubeClient(t, map[string]int{})()
+
+	clients := 0
+	newDefaultClientMock := kube.NewDefaultClient
+	kube.NewDefaultClient = func() (*kube.Client, error) {
+		clients++
+		return newDefaultClientMock()
+	}
+
+	cfg := createConfig(&config.EnrichInput{
+		Enabled:    true,
+		Attributes: []string{config.EnrichAttributeNamespace},
+		CacheTTL:   time.Minute,
+	})
+	cache := NewCache()
+
+	// Targets outside of the cluster need no client
+	NewEnricher(cfg, cache).Run(createEndpointValues("file:///var/run/status.json"))
+	assert.Equal(t, 0, clients)
+
+	NewEnricher(cfg, cache).Run(createEndpointValues("http://10.0.0.1:8080/status", "tcp://10.0.0.2:6379"))
+	assert.Equal(t, 1, clients)
+
+	// Cached targets are enriched without a client
+	evs := NewEnricher(cfg, cache).Run(createEndpointValues("http://10.0.0.1:8080/status"))
+	assert.Equal(t, "shop", evs.GetEndpointValues(evs.GetEndpoints()[0])[0]["k8s.namespace"])
+	assert.Equal(t, 1, clients)
+}
+
+func Test_FailedLookupsAreCachedBriefly(t *testing.T) {
+	requests := 0
+	apiMock := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+	defer apiMock.Close()
+
+	newDefaultClientMock := kube.NewDefaultClient
+	kube.NewDefaultClient = func() (*kube.Client, error) {
+		return kube.NewClient(apiMock.URL, "", "shop", apiMock.Client()), nil
+	}
+	defer func() { kube.NewDefaultClient = newDefaultClientMock }()
+
+	cfg := createConfig(&config.EnrichInput{
+		Enabled:    true,
+		Attributes: []string{config.EnrichAttributeNamespace},
+		CacheTTL:   time.Hour,
+	})
+	cache := NewCache()
+	now := time.Now()
+
+	run := func() map[string]string {
+		evs := createEndpointValues("http://10.0.0.1:8080/status")
+		enricher := NewEnricher(cfg, cache)
+		enricher.now = func() time.Time { return now }
+		evs = enricher.Run(evs)
+		return evs.GetEndpointValues(evs.GetEndpoints()[0])[0]
+	}
+
+	assert.Equal(t, map[string]string{"status": "ok"}, run())
+	assert.Equal(t, 1, requests)
+
+	// The failure is kept for a short time only
+	now = now.Add(errorCacheTTL / 2)
+	assert.Equal(t, map[string]string{"status": "ok"}, run())
+	assert.Equal(t, 1, requests)
+
+	now = now.Add(errorCacheTTL)
+	run()
+	assert.Equal(t, 2, requests)
+}
+
+func Test_ClientErrorIsReported(t *testing.T) {
+	newDefaultClientMock := kube.NewDefaultClient
+	clients := 0
+	kube.NewDefaultClient = func() (*kube.Client, error) {
+		clients++
+		return nil, errors.New("not in cluster")
+	}
+	defer func() { kube.NewDefaultClient = newDefaultClientMock }()
+
+	cfg := createConfig(&config.EnrichInput{
+		Enabled:    true,
+		Attributes: []string{config.EnrichAttributeNamespace},
+		CacheTTL:   time.Minute,
+	})
+	enricher := NewEnricher(cfg, NewCache())
+	evs := enricher.Run(createEndpointValues("http://10.0.0.1:8080/status", "tcp://10.0.0.2:6379"))
+
+	// The client is created once per run
+	assert.Equal(t, 1, clients)
+	assert.Equal(t, map[string]string{"status": "ok"}, evs.GetEndpointValues(evs.GetEndpoints()[1])[0])
+	assert.Contains(t, enricher.clientErr.Error(), logging.ENRICH__KUBE_CLIENT_COULD_NOT_BE_CREATED)
+}
+
+func Test_RecordAttributesAreNotOverwritten(t *testing.T) {
+	defer mockKubeClient(t, map[string]int{})()
+
+	cfg := createConfig(&config.EnrichInput{
+		Enabled:    true,
+		Attributes: []string{config.EnrichAttributeNamespace},
+		CacheTTL:   time.Minute,
+	})
+	evs := config.NewEndpointValues()
+	endpoint := config.Endpoint{Type: "json", Name: "Redis", URL: "tcp://10.0.0.2:6379"}
+	evs.AddEndpointValues(endpoint, []map[string]string{{"k8s.namespace": "custom"}})
+
+	evs = NewEnricher(cfg, NewCache()).Run(evs)
+	assert.Equal(t, "custom", evs.GetEndpointValues(endpoint)[0]["k8s.namespace"])
+}
+
+func Test_DisabledEnrichKeepsValues(t *testing.T) {
+	evs := createEndpointValues("http://10.0.0.1:8080/status")
+	assert.Same(t, evs, NewEnricher(createConfig(nil), NewCache()).Run(evs))
+	assert.Same(t, evs, NewEnricher(createConfig(&config.EnrichInput{}), NewCache()).Run(evs))
+}
+
+func Test_ServiceHostIsParsed(t *testing.T) {
+	for host, expected := range map[string][2]string{
+		"redis":                         {"redis", "shop"},
+		"redis.cache":                   {"redis", "cache"},
+		"redis.cache.svc":               {"redis", "cache"},
+		"redis.cache.svc.cluster.local": {"redis", "cache"},
+	} {
+		name, namespace, ok := parseServiceHost(host, "shop")
+		assert.True(t, ok, host)
+		assert.Equal(t, expected, [2]string{name, namespace}, host)
+	}
+
+	_, _, ok := parseServiceHost("api.example.org", "shop")
+	assert.False(t, ok)
+}
@@ -0,0 +1,710 @@
+package expr
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Compiled expression over the values of a record. The following is
+// supported, from the lowest to the highest precedence:
+//   - logical operators: ||, &&
+//   - comparisons: ==, !=, <, <=, >, >=
+//   - addition and subtraction: +, - (+ concatenates strings)
+//   - multiplication and division: *, /, %
+//   - unary operators: -, !
+//   - literals: 42, 1.5, "text", 'text', true, false
+//   - keys of the record: pool.active or `key with spaces`
+//   - functions: see functions
+//   - parentheses
+type Expression struct {
+	source string
+	root   node
+}
+
+func Compile(
+	source string,
+) (
+	*Expression,
+	error,
+) {
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokenEnd {
+		return nil, fmt.Errorf("unexpected %q at %d", p.peek().text, p.peek().pos)
+	}
+
+	return &Expression{
+		source: source,
+		root:   root,
+	}, nil
+}
+
+func (e *Expression) String() string {
+	return e.source
+}
+
+// Evaluates the expression against the values of a record
+func (e *Expression) Eval(
+	values map[string]string,
+) (
+	Value,
+	error,
+) {
+	v, err := e.root.eval(values)
+	if err != nil {
+		return Value{}, err
+	}
+	if v.kind == KindNumber && (math.IsNaN(v.num) || math.IsInf(v.num, 0)) {
+		return Value{}, fmt.Errorf("result is not a finite number")
+	}
+	return v, nil
+}
+
+// Lexer
+
+type tokenKind int
+
+const (
+	tokenEnd tokenKind = iota
+	tokenNumber
+	tokenString
+	tokenIdent
+	tokenOperator
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// Operators with two characters are matched first
+var operators = []string{"||", "&&", "==", "!=", "<=", ">=", "<", ">", "+", "-", "*", "/", "%", "!", "(", ")", ","}
+
+func tokenize(
+	source string,
+) (
+	[]token,
+	error,
+) {
+	tokens := make([]token, 0)
+	i := 0
+	for i < len(source) {
+		c := rune(source[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+
+		case unicode.IsDigit(c) || (c == '.' && i+1 < len(source) && unicode.IsDigit(rune(source[i+1]))):
+			start := i
+			for i < len(source) && (unicode.IsDigit(rune(source[i])) || source[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: source[start:i], pos: start})
+
+		case c == '"' || c == '\'':
+			start := i
+			text, end, err := readQuoted(source, i)
+			if err != nil {
+				return nil, err
+			}
+			i = end
+			tokens = append(tokens, token{kind: tokenString, text: text, pos: start})
+
+		case c == '`':
+			start := i
+			end := strings.IndexByte(source[i+1:], '`')
+			if end == -1 {
+				return nil, fmt.Errorf("missing closing ` for the key at %d", start)
+			}
+			i += end + 2
+			tokens = append(tokens, token{kind: tokenIdent, text: source[start+1 : i-1], pos: start})
+
+		case c == '_' || unicode.IsLetter(c):
+			start := i
+			for i < len(source) && isIdentChar(rune(source[i])) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: source[start:i], pos: start})
+
+		default:
+			matched := false
+			for _, op := range operators {
+				if strings.HasPrefix(source[i:], op) {
+					tokens = append(tokens, token{kind: tokenOperator, text: op, pos: i})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("unexpected %q at %d", c, i)
+			}
+		}
+	}
+
+	return append(tokens, token{kind: tokenEnd, pos: len(source)}), nil
+}
+
+func isIdentChar(
+	c rune,
+) bool {
+	return c == '_' || c == '.' || unicode.IsLetter(c) || unicode.IsDigit(c)
+}
+
+// Reads a quoted string with backslash escapes and returns the
+// position after the closing quote
+func readQuoted(
+	source string,
+	start int,
+) (
+	string,
+	int,
+	error,
+) {
+	quote := source[start]
+	var b strings.Builder
+	for i := start + 1; i < len(source); i++ {
+		switch source[i] {
+		case '\\':
+			if i+1 < len(source) {
+				i++
+				b.WriteByte(source[i])
+			}
+		case quote:
+			return b.String(), i + 1, nil
+		default:
+			b.WriteByte(source[i])
+		}
+	}
+	return "", 0, fmt.Errorf("missing closing %c for the string at %d", quote, start)
+}
+
+// Parser
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEnd {
+		p.pos++
+	}
+	return t
+}
+
+// Consumes the next token if it is one of the given operators
+func (p *parser) accept(
+	ops ...string,
+) (
+	string,
+	bool,
+) {
+	t := p.peek()
+	if t.kind != tokenOperator {
+		return "", false
+	}
+	for _, op := range ops {
+		if t.text == op {
+			p.pos++
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *parser) parseOr() (
+	node,
+	error,
+) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("||"); !ok {
+			return left, nil
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{op: "||", left: left, right: right}
+	}
+}
+
+func (p *parser) parseAnd() (
+	node,
+	error,
+) {
+	left, err := p.parseComparison()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("&&"); !ok {
+			return left, nil
+		}
+		right, err := p.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{op: "&&", left: left, right: right}
+	}
+}
+
+func (p *parser) parseComparison() (
+	node,
+	error,
+) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	op, ok := p.accept("==", "!=", "<=", ">=", "<", ">")
+	if !ok {
+		return left, nil
+	}
+	right, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	return &comparisonNode{op: op, left: left, right: right}, nil
+}
+
+func (p *parser) parseAdditive() (
+	node,
+	error,
+) {
+	left, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept("+", "-")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		left = &arithmeticNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) parseMultiplicative() (
+	node,
+	error,
+) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept("*", "/", "%")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &arithmeticNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) parseUnary() (
+	node,
+	error,
+) {
+	op, ok := p.accept("-", "!")
+	if !ok {
+		return p.parsePrimary()
+	}
+	operand, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	return &unaryNode{op: op, operand: operand}, nil
+}
+
+func (p *parser) parsePrimary() (
+	node,
+	error,
+) {
+	t := p.next()
+	switch t.kind {
+	case tokenNumber:
+		num, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at %d", t.text, t.pos)
+		}
+		return &literalNode{value: NumberValue(num)}, nil
+
+	case tokenString:
+		return &literalNode{value: StringValue(t.text)}, nil
+
+	case tokenIdent:
+		if _, ok := p.accept("("); ok {
+			return p.parseCall(t)
+		}
+		switch t.text {
+		case "true":
+			return &literalNode{value: BoolValue(true)}, nil
+		case "false":
+			return &literalNode{value: BoolValue(false)}, nil
+		}
+		return &keyNode{key: t.text}, nil
+
+	case tokenOperator:
+		if t.text == "(" {
+			inner, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := p.accept(")"); !ok {
+				return nil, fmt.Errorf("missing ) at %d", p.peek().pos)
+			}
+			return inner, nil
+		}
+	}
+
+	if t.kind == tokenEnd {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	return nil, fmt.Errorf("unexpected %q at %d", t.text, t.pos)
+}
+
+func (p *parser) parseCall(
+	name token,
+) (
+	node,
+	error,
+) {
+	f, ok := functions[name.text]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %q at %d", name.text, name.pos)
+	}
+
+	args := make([]node, 0)
+	if _, ok := p.accept(")"); !ok {
+		for {
+			arg, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if _, ok := p.accept(","); ok {
+				continue
+			}
+			if _, ok := p.accept(")"); ok {
+				break
+			}
+			return nil, fmt.Errorf("missing ) at %d", p.peek().pos)
+		}
+	}
+
+	if len(args) < f.minArgs || (f.maxArgs >= 0 && len(args) > f.maxArgs) {
+		return nil, fmt.Errorf("wrong number of arguments for %s at %d", name.text, name.pos)
+	}
+	return &callNode{name: name.text, function: f, args: args}, nil
+}
+
+// Nodes
+
+type node interface {
+	eval(values map[string]string) (Value, error)
+}
+
+type literalNode struct {
+	value Value
+}
+
+func (n *literalNode) eval(
+	values map[string]string,
+) (
+	Value,
+	error,
+) {
+	return n.value, nil
+}
+
+type keyNode struct {
+	key string
+}
+
+func (n *keyNode) eval(
+	values map[string]string,
+) (
+	Value,
+	error,
+) {
+	value, ok := values[n.key]
+	if !ok {
+		return Value{}, fmt.Errorf("key %q is not defined", n.key)
+	}
+	return inferValue(value), nil
+}
+
+type unaryNode struct {
+	op      string
+	operand node
+}
+
+func (n *unaryNode) eval(
+	values map[string]string,
+) (
+	Value,
+	error,
+) {
+	v, err := n.operand.eval(values)
+	if err != nil {
+		return Value{}, err
+	}
+
+	if n.op == "!" {
+		b, err := v.bool()
+		if err != nil {
+			return Value{}, err
+		}
+		return BoolValue(!b), nil
+	}
+
+	num, err := v.number()
+	if err != nil {
+		return Value{}, err
+	}
+	return NumberValue(-num), nil
+}
+
+type logicalNode struct {
+	op    string
+	left  node
+	right node
+}
+
+func (n *logicalNode) eval(
+	values map[string]string,
+) (
+	Value,
+	error,
+) {
+	left, err := evalBool(n.left, values)
+	if err != nil {
+		return Value{}, err
+	}
+
+	// The right operand is only evaluated if it is needed
+	if (n.op == "&&" && !left) || (n.op == "||" && left) {
+		return BoolValue(left), nil
+	}
+
+	right, err := evalBool(n.right, values)
+	if err != nil {
+		return Value{}, err
+	}
+	return BoolValue(right), nil
+}
+
+type arithmeticNode struct {
+	op    string
+	left  node
+	right node
+}
+
+func (n *arithmeticNode) eval(
+	values map[string]string,
+) (
+	Value,
+	error,
+) {
+	left, err := n.left.eval(values)
+	if err != nil {
+		return Value{}, err
+	}
+	right, err := n.right.eval(values)
+	if err != nil {
+		return Value{}, err
+	}
+
+	// Strings which are no numbers are concatenated
+	if n.op == "+" && (left.kind == KindString || right.kind == KindString) {
+		return StringValue(left.String() + right.String()), nil
+	}
+
+	a, err := left.number()
+	if err != nil {
+		return Value{}, err
+	}
+	b, err := right.number()
+	if err != nil {
+		return Value{}, err
+	}
+
+	switch n.op {
+	case "+":
+		return NumberValue(a + b), nil
+	case "-":
+		return NumberValue(a - b), nil
+	case "*":
+		return NumberValue(a * b), nil
+	case "/":
+		if b == 0 {
+			return Value{}, fmt.Errorf("division by zero")
+		}
+		return NumberValue(a / b), nil
+	default:
+		if b == 0 {
+			return Value{}, fmt.Errorf("division by zero")
+		}
+		return NumberValue(math.Mod(a, b)), nil
+	}
+}
+
+type comparisonNode struct {
+	op    string
+	left  node
+	right node
+}
+
+func (n *comparisonNode) eval(
+	values map[string]string,
+) (
+	Value,
+	error,
+) {
+	left, err := n.left.eval(values)
+	if err != nil {
+		return Value{}, err
+	}
+	right, err := n.right.eval(values)
+	if err != nil {
+		return Value{}, err
+	}
+
+	var cmp int
+	switch {
+	// Numbers are compared numerically if the other side is numeric
+	case left.kind == KindNumber || right.kind == KindNumber:
+		a, errA := left.number()
+		b, errB := right.number()
+		if errA != nil || errB != nil {
+			if n.op == "==" || n.op == "!=" {
+				return BoolValue(n.op == "!="), nil
+			}
+			return Value{}, fmt.Errorf("%s and %s cannot be compared", left.kind, right.kind)
+		}
+		cmp = compareNumbers(a, b)
+
+	case left.kind == KindBool && right.kind == KindBool:
+		if n.op != "==" && n.op != "!=" {
+			return Value{}, fmt.Errorf("bools can only be compared with == and !=")
+		}
+		if left.b != right.b {
+			cmp = 1
+		}
+
+	default:
+		cmp = strings.Compare(left.String(), right.String())
+	}
+
+	switch n.op {
+	case "==":
+		return BoolValue(cmp == 0), nil
+	case "!=":
+		return BoolValue(cmp != 0), nil
+	case "<":
+		return BoolValue(cmp < 0), nil
+	case "<=":
+		return BoolValue(cmp <= 0), nil
+	case ">":
+		return BoolValue(cmp > 0), nil
+	default:
+		return BoolValue(cmp >= 0), nil
+	}
+}
+
+func compareNumbers(
+	a float64,
+	b float64,
+) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func evalBool(
+	n node,
+	values map[string]string,
+) (
+	bool,
+	error,
+) {
+	v, err := n.eval(values)
+	if err != nil {
+		return false, err
+	}
+	return v.bool()
+}
+
+type callNode struct {
+	name     string
+	function function
+	args     []node
+}
+
+func (n *callNode) eval(
+	values map[string]string,
+) (
+	Value,
+	error,
+) {
+	// The branches of if are only evaluated if they are taken
+	if n.name == "if" {
+		cond, err := evalBool(n.args[0], values)
+		if err != nil {
+			return Value{}, err
+		}
+		if cond {
+			return n.args[1].eval(values)
+		}
+		return n.args[2].eval(values)
+	}
+
+	args := make([]Value, 0, len(n.args))
+	for _, arg := range n.args {
+		v, err := arg.eval(values)
+		if err != nil {
+			return Value{}, err
+		}
+		args = append(args, v)
+	}
+
+	v, err := n.function.call(values, args)
+	if err != nil {
+		return Value{}, fmt.Errorf("%s: %s", n.name, err.Error())
+	}
+	return v, nil
+}
@@ -0,0 +1,105 @@
+package expr
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ExpressionsAreEvaluated(t *testing.T) {
+	values := map[string]string{
+		"active":       "5",
+		"max":          "8",
+		"status":       "OK",
+		"ready":        "true",
+		"id":           "007",
+		"pool.waiting": "2",
+		"queue depth":  "3",
+	}
+
+	tests := []struct {
+		expression string
+		expected   string
+		kind       Kind
+	}{
+		{"active / max * 100", "62.5", KindNumber},
+		{"(active + pool.waiting) * 2", "14", KindNumber},
+		{"-active % 3", "-2", KindNumber},
+		{"`queue depth` >= 3", "true", KindBool},
+		{"status == \"OK\"", "true", KindBool},
+		{"status != 'OK' || !ready", "false", KindBool},
+		{"active > 10 && missing > 0", "false", KindBool},
+		{"\"id-\" + id", "id-007", KindString},
+		{"id == 7", "true", KindBool},
+		{"if(active < max, 'free', 'full')", "free", KindString},
+		{"has('missing')", "false", KindBool},
+		{"round(active / 3, 2)", "1.67", KindNumber},
+		{"max(active, pool.waiting, 1)", "5", KindNumber},
+		{"upper(lower(status)) + len(status)", "OK2", KindString},
+	}
+
+	for _, test := range tests {
+		e, err := Compile(test.expression)
+		assert.Nil(t, err, test.expression)
+
+		v, err := e.Eval(values)
+		assert.Nil(t, err, test.expression)
+		assert.Equal(t, test.expected, v.String(), test.expression)
+		assert.Equal(t, test.kind, v.Kind(), test.expression)
+	}
+}
+
+func Test_InvalidExpressionsAreNotCompiled(t *testing.T) {
+	expressions := []string{
+		"",
+		"active +",
+		"(active",
+		"active max",
+		"'open",
+		"unknown(active)",
+		"round()",
+		"active # 2",
+	}
+
+	for _, expression := range expressions {
+		_, err := Compile(expression)
+		assert.NotNil(t, err, expression)
+	}
+}
+
+func Test_EvaluationErrorsAreReturned(t *testing.T) {
+	values := map[string]string{
+		"active": "5",
+		"zero":   "0",
+		"status": "OK",
+	}
+
+	expressions := []string{
+		"missing + 1",
+		"active / zero",
+		"status * 2",
+		"status && true",
+		"status < 1",
+	}
+
+	for _, expression := range expressions {
+		e, err := Compile(expression)
+		assert.Nil(t, err, expression)
+
+		_, err = e.Eval(values)
+		assert.NotNil(t, err, expression)
+	}
+}
+
+func Test_ValuesAreConverted(t *testing.T) {
+	v, err := StringValue("42").Convert(KindNumber)
+	assert.Nil(t, err)
+	assert.Equal(t, KindNumber, v.Kind())
+
+	v, err = NumberValue(1.5).Convert(KindString)
+	assert.Nil(t, err)
+	assert.Equal(t, "1.5", v.String())
+
+	_, err = StringValue("OK").Convert(KindBool)
+	assert.NotNil(t, err)
+}
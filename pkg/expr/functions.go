@@ -0,0 +1,96 @@
+package expr
+
+import (
+	"math"
+	"strings"
+)
+
+type function struct {
+	// Number of arguments, maxArgs is -1 if unlimited
+	minArgs int
+	maxArgs int
+
+	call func(values map[string]string, args []Value) (Value, error)
+}
+
+// Functions which can be called in expressions
+var functions = map[string]function{
+	// if(condition, then, else)
+	"if": {minArgs: 3, maxArgs: 3},
+
+	// has("key") is true if the record has the key
+	"has": {minArgs: 1, maxArgs: 1, call: func(values map[string]string, args []Value) (Value, error) {
+		_, ok := values[args[0].String()]
+		return BoolValue(ok), nil
+	}},
+
+	"lower": {minArgs: 1, maxArgs: 1, call: func(values map[string]string, args []Value) (Value, error) {
+		return StringValue(strings.ToLower(args[0].String())), nil
+	}},
+
+	"upper": {minArgs: 1, maxArgs: 1, call: func(values map[string]string, args []Value) (Value, error) {
+		return StringValue(strings.ToUpper(args[0].String())), nil
+	}},
+
+	"contains": {minArgs: 2, maxArgs: 2, call: func(values map[string]string, args []Value) (Value, error) {
+		return BoolValue(strings.Contains(args[0].String(), args[1].String())), nil
+	}},
+
+	"len": {minArgs: 1, maxArgs: 1, call: func(values map[string]string, args []Value) (Value, error) {
+		return NumberValue(float64(len(args[0].String()))), nil
+	}},
+
+	"abs": {minArgs: 1, maxArgs: 1, call: func(values map[string]string, args []Value) (Value, error) {
+		num, err := args[0].number()
+		if err != nil {
+			return Value{}, err
+		}
+		return NumberValue(math.Abs(num)), nil
+	}},
+
+	// round(number) or round(number, digits)
+	"round": {minArgs: 1, maxArgs: 2, call: func(values map[string]string, args []Value) (Value, error) {
+		num, err := args[0].number()
+		if err != nil {
+			return Value{}, err
+		}
+		digits := 0.0
+		if len(args) == 2 {
+			digits, err = args[1].number()
+			if err != nil {
+				return Value{}, err
+			}
+		}
+		scale := math.Pow(10, math.Trunc(digits))
+		return NumberValue(math.Round(num*scale) / scale), nil
+	}},
+
+	"min": {minArgs: 1, maxArgs: -1, call: func(values map[string]string, args []Value) (Value, error) {
+		return reduceNumbers(args, math.Min)
+	}},
+
+	"max": {minArgs: 1, maxArgs: -1, call: func(values map[string]string, args []Value) (Value, error) {
+		return reduceNumbers(args, math.Max)
+	}},
+}
+
+func reduceNumbers(
+	args []Value,
+	reduce func(float64, float64) float64,
+) (
+	Value,
+	error,
+) {
+	result, err := args[0].number()
+	if err != nil {
+		return Value{}, err
+	}
+	for _, arg := range args[1:] {
+		num, err := arg.number()
+		if err != nil {
+			return Value{}, err
+		}
+		result = reduce(result, num)
+	}
+	return NumberValue(result), nil
+}
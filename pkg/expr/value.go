@@ -0,0 +1,173 @@
+package expr
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Type of a value
+type Kind int
+
+const (
+	KindString Kind = iota
+	KindNumber
+	KindBool
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindNumber:
+		return "number"
+	case KindBool:
+		return "bool"
+	default:
+		return "string"
+	}
+}
+
+// Typed value of an expression. The values of the records are plain
+// strings; they are typed by their content when they are read, e.g.
+// "42" is a number and "true" is a bool.
+type Value struct {
+	kind Kind
+	num  float64
+	b    bool
+
+	// Text of the value as it was read from the record, if any, so
+	// that e.g. "007" is not turned into "7"
+	str string
+}
+
+func NumberValue(
+	num float64,
+) Value {
+	return Value{kind: KindNumber, num: num}
+}
+
+func StringValue(
+	str string,
+) Value {
+	return Value{kind: KindString, str: str}
+}
+
+func BoolValue(
+	b bool,
+) Value {
+	return Value{kind: KindBool, b: b}
+}
+
+// Types the value of a record by its content
+func inferValue(
+	text string,
+) Value {
+	trimmed := strings.TrimSpace(text)
+	if num, err := strconv.ParseFloat(trimmed, 64); err == nil && !math.IsNaN(num) && !math.IsInf(num, 0) {
+		return Value{kind: KindNumber, num: num, str: text}
+	}
+	if b, err := strconv.ParseBool(trimmed); err == nil && (trimmed == "true" || trimmed == "false") {
+		return Value{kind: KindBool, b: b, str: text}
+	}
+	return Value{kind: KindString, str: text}
+}
+
+func (v Value) Kind() Kind {
+	return v.kind
+}
+
+// Returns the value as it is stored in a record
+func (v Value) String() string {
+	switch v.kind {
+	case KindNumber:
+		if v.str != "" {
+			return v.str
+		}
+		return strconv.FormatFloat(v.num, 'f', -1, 64)
+	case KindBool:
+		if v.str != "" {
+			return v.str
+		}
+		return strconv.FormatBool(v.b)
+	default:
+		return v.str
+	}
+}
+
+// Converts the value into the given kind
+func (v Value) Convert(
+	kind Kind,
+) (
+	Value,
+	error,
+) {
+	if v.kind == kind {
+		return v, nil
+	}
+
+	switch kind {
+	case KindString:
+		return StringValue(v.String()), nil
+	case KindNumber:
+		num, err := v.number()
+		if err != nil {
+			return Value{}, err
+		}
+		return NumberValue(num), nil
+	default:
+		b, err := v.bool()
+		if err != nil {
+			return Value{}, err
+		}
+		return BoolValue(b), nil
+	}
+}
+
+func (v Value) number() (
+	float64,
+	error,
+) {
+	switch v.kind {
+	case KindNumber:
+		return v.num, nil
+	case KindString:
+		num, err := strconv.ParseFloat(strings.TrimSpace(v.str), 64)
+		if err == nil {
+			return num, nil
+		}
+	}
+	return 0, fmt.Errorf("%s %q is not a number", v.kind, v.String())
+}
+
+func (v Value) bool() (
+	bool,
+	error,
+) {
+	if v.kind == KindBool {
+		return v.b, nil
+	}
+	if v.kind == KindString {
+		if b, err := strconv.ParseBool(strings.TrimSpace(v.str)); err == nil {
+			return b, nil
+		}
+	}
+	return false, fmt.Errorf("%s %q is not a bool", v.kind, v.String())
+}
+
+// Parses the name of a kind
+func ParseKind(
+	name string,
+) (
+	Kind,
+	error,
+) {
+	switch name {
+	case "string":
+		return KindString, nil
+	case "number":
+		return KindNumber, nil
+	case "bool":
+		return KindBool, nil
+	}
+	return KindString, fmt.Errorf("unsupported type %q", name)
+}
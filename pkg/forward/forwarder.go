@@ -6,75 +6,245 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/config"
 	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/logging"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/spool"
 )
 
+// Implements Sink interface
 type Forwarder struct {
-	config *config.Config
-	client *http.Client
-	evs    *config.EndpointValues
+	name    string
+	config  *config.Config
+	account *config.NewRelicInput
+	client  *http.Client
+	evs     *config.EndpointValues
+
+	// Buffer of the batches which could not be sent, nil if disabled
+	spool *spool.Spool
 }
 
+// Creates new forwarder which routes the events to the
+// destinations of their endpoints
 func NewForwarder(
 	cfg *config.Config,
 	evs *config.EndpointValues,
 ) *Forwarder {
+	return newForwarder(config.SinkTypeNewrelic, cfg, nil, evs)
+}
+
+func newForwarder(
+	name string,
+	cfg *config.Config,
+	account *config.NewRelicInput,
+	evs *config.EndpointValues,
+) *Forwarder {
 
 	// Create HTTP client
-	client := http.Client{Timeout: time.Duration(30 * time.Second)}
+	client := http.Client{
+		Timeout:   time.Duration(30 * time.Second),
+		Transport: cfg.Transport,
+	}
+
+	// Create spool
+	var s *spool.Spool
+	if cfg.Spool != nil && cfg.Spool.Enabled {
+		s = spool.New(cfg.Spool.Directory, cfg.Spool.MaxAge, cfg.Spool.MaxBytes)
+	}
 
 	cfg.Logger.Log(logrus.DebugLevel, "Forwarder is succesfully initialized.")
 
 	return &Forwarder{
-		config: cfg,
-		client: &client,
-		evs:    evs,
+		name:    name,
+		config:  cfg,
+		account: account,
+		client:  &client,
+		evs:     evs,
+		spool:   s,
 	}
 }
 
+func (f *Forwarder) Name() string {
+	return f.name
+}
+
 func (f *Forwarder) Run() error {
 
-	// Create New Relic events
-	nrEvents := f.createNewRelicEvents()
+	// Send all events to the account of the sink
+	if f.account != nil {
+		return f.deliver(f.name, f.account, f.createNewRelicEvents())
+	}
+
+	// Send the events of every destination to its account.
+	// A failing destination does not stop the others.
+	destinations, nrEvents := f.createNewRelicEventsPerDestination()
+
+	var firstErr error
+	for _, destination := range destinations {
+		account, _ := f.config.GetDestination(destination)
+		err := f.deliver(f.name+"-"+destination, account, nrEvents[destination])
+		if err != nil {
+			f.config.Logger.LogWithFields(logrus.ErrorLevel, logging.FORWARD__DESTINATION_HAS_FAILED,
+				map[string]string{
+					"destinationName": destination,
+					"error":           err.Error(),
+				})
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
 
-	// Flush data to New Relic
-	return f.sendToNewRelic(nrEvents)
+	return firstErr
 }
 
-func (f *Forwarder) createNewRelicEvents() []map[string]string {
+// Sends the events to the account. If the spool is enabled, the
+// spooled batches of the queue are replayed first to keep the order
+// and the events are spooled if they could not be sent.
+func (f *Forwarder) deliver(
+	queue string,
+	account *config.NewRelicInput,
+	nrEvents []map[string]interface{},
+) error {
+	if f.spool == nil {
+		return f.sendToNewRelic(account, nrEvents)
+	}
 
-	f.config.Logger.Log(logrus.DebugLevel, "Creating New Relic events...")
+	discarded := f.spool.Discarded()
+	defer f.logDiscarded(discarded)
+
+	err := f.replay(queue, account)
+	if err == nil {
+		err = f.sendToNewRelic(account, nrEvents)
 
-	endpoints := f.evs.GetEndpoints()
+		// Events which are rejected would block the queue forever
+		if isRejected(err) && len(nrEvents) > 0 {
+			f.spool.Discard(&spool.Batch{Events: nrEvents})
+			return err
+		}
+	}
+
+	if err != nil && len(nrEvents) > 0 {
+		if spoolErr := f.spool.Write(queue, nrEvents); spoolErr != nil {
+			f.config.Logger.LogWithFields(logrus.ErrorLevel, spoolErr.Error(),
+				map[string]string{
+					"queue": queue,
+				})
+		} else {
+			f.config.Logger.LogWithFields(logrus.InfoLevel, "New Relic events are spooled.",
+				map[string]string{
+					"queue": queue,
+				})
+		}
+	}
 
-	// Initialize to be sent New Relic events
-	nrEvents := make([]map[string]string, 0, len(endpoints))
+	return err
+}
 
-	for _, endpoint := range endpoints {
+// Sends the spooled batches of the queue in order and stops at
+// the first one which could not be sent. Batches which are rejected
+// are discarded.
+func (f *Forwarder) replay(
+	queue string,
+	account *config.NewRelicInput,
+) error {
+	batches, err := f.spool.Read(queue)
+	if err != nil {
+		f.config.Logger.LogWithFields(logrus.ErrorLevel, err.Error(),
+			map[string]string{
+				"queue": queue,
+			})
+		return nil
+	}
 
-		// All of the events are to be stored under "endpoint.Name"
-		nrEvent := map[string]string{
-			"eventType":    endpoint.Name,
-			"endpointType": endpoint.Type,
-			"endpointUrl":  endpoint.URL,
+	for _, batch := range batches {
+		err := f.sendToNewRelic(account, batch.Events)
+		if err != nil && !isRejected(err) {
+			return err
 		}
 
-		for endpointKey, endpointValue := range f.evs.GetEndpointValues(endpoint) {
-			nrEvent[endpointKey] = endpointValue
+		// Stop if the batch could not be removed to avoid sending it twice
+		if err != nil {
+			err = f.spool.Discard(batch)
+		} else {
+			err = f.spool.Remove(batch)
+		}
+		if err != nil {
+			f.config.Logger.LogWithFields(logrus.ErrorLevel, err.Error(),
+				map[string]string{
+					"queue": queue,
+				})
+			return err
 		}
-		nrEvents = append(nrEvents, nrEvent)
 	}
 
+	if len(batches) > 0 {
+		f.config.Logger.LogWithFields(logrus.InfoLevel, "Spooled New Relic events are replayed.",
+			map[string]string{
+				"queue":   queue,
+				"batches": strconv.Itoa(len(batches)),
+			})
+	}
+
+	return nil
+}
+
+func (f *Forwarder) logDiscarded(
+	before int,
+) {
+	after := f.spool.Discarded()
+	if after > before {
+		f.config.Logger.LogWithFields(logrus.ErrorLevel, logging.SPOOL__BATCHES_ARE_DISCARDED,
+			map[string]string{
+				"discarded":      strconv.Itoa(after - before),
+				"discardedTotal": strconv.Itoa(after),
+			})
+	}
+}
+
+func (f *Forwarder) createNewRelicEvents() []map[string]interface{} {
+
+	f.config.Logger.Log(logrus.DebugLevel, "Creating New Relic events...")
+	nrEvents := createEvents(f.evs)
 	f.config.Logger.Log(logrus.DebugLevel, "New Relic events are created successfully.")
+
 	return nrEvents
 }
 
+// Groups the events by the destinations of their endpoints.
+// The destinations are returned in the order of appearance.
+func (f *Forwarder) createNewRelicEventsPerDestination() (
+	[]string,
+	map[string][]map[string]interface{},
+) {
+
+	f.config.Logger.Log(logrus.DebugLevel, "Creating New Relic events per destination...")
+
+	destinations := make([]string, 0)
+	nrEvents := make(map[string][]map[string]interface{})
+
+	for _, endpoint := range f.evs.GetEndpoints() {
+		events := createEndpointEvents(f.evs, endpoint)
+		for _, destination := range endpoint.GetDestinations() {
+			if _, ok := nrEvents[destination]; !ok {
+				destinations = append(destinations, destination)
+				nrEvents[destination] = make([]map[string]interface{}, 0)
+			}
+			nrEvents[destination] = append(nrEvents[destination], events...)
+		}
+	}
+
+	f.config.Logger.Log(logrus.DebugLevel, "New Relic events are created successfully.")
+
+	return destinations, nrEvents
+}
+
 func (f *Forwarder) sendToNewRelic(
-	nrEvents []map[string]string,
+	account *config.NewRelicInput,
+	nrEvents []map[string]interface{},
 ) error {
 
 	// Create zipped payload
@@ -85,7 +255,7 @@ func (f *Forwarder) sendToNewRelic(
 
 	// Create HTTP request
 	f.config.Logger.Log(logrus.DebugLevel, "Creating HTTP request...")
-	req, err := http.NewRequest(http.MethodPost, f.config.Newrelic.EventsEndpoint, payloadZipped)
+	req, err := http.NewRequest(http.MethodPost, account.EventsEndpoint, payloadZipped)
 	if err != nil {
 		f.config.Logger.LogWithFields(logrus.ErrorLevel, logging.FORWARD__HTTP_REQUEST_COULD_NOT_BE_CREATED,
 			map[string]string{
@@ -95,7 +265,7 @@ func (f *Forwarder) sendToNewRelic(
 	}
 	req.Header.Add("Content-Type", "application/json")
 	req.Header.Add("Content-Encoding", "gzip")
-	req.Header.Add("Api-Key", f.config.Newrelic.LicenseKey)
+	req.Header.Add("Api-Key", account.LicenseKey)
 
 	// Perform HTTP request
 	f.config.Logger.Log(logrus.DebugLevel, "Performing HTTP request...")
@@ -112,6 +282,12 @@ func (f *Forwarder) sendToNewRelic(
 	// Check if call was successful
 	if res.StatusCode == http.StatusOK {
 		f.config.Logger.Log(logrus.DebugLevel, "New Relic events are forwarded successfully.")
+	} else if res.StatusCode >= 400 && res.StatusCode < 500 && res.StatusCode != http.StatusTooManyRequests {
+		f.config.Logger.LogWithFields(logrus.ErrorLevel, logging.FORWARD__NEW_RELIC_REJECTED_EVENTS,
+			map[string]string{
+				"statusCode": strconv.Itoa(res.StatusCode),
+			})
+		return errors.New(logging.FORWARD__NEW_RELIC_REJECTED_EVENTS)
 	} else {
 		f.config.Logger.Log(logrus.ErrorLevel, logging.FORWARD__NEW_RELIC_RETURNED_NOT_OK_STATUS)
 		return errors.New(logging.FORWARD__NEW_RELIC_RETURNED_NOT_OK_STATUS)
@@ -120,8 +296,16 @@ func (f *Forwarder) sendToNewRelic(
 	return nil
 }
 
+// Whether New Relic has rejected the events for good, e.g. because
+// the payload is too large, so that sending them again is useless
+func isRejected(
+	err error,
+) bool {
+	return err != nil && err.Error() == logging.FORWARD__NEW_RELIC_REJECTED_EVENTS
+}
+
 func (f *Forwarder) createPayload(
-	nrEvents []map[string]string,
+	nrEvents []map[string]interface{},
 ) (
 	*bytes.Buffer,
 	error,
@@ -1,13 +1,19 @@
 package forward
 
 import (
+	"compress/gzip"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"sort"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/config"
 	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/logging"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/spool"
 )
 
 func Test_NewRelicEventsAreCreated(t *testing.T) {
@@ -36,6 +42,28 @@ func Test_NewRelicEventsAreCreated(t *testing.T) {
 	}
 }
 
+func Test_NewRelicEventIsCreatedPerRecord(t *testing.T) {
+	endpointInfoMock := createEndpointInfoMock()
+	cfg := createConfig("", endpointInfoMock)
+
+	evs := config.NewEndpointValues()
+	evs.AddEndpointValues(cfg.Endpoints[0], []map[string]string{
+		{"queue": "orders"},
+		{"queue": "payments"},
+	})
+
+	forwarder := NewForwarder(cfg, evs)
+	nrEvents := forwarder.createNewRelicEvents()
+
+	assert.Equal(t, 2, len(nrEvents))
+	assert.Equal(t, "orders", nrEvents[0]["queue"])
+	assert.Equal(t, "payments", nrEvents[1]["queue"])
+	for _, nrEvent := range nrEvents {
+		assert.Equal(t, cfg.Endpoints[0].Name, nrEvent["eventType"])
+		assert.Equal(t, cfg.Endpoints[0].URL, nrEvent["endpointUrl"])
+	}
+}
+
 func Test_HttpRequestCouldNotBeCreated(t *testing.T) {
 	endpointInfoMock := createEndpointInfoMock()
 	cfg := createConfig("::", endpointInfoMock)
@@ -63,7 +91,7 @@ func Test_HttpRequestHasFailed(t *testing.T) {
 func Test_NewRelicReturnsNotOkResponse(t *testing.T) {
 	newrelicEventServerMock := httptest.NewServer(http.HandlerFunc(
 		func(w http.ResponseWriter, r *http.Request) {
-			w.WriteHeader(http.StatusBadRequest)
+			w.WriteHeader(http.StatusInternalServerError)
 		}))
 	defer newrelicEventServerMock.Close()
 
@@ -94,13 +122,198 @@ func Test_EventsAreSent(t *testing.T) {
 	assert.Nil(t, err)
 }
 
+func Test_EventsAreRoutedToDestinations(t *testing.T) {
+	received := map[string][]map[string]string{}
+	newrelicEventServerMock := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			zr, err := gzip.NewReader(r.Body)
+			assert.Nil(t, err)
+
+			events := []map[string]string{}
+			err = json.NewDecoder(zr).Decode(&events)
+			assert.Nil(t, err)
+
+			received[r.Header.Get("Api-Key")] = events
+			w.WriteHeader(http.StatusOK)
+		}))
+	defer newrelicEventServerMock.Close()
+
+	endpointInfoMock := createEndpointInfoMock()
+	cfg := createConfig(newrelicEventServerMock.URL, endpointInfoMock)
+	cfg.Newrelic.LicenseKey = "default-key"
+	cfg.Newrelic.Accounts = map[string]*config.NewRelicInput{
+		"team": {
+			EventsEndpoint: newrelicEventServerMock.URL,
+			LicenseKey:     "team-key",
+		},
+	}
+	cfg.Endpoints[0].Destinations = []string{"team"}
+	cfg.Endpoints[1].Destinations = []string{config.DefaultDestination, "team"}
+	evs := createEndpointValues(cfg, endpointInfoMock)
+
+	forwarder := NewForwarder(cfg, evs)
+	err := forwarder.Run()
+	assert.Nil(t, err)
+
+	assert.Equal(t, 1, len(received["default-key"]))
+	assert.Equal(t, "ep2Url", received["default-key"][0]["endpointUrl"])
+
+	assert.Equal(t, 2, len(received["team-key"]))
+	assert.Equal(t, "ep1Url", received["team-key"][0]["endpointUrl"])
+	assert.Equal(t, "ep2Url", received["team-key"][1]["endpointUrl"])
+}
+
+func Test_EventsAreSentThroughProxy(t *testing.T) {
+	proxiedHost := ""
+	proxyMock := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			proxiedHost = r.URL.Host
+			w.WriteHeader(http.StatusOK)
+		}))
+	defer proxyMock.Close()
+
+	proxyUrl, _ := url.Parse(proxyMock.URL)
+
+	endpointInfoMock := createEndpointInfoMock()
+	cfg := createConfig("http://insights-collector.example/v1/accounts/1/events", endpointInfoMock)
+	cfg.Transport = &http.Transport{Proxy: http.ProxyURL(proxyUrl)}
+	evs := createEndpointValues(cfg, endpointInfoMock)
+
+	forwarder := NewForwarder(cfg, evs)
+	err := forwarder.Run()
+	assert.Nil(t, err)
+	assert.Equal(t, "insights-collector.example", proxiedHost)
+}
+
+func Test_FailedEventsAreSpooledAndReplayed(t *testing.T) {
+	available := false
+	received := [][]map[string]string{}
+	newrelicEventServerMock := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if !available {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+
+			zr, err := gzip.NewReader(r.Body)
+			assert.Nil(t, err)
+
+			events := []map[string]string{}
+			err = json.NewDecoder(zr).Decode(&events)
+			assert.Nil(t, err)
+
+			received = append(received, events)
+			w.WriteHeader(http.StatusOK)
+		}))
+	defer newrelicEventServerMock.Close()
+
+	endpointInfoMock := createEndpointInfoMock()
+	cfg := createConfig(newrelicEventServerMock.URL, endpointInfoMock)
+	cfg.Spool = &config.SpoolInput{
+		Enabled:   true,
+		Directory: t.TempDir(),
+		MaxAge:    time.Hour,
+	}
+
+	// New Relic is not reachable
+	evs := config.NewEndpointValues()
+	evs.AddEndpointValues(cfg.Endpoints[0], []map[string]string{{"run": "1"}})
+	err := NewForwarder(cfg, evs).Run()
+	assert.NotNil(t, err)
+
+	// New Relic is reachable again
+	available = true
+	evs = config.NewEndpointValues()
+	evs.AddEndpointValues(cfg.Endpoints[0], []map[string]string{{"run": "2"}})
+	err = NewForwarder(cfg, evs).Run()
+	assert.Nil(t, err)
+
+	assert.Equal(t, 2, len(received))
+	assert.Equal(t, "1", received[0][0]["run"])
+	assert.Equal(t, "2", received[1][0]["run"])
+
+	// Spooled events are sent only once
+	received = [][]map[string]string{}
+	evs = config.NewEndpointValues()
+	evs.AddEndpointValues(cfg.Endpoints[0], []map[string]string{{"run": "3"}})
+	err = NewForwarder(cfg, evs).Run()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(received))
+	assert.Equal(t, "3", received[0][0]["run"])
+}
+
+func Test_RejectedBatchesAreDiscarded(t *testing.T) {
+	available := false
+	received := []string{}
+	newrelicEventServerMock := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if !available {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+
+			zr, err := gzip.NewReader(r.Body)
+			assert.Nil(t, err)
+
+			events := []map[string]string{}
+			err = json.NewDecoder(zr).Decode(&events)
+			assert.Nil(t, err)
+
+			// The events of the first and the last run are too large
+			run := events[0]["run"]
+			if run == "1" || run == "4" {
+				w.WriteHeader(http.StatusRequestEntityTooLarge)
+				return
+			}
+			received = append(received, run)
+			w.WriteHeader(http.StatusOK)
+		}))
+	defer newrelicEventServerMock.Close()
+
+	endpointInfoMock := createEndpointInfoMock()
+	cfg := createConfig(newrelicEventServerMock.URL, endpointInfoMock)
+	cfg.Spool = &config.SpoolInput{
+		Enabled:   true,
+		Directory: t.TempDir(),
+		MaxAge:    time.Hour,
+	}
+	forward := func(run string) error {
+		evs := config.NewEndpointValues()
+		evs.AddEndpointValues(cfg.Endpoints[0], []map[string]string{{"run": run}})
+		return NewForwarder(cfg, evs).Run()
+	}
+
+	// New Relic is not reachable
+	err := forward("1")
+	assert.NotNil(t, err)
+
+	// The spooled batch is rejected and does not block the new events
+	available = true
+	err = forward("2")
+	assert.Nil(t, err)
+	err = forward("3")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"2", "3"}, received)
+
+	// Rejected events are not spooled
+	err = forward("4")
+	assert.NotNil(t, err)
+	assert.Equal(t, logging.FORWARD__NEW_RELIC_REJECTED_EVENTS, err.Error())
+
+	s := spool.New(cfg.Spool.Directory, cfg.Spool.MaxAge, cfg.Spool.MaxBytes)
+	batches, err := s.Read(config.SinkTypeNewrelic + "-" + config.DefaultDestination)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(batches))
+	assert.Equal(t, 2, s.Discarded())
+}
+
 func createEndpointValues(
 	cfg *config.Config,
 	endpointInfoMock map[string](map[string]string),
 ) *config.EndpointValues {
 	evs := config.NewEndpointValues()
 	for _, endpoint := range cfg.Endpoints {
-		evs.AddEndpointValues(endpoint, endpointInfoMock[endpoint.URL])
+		evs.AddEndpointValues(endpoint, []map[string]string{endpointInfoMock[endpoint.URL]})
 	}
 	return evs
 }
@@ -123,8 +336,16 @@ func createConfig(
 	endpointInfo map[string](map[string]string),
 ) *config.Config {
 	logLevel := "ERROR"
-	eps := []config.Endpoint{}
+
+	// Sort the URLs to keep the order of the endpoints stable
+	urls := make([]string, 0, len(endpointInfo))
 	for url := range endpointInfo {
+		urls = append(urls, url)
+	}
+	sort.Strings(urls)
+
+	eps := []config.Endpoint{}
+	for _, url := range urls {
 		eps = append(eps, config.Endpoint{
 			Type: "kvp",
 			Name: "MyEndpoint" + url,
@@ -0,0 +1,107 @@
+package forward
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+
+	"github.com/sirupsen/logrus"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/config"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/logging"
+)
+
+// Writes one JSON object per event into stdout or a file.
+// Implements Sink interface.
+type JsonLinesSink struct {
+	name   string
+	config *config.Config
+	evs    *config.EndpointValues
+
+	// Opens the writer for a run
+	open func() (io.WriteCloser, error)
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+func newStdoutSink(
+	name string,
+	cfg *config.Config,
+	evs *config.EndpointValues,
+) *JsonLinesSink {
+	return &JsonLinesSink{
+		name:   name,
+		config: cfg,
+		evs:    evs,
+		open: func() (io.WriteCloser, error) {
+			return nopCloser{os.Stdout}, nil
+		},
+	}
+}
+
+func newFileSink(
+	name string,
+	cfg *config.Config,
+	file *config.FileSinkInput,
+	evs *config.EndpointValues,
+) *JsonLinesSink {
+	return &JsonLinesSink{
+		name:   name,
+		config: cfg,
+		evs:    evs,
+		open: func() (io.WriteCloser, error) {
+			return os.OpenFile(file.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		},
+	}
+}
+
+func (s *JsonLinesSink) Name() string {
+	return s.name
+}
+
+func (s *JsonLinesSink) Run() error {
+
+	s.config.Logger.Log(logrus.DebugLevel, "Writing events as JSON lines...")
+
+	w, err := s.open()
+	if err != nil {
+		s.config.Logger.LogWithFields(logrus.ErrorLevel, logging.SINK__EVENTS_COULD_NOT_BE_WRITTEN,
+			map[string]string{
+				"sinkName": s.name,
+				"error":    err.Error(),
+			})
+		return errors.New(logging.SINK__EVENTS_COULD_NOT_BE_WRITTEN)
+	}
+	defer w.Close()
+
+	encoder := json.NewEncoder(w)
+	for _, event := range createEvents(s.evs) {
+		if err := encoder.Encode(event); err != nil {
+			s.config.Logger.LogWithFields(logrus.ErrorLevel, logging.SINK__EVENTS_COULD_NOT_BE_WRITTEN,
+				map[string]string{
+					"sinkName": s.name,
+					"error":    err.Error(),
+				})
+			return errors.New(logging.SINK__EVENTS_COULD_NOT_BE_WRITTEN)
+		}
+	}
+
+	// Make sure that the file is written completely
+	if err := w.Close(); err != nil {
+		s.config.Logger.LogWithFields(logrus.ErrorLevel, logging.SINK__EVENTS_COULD_NOT_BE_WRITTEN,
+			map[string]string{
+				"sinkName": s.name,
+				"error":    err.Error(),
+			})
+		return errors.New(logging.SINK__EVENTS_COULD_NOT_BE_WRITTEN)
+	}
+
+	s.config.Logger.Log(logrus.DebugLevel, "Events are written successfully.")
+	return nil
+}
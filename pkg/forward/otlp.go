@@ -0,0 +1,363 @@
+package forward
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/config"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/logging"
+)
+
+const (
+	otlpScopeName = "newrelic-kubernetes-endpoint-scraper"
+
+	otlpSeverityNumberInfo = 9
+)
+
+// Object which exports the endpoint values to an OTLP/HTTP receiver.
+// Implements Sink interface.
+type OtlpExporter struct {
+	name   string
+	config *config.Config
+	otlp   *config.OtlpInput
+	client *http.Client
+	evs    *config.EndpointValues
+	now    func() time.Time
+}
+
+// Creates new exporter to the OTLP receiver of the config
+func NewOtlpExporter(
+	cfg *config.Config,
+	evs *config.EndpointValues,
+) *OtlpExporter {
+	return newOtlpExporter(config.SinkTypeOtlp, cfg, cfg.Otlp, evs)
+}
+
+func newOtlpExporter(
+	name string,
+	cfg *config.Config,
+	otlp *config.OtlpInput,
+	evs *config.EndpointValues,
+) *OtlpExporter {
+
+	// Create HTTP client
+	client := http.Client{
+		Timeout:   time.Duration(30 * time.Second),
+		Transport: cfg.Transport,
+	}
+
+	cfg.Logger.Log(logrus.DebugLevel, "OTLP exporter is succesfully initialized.")
+
+	return &OtlpExporter{
+		name:   name,
+		config: cfg,
+		otlp:   otlp,
+		client: &client,
+		evs:    evs,
+		now:    time.Now,
+	}
+}
+
+func (e *OtlpExporter) Name() string {
+	return e.name
+}
+
+func (e *OtlpExporter) Run() error {
+
+	// Create & encode the OTLP request
+	var payload []byte
+	var err error
+	var path string
+
+	switch e.otlp.Signal {
+	case config.OtlpSignalLogs:
+		path = "/v1/logs"
+		payload, err = e.encode(e.createLogsRequest())
+	default:
+		path = "/v1/metrics"
+		payload, err = e.encode(e.createMetricsRequest())
+	}
+	if err != nil {
+		return err
+	}
+
+	// Flush data to the receiver
+	return e.send(strings.TrimSuffix(e.otlp.Endpoint, "/")+path, payload)
+}
+
+// Every numeric value becomes a gauge data point while the
+// other values become the attributes of the data points.
+func (e *OtlpExporter) createMetricsRequest() *otlpMetricsRequest {
+
+	e.config.Logger.Log(logrus.DebugLevel, "Creating OTLP metrics...")
+
+	timestamp := uint64(e.now().UnixNano())
+	metrics := make([]otlpMetric, 0)
+	indexes := make(map[string]int)
+
+	for _, endpoint := range e.evs.GetEndpoints() {
+		for _, values := range e.evs.GetEndpointValues(endpoint) {
+
+			numerics, attributes := splitNumericValues(values)
+			attributes = append(createEndpointAttributes(endpoint), attributes...)
+
+			for _, numeric := range numerics {
+				name := endpoint.Name + "." + numeric.key
+				index, ok := indexes[name]
+				if !ok {
+					index = len(metrics)
+					indexes[name] = index
+					metrics = append(metrics, otlpMetric{
+						Name: name,
+					})
+				}
+
+				metrics[index].Gauge.DataPoints = append(metrics[index].Gauge.DataPoints, otlpNumberDataPoint{
+					Attributes:   attributes,
+					TimeUnixNano: timestamp,
+					AsDouble:     numeric.value,
+				})
+			}
+		}
+	}
+
+	return &otlpMetricsRequest{
+		ResourceMetrics: []otlpResourceMetrics{
+			{
+				Resource: createOtlpResource(),
+				ScopeMetrics: []otlpScopeMetrics{
+					{
+						Scope:   otlpScope{Name: otlpScopeName},
+						Metrics: metrics,
+					},
+				},
+			},
+		},
+	}
+}
+
+// Every record becomes a log record which carries all values
+// as attributes.
+func (e *OtlpExporter) createLogsRequest() *otlpLogsRequest {
+
+	e.config.Logger.Log(logrus.DebugLevel, "Creating OTLP log records...")
+
+	timestamp := uint64(e.now().UnixNano())
+	records := make([]otlpLogRecord, 0)
+
+	for _, endpoint := range e.evs.GetEndpoints() {
+		for _, values := range e.evs.GetEndpointValues(endpoint) {
+			attributes := createEndpointAttributes(endpoint)
+			for _, key := range sortedKeys(values) {
+				attributes = append(attributes, otlpStringAttribute(key, values[key]))
+			}
+
+			body := endpoint.Name
+			records = append(records, otlpLogRecord{
+				TimeUnixNano:   timestamp,
+				SeverityNumber: otlpSeverityNumberInfo,
+				SeverityText:   "INFO",
+				Body:           otlpAnyValue{StringValue: &body},
+				Attributes:     attributes,
+			})
+		}
+	}
+
+	return &otlpLogsRequest{
+		ResourceLogs: []otlpResourceLogs{
+			{
+				Resource: createOtlpResource(),
+				ScopeLogs: []otlpScopeLogs{
+					{
+						Scope:      otlpScope{Name: otlpScopeName},
+						LogRecords: records,
+					},
+				},
+			},
+		},
+	}
+}
+
+type otlpRequest interface {
+	marshalProto() []byte
+}
+
+func (e *OtlpExporter) encode(
+	req otlpRequest,
+) (
+	[]byte,
+	error,
+) {
+	e.config.Logger.Log(logrus.DebugLevel, "Encoding OTLP payload...")
+
+	if e.otlp.Protocol == config.OtlpProtocolJson {
+		payload, err := json.Marshal(req)
+		if err != nil {
+			e.config.Logger.LogWithFields(logrus.ErrorLevel, logging.OTLP__PAYLOAD_COULD_NOT_BE_CREATED,
+				map[string]string{
+					"error": err.Error(),
+				})
+			return nil, errors.New(logging.OTLP__PAYLOAD_COULD_NOT_BE_CREATED)
+		}
+		return payload, nil
+	}
+
+	return req.marshalProto(), nil
+}
+
+func (e *OtlpExporter) send(
+	url string,
+	payload []byte,
+) error {
+
+	// Zip the payload
+	var payloadZipped bytes.Buffer
+	zw := gzip.NewWriter(&payloadZipped)
+	if _, err := zw.Write(payload); err != nil {
+		e.config.Logger.LogWithFields(logrus.ErrorLevel, logging.OTLP__PAYLOAD_COULD_NOT_BE_ZIPPED,
+			map[string]string{
+				"error": err.Error(),
+			})
+		return errors.New(logging.OTLP__PAYLOAD_COULD_NOT_BE_ZIPPED)
+	}
+	if err := zw.Close(); err != nil {
+		e.config.Logger.LogWithFields(logrus.ErrorLevel, logging.OTLP__PAYLOAD_COULD_NOT_BE_ZIPPED,
+			map[string]string{
+				"error": err.Error(),
+			})
+		return errors.New(logging.OTLP__PAYLOAD_COULD_NOT_BE_ZIPPED)
+	}
+
+	// Create HTTP request
+	e.config.Logger.Log(logrus.DebugLevel, "Creating OTLP HTTP request...")
+	req, err := http.NewRequest(http.MethodPost, url, &payloadZipped)
+	if err != nil {
+		e.config.Logger.LogWithFields(logrus.ErrorLevel, logging.OTLP__HTTP_REQUEST_COULD_NOT_BE_CREATED,
+			map[string]string{
+				"error": err.Error(),
+			})
+		return errors.New(logging.OTLP__HTTP_REQUEST_COULD_NOT_BE_CREATED)
+	}
+
+	if e.otlp.Protocol == config.OtlpProtocolJson {
+		req.Header.Add("Content-Type", "application/json")
+	} else {
+		req.Header.Add("Content-Type", "application/x-protobuf")
+	}
+	req.Header.Add("Content-Encoding", "gzip")
+	for key, val := range e.otlp.Headers {
+		req.Header.Add(key, val)
+	}
+
+	// Perform HTTP request
+	e.config.Logger.Log(logrus.DebugLevel, "Performing OTLP HTTP request...")
+	res, err := e.client.Do(req)
+	if err != nil {
+		e.config.Logger.LogWithFields(logrus.ErrorLevel, logging.OTLP__HTTP_REQUEST_HAS_FAILED,
+			map[string]string{
+				"error": err.Error(),
+			})
+		return errors.New(logging.OTLP__HTTP_REQUEST_HAS_FAILED)
+	}
+	defer res.Body.Close()
+
+	// Check if call was successful
+	if res.StatusCode != http.StatusOK {
+		e.config.Logger.LogWithFields(logrus.ErrorLevel, logging.OTLP__RECEIVER_RETURNED_NOT_OK_STATUS,
+			map[string]string{
+				"statusCode": strconv.Itoa(res.StatusCode),
+			})
+		return errors.New(logging.OTLP__RECEIVER_RETURNED_NOT_OK_STATUS)
+	}
+
+	e.config.Logger.Log(logrus.DebugLevel, "OTLP data is exported successfully.")
+	return nil
+}
+
+// Resource attributes which describe the scraper pod
+func createOtlpResource() otlpResource {
+	attributes := []otlpKeyValue{
+		otlpStringAttribute("service.name", otlpScopeName),
+	}
+
+	for _, attr := range []struct {
+		key string
+		env string
+	}{
+		{"k8s.node.name", "NODE_NAME"},
+		{"k8s.namespace.name", "NAMESPACE_NAME"},
+		{"k8s.pod.name", "POD_NAME"},
+	} {
+		if val := os.Getenv(attr.env); val != "" {
+			attributes = append(attributes, otlpStringAttribute(attr.key, val))
+		}
+	}
+
+	return otlpResource{Attributes: attributes}
+}
+
+func createEndpointAttributes(
+	endpoint config.Endpoint,
+) []otlpKeyValue {
+	return []otlpKeyValue{
+		otlpStringAttribute("endpointName", endpoint.Name),
+		otlpStringAttribute("endpointType", endpoint.Type),
+		otlpStringAttribute("endpointUrl", endpoint.URL),
+	}
+}
+
+func otlpStringAttribute(
+	key string,
+	val string,
+) otlpKeyValue {
+	return otlpKeyValue{
+		Key:   key,
+		Value: otlpAnyValue{StringValue: &val},
+	}
+}
+
+type otlpNumericValue struct {
+	key   string
+	value float64
+}
+
+// Splits the values into numeric ones and string attributes
+func splitNumericValues(
+	values map[string]string,
+) (
+	[]otlpNumericValue,
+	[]otlpKeyValue,
+) {
+	numerics := make([]otlpNumericValue, 0)
+	attributes := make([]otlpKeyValue, 0)
+
+	for _, key := range sortedKeys(values) {
+		if num, err := strconv.ParseFloat(values[key], 64); err == nil {
+			numerics = append(numerics, otlpNumericValue{key: key, value: num})
+			continue
+		}
+		attributes = append(attributes, otlpStringAttribute(key, values[key]))
+	}
+
+	return numerics, attributes
+}
+
+func sortedKeys(
+	values map[string]string,
+) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
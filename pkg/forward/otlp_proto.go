@@ -0,0 +1,274 @@
+package forward
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// Subset of the OTLP data model which is needed to export the
+// endpoint values. The JSON tags follow the OTLP/JSON encoding
+// and the marshalProto methods the OTLP protobuf definitions
+// (opentelemetry/proto/collector/{metrics,logs}/v1).
+
+type otlpAnyValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScope struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+type otlpNumberDataPoint struct {
+	Attributes   []otlpKeyValue `json:"attributes"`
+	TimeUnixNano uint64         `json:"timeUnixNano,string"`
+	AsDouble     float64        `json:"asDouble"`
+}
+
+type otlpGauge struct {
+	DataPoints []otlpNumberDataPoint `json:"dataPoints"`
+}
+
+type otlpMetric struct {
+	Name  string    `json:"name"`
+	Gauge otlpGauge `json:"gauge"`
+}
+
+type otlpScopeMetrics struct {
+	Scope   otlpScope    `json:"scope"`
+	Metrics []otlpMetric `json:"metrics"`
+}
+
+type otlpResourceMetrics struct {
+	Resource     otlpResource       `json:"resource"`
+	ScopeMetrics []otlpScopeMetrics `json:"scopeMetrics"`
+}
+
+type otlpMetricsRequest struct {
+	ResourceMetrics []otlpResourceMetrics `json:"resourceMetrics"`
+}
+
+type otlpLogRecord struct {
+	TimeUnixNano   uint64         `json:"timeUnixNano,string"`
+	SeverityNumber int            `json:"severityNumber"`
+	SeverityText   string         `json:"severityText"`
+	Body           otlpAnyValue   `json:"body"`
+	Attributes     []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeLogs struct {
+	Scope      otlpScope       `json:"scope"`
+	LogRecords []otlpLogRecord `json:"logRecords"`
+}
+
+type otlpResourceLogs struct {
+	Resource  otlpResource    `json:"resource"`
+	ScopeLogs []otlpScopeLogs `json:"scopeLogs"`
+}
+
+type otlpLogsRequest struct {
+	ResourceLogs []otlpResourceLogs `json:"resourceLogs"`
+}
+
+// Minimal protobuf wire format encoder
+type protoBuffer []byte
+
+const (
+	protoWireVarint  = 0
+	protoWireFixed64 = 1
+	protoWireBytes   = 2
+)
+
+func (b *protoBuffer) tag(
+	field int,
+	wire int,
+) {
+	b.varint(uint64(field<<3 | wire))
+}
+
+func (b *protoBuffer) varint(
+	v uint64,
+) {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], v)
+	*b = append(*b, buf[:n]...)
+}
+
+func (b *protoBuffer) string(
+	field int,
+	v string,
+) {
+	if v == "" {
+		return
+	}
+	b.tag(field, protoWireBytes)
+	b.varint(uint64(len(v)))
+	*b = append(*b, v...)
+}
+
+func (b *protoBuffer) message(
+	field int,
+	v []byte,
+) {
+	b.tag(field, protoWireBytes)
+	b.varint(uint64(len(v)))
+	*b = append(*b, v...)
+}
+
+func (b *protoBuffer) fixed64(
+	field int,
+	v uint64,
+) {
+	if v == 0 {
+		return
+	}
+	b.tag(field, protoWireFixed64)
+	b.uint64(v)
+}
+
+func (b *protoBuffer) double(
+	field int,
+	v float64,
+) {
+	b.tag(field, protoWireFixed64)
+	b.uint64(math.Float64bits(v))
+}
+
+func (b *protoBuffer) uint64(
+	v uint64,
+) {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], v)
+	*b = append(*b, buf[:]...)
+}
+
+func (b *protoBuffer) enum(
+	field int,
+	v int,
+) {
+	if v == 0 {
+		return
+	}
+	b.tag(field, protoWireVarint)
+	b.varint(uint64(v))
+}
+
+func (v *otlpAnyValue) marshalProto() []byte {
+	var b protoBuffer
+	if v.StringValue != nil {
+		// Empty strings have to be encoded explicitly to keep the oneof set
+		b.tag(1, protoWireBytes)
+		b.varint(uint64(len(*v.StringValue)))
+		b = append(b, *v.StringValue...)
+	}
+	if v.DoubleValue != nil {
+		b.double(4, *v.DoubleValue)
+	}
+	return b
+}
+
+func (kv *otlpKeyValue) marshalProto() []byte {
+	var b protoBuffer
+	b.string(1, kv.Key)
+	b.message(2, kv.Value.marshalProto())
+	return b
+}
+
+func marshalProtoAttributes(
+	b *protoBuffer,
+	field int,
+	attributes []otlpKeyValue,
+) {
+	for i := range attributes {
+		b.message(field, attributes[i].marshalProto())
+	}
+}
+
+func (r *otlpResource) marshalProto() []byte {
+	var b protoBuffer
+	marshalProtoAttributes(&b, 1, r.Attributes)
+	return b
+}
+
+func (s *otlpScope) marshalProto() []byte {
+	var b protoBuffer
+	b.string(1, s.Name)
+	b.string(2, s.Version)
+	return b
+}
+
+func (dp *otlpNumberDataPoint) marshalProto() []byte {
+	var b protoBuffer
+	b.fixed64(3, dp.TimeUnixNano)
+	b.double(4, dp.AsDouble)
+	marshalProtoAttributes(&b, 7, dp.Attributes)
+	return b
+}
+
+func (m *otlpMetric) marshalProto() []byte {
+	var gauge protoBuffer
+	for i := range m.Gauge.DataPoints {
+		gauge.message(1, m.Gauge.DataPoints[i].marshalProto())
+	}
+
+	var b protoBuffer
+	b.string(1, m.Name)
+	b.message(5, gauge)
+	return b
+}
+
+func (r *otlpMetricsRequest) marshalProto() []byte {
+	var b protoBuffer
+	for _, rm := range r.ResourceMetrics {
+		var rmb protoBuffer
+		rmb.message(1, rm.Resource.marshalProto())
+		for _, sm := range rm.ScopeMetrics {
+			var smb protoBuffer
+			smb.message(1, sm.Scope.marshalProto())
+			for i := range sm.Metrics {
+				smb.message(2, sm.Metrics[i].marshalProto())
+			}
+			rmb.message(2, smb)
+		}
+		b.message(1, rmb)
+	}
+	return b
+}
+
+func (lr *otlpLogRecord) marshalProto() []byte {
+	var b protoBuffer
+	b.fixed64(1, lr.TimeUnixNano)
+	b.enum(2, lr.SeverityNumber)
+	b.string(3, lr.SeverityText)
+	b.message(5, lr.Body.marshalProto())
+	marshalProtoAttributes(&b, 6, lr.Attributes)
+	return b
+}
+
+func (r *otlpLogsRequest) marshalProto() []byte {
+	var b protoBuffer
+	for _, rl := range r.ResourceLogs {
+		var rlb protoBuffer
+		rlb.message(1, rl.Resource.marshalProto())
+		for _, sl := range rl.ScopeLogs {
+			var slb protoBuffer
+			slb.message(1, sl.Scope.marshalProto())
+			for i := range sl.LogRecords {
+				slb.message(2, sl.LogRecords[i].marshalProto())
+			}
+			rlb.message(2, slb)
+		}
+		b.message(1, rlb)
+	}
+	return b
+}
@@ -0,0 +1,160 @@
+package forward
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/config"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/logging"
+)
+
+func Test_OtlpMetricsAreCreated(t *testing.T) {
+	cfg := createOtlpConfig("", config.OtlpProtocolJson, config.OtlpSignalMetrics)
+	evs := createOtlpEndpointValues(cfg)
+
+	exporter := NewOtlpExporter(cfg, evs)
+	req := exporter.createMetricsRequest()
+
+	metrics := req.ResourceMetrics[0].ScopeMetrics[0].Metrics
+	assert.Equal(t, 1, len(metrics))
+	assert.Equal(t, "MyEndpoint.depth", metrics[0].Name)
+
+	// Every record becomes a data point with its string values as attributes
+	dataPoints := metrics[0].Gauge.DataPoints
+	assert.Equal(t, 2, len(dataPoints))
+	assert.Equal(t, 12.0, dataPoints[0].AsDouble)
+	assert.Equal(t, 0.0, dataPoints[1].AsDouble)
+	assert.Contains(t, dataPoints[0].Attributes, otlpStringAttribute("queue", "orders"))
+	assert.Contains(t, dataPoints[1].Attributes, otlpStringAttribute("queue", "payments"))
+	assert.Contains(t, dataPoints[0].Attributes, otlpStringAttribute("endpointName", "MyEndpoint"))
+}
+
+func Test_OtlpLogsAreCreated(t *testing.T) {
+	cfg := createOtlpConfig("", config.OtlpProtocolJson, config.OtlpSignalLogs)
+	evs := createOtlpEndpointValues(cfg)
+
+	exporter := NewOtlpExporter(cfg, evs)
+	req := exporter.createLogsRequest()
+
+	records := req.ResourceLogs[0].ScopeLogs[0].LogRecords
+	assert.Equal(t, 2, len(records))
+	assert.Equal(t, "MyEndpoint", *records[0].Body.StringValue)
+	assert.Contains(t, records[0].Attributes, otlpStringAttribute("depth", "12"))
+	assert.Contains(t, records[1].Attributes, otlpStringAttribute("queue", "payments"))
+}
+
+func Test_OtlpJsonIsExported(t *testing.T) {
+	var body map[string]interface{}
+	receiverMock := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/v1/metrics", r.URL.Path)
+			assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+			assert.Equal(t, "secret", r.Header.Get("X-Custom"))
+
+			zr, err := gzip.NewReader(r.Body)
+			assert.Nil(t, err)
+			err = json.NewDecoder(zr).Decode(&body)
+			assert.Nil(t, err)
+
+			w.WriteHeader(http.StatusOK)
+		}))
+	defer receiverMock.Close()
+
+	cfg := createOtlpConfig(receiverMock.URL, config.OtlpProtocolJson, config.OtlpSignalMetrics)
+	evs := createOtlpEndpointValues(cfg)
+
+	exporter := NewOtlpExporter(cfg, evs)
+	err := exporter.Run()
+	assert.Nil(t, err)
+	assert.Contains(t, body, "resourceMetrics")
+}
+
+func Test_OtlpProtobufIsExported(t *testing.T) {
+	var payload []byte
+	receiverMock := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/v1/logs", r.URL.Path)
+			assert.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"))
+
+			zr, err := gzip.NewReader(r.Body)
+			assert.Nil(t, err)
+			payload, err = ioutil.ReadAll(zr)
+			assert.Nil(t, err)
+
+			w.WriteHeader(http.StatusOK)
+		}))
+	defer receiverMock.Close()
+
+	cfg := createOtlpConfig(receiverMock.URL, config.OtlpProtocolProtobuf, config.OtlpSignalLogs)
+	evs := createOtlpEndpointValues(cfg)
+
+	exporter := NewOtlpExporter(cfg, evs)
+	err := exporter.Run()
+	assert.Nil(t, err)
+
+	// Field 1 (resource_logs) with wire type 2 (length delimited)
+	assert.Equal(t, byte(1<<3|2), payload[0])
+	assert.Contains(t, string(payload), "MyEndpoint")
+}
+
+func Test_OtlpReceiverReturnsNotOkResponse(t *testing.T) {
+	receiverMock := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+		}))
+	defer receiverMock.Close()
+
+	cfg := createOtlpConfig(receiverMock.URL, config.OtlpProtocolProtobuf, config.OtlpSignalMetrics)
+	evs := createOtlpEndpointValues(cfg)
+
+	exporter := NewOtlpExporter(cfg, evs)
+	err := exporter.Run()
+	assert.NotNil(t, err)
+	assert.Equal(t, logging.OTLP__RECEIVER_RETURNED_NOT_OK_STATUS, err.Error())
+}
+
+func createOtlpEndpointValues(
+	cfg *config.Config,
+) *config.EndpointValues {
+	evs := config.NewEndpointValues()
+	evs.AddEndpointValues(cfg.Endpoints[0], []map[string]string{
+		{"queue": "orders", "depth": "12"},
+		{"queue": "payments", "depth": "0"},
+	})
+	return evs
+}
+
+func createOtlpConfig(
+	otlpEndpoint string,
+	protocol string,
+	signal string,
+) *config.Config {
+	logLevel := "ERROR"
+	return &config.Config{
+		Newrelic: &config.NewRelicInput{
+			LogLevel: logLevel,
+		},
+		Otlp: &config.OtlpInput{
+			Enabled:  true,
+			Endpoint: otlpEndpoint,
+			Protocol: protocol,
+			Signal:   signal,
+			Headers: map[string]string{
+				"X-Custom": "secret",
+			},
+		},
+		Logger: logging.NewLogger(logLevel),
+		Endpoints: []config.Endpoint{
+			{
+				Type: "kvp",
+				Name: "MyEndpoint",
+				URL:  "ep1Url",
+			},
+		},
+	}
+}
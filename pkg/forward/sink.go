@@ -0,0 +1,133 @@
+package forward
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/config"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/logging"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/parse"
+)
+
+// Destination which the endpoint values are sent to
+type Sink interface {
+	Name() string
+	Run() error
+}
+
+// Creates the sinks which are defined in the config
+func NewSinks(
+	cfg *config.Config,
+	evs *config.EndpointValues,
+) []Sink {
+	sinks := make([]Sink, 0, len(cfg.Sinks))
+	for _, sink := range cfg.Sinks {
+		switch sink.Type {
+		case config.SinkTypeNewrelic:
+			sinks = append(sinks, newForwarder(sink.Name, cfg, sink.Account, evs))
+		case config.SinkTypeOtlp:
+			sinks = append(sinks, newOtlpExporter(sink.Name, cfg, sink.Otlp, evs))
+		case config.SinkTypeStdout:
+			sinks = append(sinks, newStdoutSink(sink.Name, cfg, evs))
+		case config.SinkTypeFile:
+			sinks = append(sinks, newFileSink(sink.Name, cfg, sink.File, evs))
+		case config.SinkTypeWebhook:
+			sinks = append(sinks, newWebhookSink(sink.Name, cfg, sink.Webhook, evs))
+		}
+	}
+	return sinks
+}
+
+// Runs all sinks. A failing sink does not stop the others.
+func RunSinks(
+	cfg *config.Config,
+	sinks []Sink,
+) error {
+	failed := make([]string, 0)
+	for _, sink := range sinks {
+		err := sink.Run()
+		if err != nil {
+			cfg.Logger.LogWithFields(logrus.ErrorLevel, logging.SINK__HAS_FAILED,
+				map[string]string{
+					"sinkName": sink.Name(),
+					"error":    err.Error(),
+				})
+			failed = append(failed, sink.Name())
+		}
+	}
+
+	if len(failed) > 0 {
+		return errors.New(logging.SINK__HAS_FAILED + ": " + strings.Join(failed, ","))
+	}
+	return nil
+}
+
+// Creates an event per record of every endpoint
+func createEvents(
+	evs *config.EndpointValues,
+) []map[string]interface{} {
+
+	endpoints := evs.GetEndpoints()
+
+	// Initialize to be sent events
+	events := make([]map[string]interface{}, 0, len(endpoints))
+
+	for _, endpoint := range endpoints {
+		events = append(events, createEndpointEvents(evs, endpoint)...)
+	}
+
+	return events
+}
+
+// Creates an event per record of the given endpoint
+func createEndpointEvents(
+	evs *config.EndpointValues,
+	endpoint config.Endpoint,
+) []map[string]interface{} {
+
+	records := evs.GetEndpointValues(endpoint)
+	types := evs.GetEndpointTypes(endpoint)
+	events := make([]map[string]interface{}, 0, len(records))
+
+	// Every record of the endpoint becomes a separate event
+	for _, values := range records {
+
+		// All of the events are to be stored under "endpoint.Name"
+		event := map[string]interface{}{
+			"eventType":    endpoint.Name,
+			"endpointType": endpoint.Type,
+			"endpointUrl":  endpoint.URL,
+		}
+
+		for endpointKey, endpointValue := range values {
+			event[endpointKey] = typedValue(endpointValue, types[endpointKey])
+		}
+		events = append(events, event)
+	}
+
+	return events
+}
+
+// Returns the value as a number or a bool if the attribute has such
+// a type so that it can be used in calculations
+func typedValue(
+	value string,
+	typ string,
+) interface{} {
+	switch typ {
+	case parse.ValueTypeNumber:
+		// JSON has neither NaN nor infinities
+		num, err := strconv.ParseFloat(value, 64)
+		if err == nil && !math.IsNaN(num) && !math.IsInf(num, 0) {
+			return num
+		}
+	case parse.ValueTypeBool:
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return value
+}
@@ -0,0 +1,173 @@
+package forward
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/config"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/logging"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/rate"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/scrape"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/state"
+)
+
+func Test_FileSinkWritesJsonLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+
+	endpointInfoMock := createEndpointInfoMock()
+	cfg := createConfig("", endpointInfoMock)
+	cfg.Sinks = []config.SinkInput{
+		{Type: config.SinkTypeFile, Name: "file", File: &config.FileSinkInput{Path: path}},
+	}
+	evs := createEndpointValues(cfg, endpointInfoMock)
+
+	// Run twice to check that the events are appended
+	for i := 0; i < 2; i++ {
+		err := RunSinks(cfg, NewSinks(cfg, evs))
+		assert.Nil(t, err)
+	}
+
+	content, err := ioutil.ReadFile(path)
+	assert.Nil(t, err)
+
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	assert.Equal(t, 4, len(lines))
+
+	event := map[string]string{}
+	err = json.Unmarshal([]byte(lines[0]), &event)
+	assert.Nil(t, err)
+	assert.Equal(t, "MyEndpointep1Url", event["eventType"])
+	assert.Equal(t, "v1", event["k1"])
+}
+
+func Test_TypedValuesAreNotSentAsStrings(t *testing.T) {
+	endpointInfoMock := map[string](map[string]string){
+		"ep1Url": {"id": "job-1", "duration": "12.5", "succeeded": "true", "failed": "no"},
+	}
+	cfg := createConfig("", endpointInfoMock)
+	evs := createEndpointValues(cfg, endpointInfoMock)
+	evs.AddEndpointTypes(cfg.Endpoints[0], map[string]string{
+		"duration":  "number",
+		"succeeded": "bool",
+		"failed":    "bool",
+	})
+
+	events := createEvents(evs)
+	assert.Equal(t, "job-1", events[0]["id"])
+	assert.Equal(t, 12.5, events[0]["duration"])
+	assert.Equal(t, true, events[0]["succeeded"])
+
+	// Values which do not match their type are kept as strings
+	assert.Equal(t, "no", events[0]["failed"])
+}
+
+func Test_ComputedRatesAreSentAsNumbers(t *testing.T) {
+	endpointInfoMock := map[string](map[string]string){
+		"ep1Url": {"requests_total": "100"},
+	}
+	cfg := createConfig("", endpointInfoMock)
+	cfg.State = &config.StateInput{Backend: config.StateBackendMemory}
+	cfg.Endpoints[0].Counters = []config.CounterInput{
+		{Key: "requests_total", Compute: []string{config.CounterComputeRate, config.CounterComputeDelta}},
+	}
+	calculator := rate.NewCalculator(cfg, state.NewMemoryStore())
+	calculator.Run(createEndpointValues(cfg, endpointInfoMock))
+
+	endpointInfoMock["ep1Url"]["requests_total"] = "160"
+	evs := calculator.Run(createEndpointValues(cfg, endpointInfoMock))
+
+	payload, err := json.Marshal(createEvents(evs))
+	assert.Nil(t, err)
+
+	events := []map[string]interface{}{}
+	err = json.Unmarshal(payload, &events)
+	assert.Nil(t, err)
+	assert.IsType(t, float64(0), events[0]["requests_total.rate"])
+	assert.Equal(t, float64(60), events[0]["requests_total.delta"])
+}
+
+func Test_PrometheusValuesAreSentAsNumbers(t *testing.T) {
+	endpointServerMock := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/openmetrics-text; version=1.0.0")
+			w.Write([]byte("# TYPE up gauge\nup 1\n# TYPE ratio gauge\nratio NaN\n# EOF\n"))
+		}))
+	defer endpointServerMock.Close()
+
+	cfg := createConfig("", map[string](map[string]string){endpointServerMock.URL: {}})
+	cfg.Endpoints[0].Type = "auto"
+	evs := scraper.NewScraper(cfg).Run()
+
+	payload, err := json.Marshal(createEvents(evs))
+	assert.Nil(t, err)
+
+	events := []map[string]interface{}{}
+	err = json.Unmarshal(payload, &events)
+	assert.Nil(t, err)
+	assert.Equal(t, float64(1), events[0]["metricValue"])
+
+	// Values which JSON cannot represent are kept as strings
+	assert.Equal(t, "NaN", events[1]["metricValue"])
+}
+
+func Test_WebhookSinkPostsEvents(t *testing.T) {
+	events := []map[string]string{}
+	webhookMock := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "token", r.Header.Get("Authorization"))
+			err := json.NewDecoder(r.Body).Decode(&events)
+			assert.Nil(t, err)
+			w.WriteHeader(http.StatusNoContent)
+		}))
+	defer webhookMock.Close()
+
+	endpointInfoMock := createEndpointInfoMock()
+	cfg := createConfig("", endpointInfoMock)
+	cfg.Sinks = []config.SinkInput{
+		{
+			Type: config.SinkTypeWebhook,
+			Name: "webhook",
+			Webhook: &config.WebhookSinkInput{
+				URL:     webhookMock.URL,
+				Headers: map[string]string{"Authorization": "token"},
+			},
+		},
+	}
+	evs := createEndpointValues(cfg, endpointInfoMock)
+
+	err := RunSinks(cfg, NewSinks(cfg, evs))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(events))
+}
+
+func Test_FailingSinkDoesNotStopOthers(t *testing.T) {
+	newrelicEventServerMock := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+		}))
+	defer newrelicEventServerMock.Close()
+
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+
+	endpointInfoMock := createEndpointInfoMock()
+	cfg := createConfig(newrelicEventServerMock.URL, endpointInfoMock)
+	cfg.Sinks = []config.SinkInput{
+		{Type: config.SinkTypeNewrelic, Name: "newrelic", Account: cfg.Newrelic},
+		{Type: config.SinkTypeFile, Name: "file", File: &config.FileSinkInput{Path: path}},
+	}
+	evs := createEndpointValues(cfg, endpointInfoMock)
+
+	err := RunSinks(cfg, NewSinks(cfg, evs))
+	assert.NotNil(t, err)
+	assert.Equal(t, logging.SINK__HAS_FAILED+": newrelic", err.Error())
+
+	content, err := ioutil.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, strings.Count(string(content), "\n"))
+}
@@ -0,0 +1,104 @@
+package forward
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/config"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/logging"
+)
+
+// Posts all events as a JSON array to an HTTP endpoint.
+// Implements Sink interface.
+type WebhookSink struct {
+	name    string
+	config  *config.Config
+	webhook *config.WebhookSinkInput
+	client  *http.Client
+	evs     *config.EndpointValues
+}
+
+func newWebhookSink(
+	name string,
+	cfg *config.Config,
+	webhook *config.WebhookSinkInput,
+	evs *config.EndpointValues,
+) *WebhookSink {
+
+	// Create HTTP client
+	client := http.Client{
+		Timeout:   time.Duration(30 * time.Second),
+		Transport: cfg.Transport,
+	}
+
+	return &WebhookSink{
+		name:    name,
+		config:  cfg,
+		webhook: webhook,
+		client:  &client,
+		evs:     evs,
+	}
+}
+
+func (s *WebhookSink) Name() string {
+	return s.name
+}
+
+func (s *WebhookSink) Run() error {
+
+	// Create payload
+	payload, err := json.Marshal(createEvents(s.evs))
+	if err != nil {
+		s.config.Logger.LogWithFields(logrus.ErrorLevel, logging.SINK__EVENTS_COULD_NOT_BE_CREATED,
+			map[string]string{
+				"sinkName": s.name,
+				"error":    err.Error(),
+			})
+		return errors.New(logging.SINK__EVENTS_COULD_NOT_BE_CREATED)
+	}
+
+	// Create HTTP request
+	req, err := http.NewRequest(http.MethodPost, s.webhook.URL, bytes.NewReader(payload))
+	if err != nil {
+		s.config.Logger.LogWithFields(logrus.ErrorLevel, logging.SINK__HTTP_REQUEST_COULD_NOT_BE_CREATED,
+			map[string]string{
+				"sinkName": s.name,
+				"error":    err.Error(),
+			})
+		return errors.New(logging.SINK__HTTP_REQUEST_COULD_NOT_BE_CREATED)
+	}
+	req.Header.Add("Content-Type", "application/json")
+	for key, val := range s.webhook.Headers {
+		req.Header.Add(key, val)
+	}
+
+	// Perform HTTP request
+	res, err := s.client.Do(req)
+	if err != nil {
+		s.config.Logger.LogWithFields(logrus.ErrorLevel, logging.SINK__HTTP_REQUEST_HAS_FAILED,
+			map[string]string{
+				"sinkName": s.name,
+				"error":    err.Error(),
+			})
+		return errors.New(logging.SINK__HTTP_REQUEST_HAS_FAILED)
+	}
+	defer res.Body.Close()
+
+	// Check if call was successful
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		s.config.Logger.LogWithFields(logrus.ErrorLevel, logging.SINK__WEBHOOK_RETURNED_NOT_OK_STATUS,
+			map[string]string{
+				"sinkName":   s.name,
+				"statusCode": strconv.Itoa(res.StatusCode),
+			})
+		return errors.New(logging.SINK__WEBHOOK_RETURNED_NOT_OK_STATUS)
+	}
+
+	s.config.Logger.Log(logrus.DebugLevel, "Events are posted to the webhook successfully.")
+	return nil
+}
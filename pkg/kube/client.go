@@ -0,0 +1,214 @@
+package kube
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/logging"
+)
+
+const serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// Client of the Kubernetes API which authenticates with the
+// service account of the pod
+type Client struct {
+	host      string
+	tokenFile string
+	namespace string
+	client    *http.Client
+
+	// Discovered resources per API version
+	resources map[string]*APIResourceList
+}
+
+// Error of the Kubernetes API with its status code
+type StatusError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s: %d %s", logging.KUBE__API_RETURNED_NOT_OK_STATUS, e.StatusCode, e.Message)
+}
+
+// Returns whether the error is a "not found" of the API
+func IsNotFound(
+	err error,
+) bool {
+	var statusErr *StatusError
+	return errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound
+}
+
+// Creates the client which all features of the scraper use. The tests
+// replace it with a client of a mock API server.
+var NewDefaultClient = func() (
+	*Client,
+	error,
+) {
+	return NewInClusterClient()
+}
+
+// Creates a client from the environment of a pod
+func NewInClusterClient() (
+	*Client,
+	error,
+) {
+	host := os.Getenv("KUBERNETES_SERVICE_HOST")
+	port := os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, errors.New(logging.KUBE__CLIENT_IS_NOT_IN_CLUSTER)
+	}
+
+	ca, err := ioutil.ReadFile(serviceAccountDir + "/ca.crt")
+	if err != nil {
+		return nil, errors.New(logging.KUBE__CLIENT_IS_NOT_IN_CLUSTER)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, errors.New(logging.KUBE__CLIENT_IS_NOT_IN_CLUSTER)
+	}
+
+	namespace, err := ioutil.ReadFile(serviceAccountDir + "/namespace")
+	if err != nil {
+		return nil, errors.New(logging.KUBE__CLIENT_IS_NOT_IN_CLUSTER)
+	}
+
+	// The API server is always reached directly
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+
+	return NewClient(
+		"https://"+net.JoinHostPort(host, port),
+		serviceAccountDir+"/token",
+		strings.TrimSpace(string(namespace)),
+		&http.Client{
+			Timeout:   time.Duration(30 * time.Second),
+			Transport: transport,
+		},
+	), nil
+}
+
+func NewClient(
+	host string,
+	tokenFile string,
+	namespace string,
+	client *http.Client,
+) *Client {
+	return &Client{
+		host:      strings.TrimSuffix(host, "/"),
+		tokenFile: tokenFile,
+		namespace: namespace,
+		client:    client,
+	}
+}
+
+// Returns the namespace of the pod
+func (c *Client) Namespace() string {
+	return c.namespace
+}
+
+// Performs a request against the API. The body and the result are
+// encoded as JSON if they are not nil.
+func (c *Client) Do(
+	method string,
+	path string,
+	body interface{},
+	result interface{},
+) error {
+	res, err := c.open(method, path, body)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if result == nil {
+		return nil
+	}
+	if err := json.NewDecoder(res.Body).Decode(result); err != nil {
+		return errors.New(logging.KUBE__RESPONSE_COULD_NOT_BE_PARSED)
+	}
+	return nil
+}
+
+func (c *Client) open(
+	method string,
+	path string,
+	body interface{},
+) (
+	*http.Response,
+	error,
+) {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return nil, errors.New(logging.KUBE__REQUEST_COULD_NOT_BE_CREATED)
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequest(method, c.host+path, reader)
+	if err != nil {
+		return nil, errors.New(logging.KUBE__REQUEST_COULD_NOT_BE_CREATED)
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	if err := c.authorize(req.Header); err != nil {
+		return nil, err
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, errors.New(logging.KUBE__REQUEST_HAS_FAILED)
+	}
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		defer res.Body.Close()
+
+		// Use the message of the API status if available
+		status := struct {
+			Message string `json:"message"`
+		}{}
+		content, _ := ioutil.ReadAll(io.LimitReader(res.Body, 64*1024))
+		if json.Unmarshal(content, &status) != nil || status.Message == "" {
+			status.Message = strings.TrimSpace(string(content))
+		}
+		return nil, &StatusError{
+			StatusCode: res.StatusCode,
+			Message:    status.Message,
+		}
+	}
+
+	return res, nil
+}
+
+// Sets the token of the service account. The token is read on every
+// request since it is rotated.
+func (c *Client) authorize(
+	header http.Header,
+) error {
+	if c.tokenFile == "" {
+		return nil
+	}
+	token, err := ioutil.ReadFile(c.tokenFile)
+	if err != nil {
+		return errors.New(logging.KUBE__REQUEST_COULD_NOT_BE_CREATED)
+	}
+	header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	return nil
+}
@@ -0,0 +1,49 @@
+package kube
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_TokenIsSentOnEveryRequest(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+
+	tokens := []string{}
+	apiMock := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			tokens = append(tokens, r.Header.Get("Authorization"))
+			w.Write([]byte(`{}`))
+		}))
+	defer apiMock.Close()
+
+	client := NewClient(apiMock.URL, tokenFile, "default", apiMock.Client())
+
+	// The rotated token is picked up
+	for _, token := range []string{"first", "second"} {
+		ioutil.WriteFile(tokenFile, []byte(token+"\n"), 0600)
+		err := client.Do(http.MethodGet, "/api/v1/namespaces/default/configmaps/state", nil, &ConfigMap{})
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, []string{"Bearer first", "Bearer second"}, tokens)
+}
+
+func Test_StatusErrorIsReturned(t *testing.T) {
+	apiMock := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"kind":"Status","message":"configmaps \"state\" not found"}`))
+		}))
+	defer apiMock.Close()
+
+	client := NewClient(apiMock.URL, "", "default", apiMock.Client())
+
+	_, err := client.GetConfigMap("default", "state")
+	assert.NotNil(t, err)
+	assert.True(t, IsNotFound(err))
+	assert.Contains(t, err.Error(), `configmaps "state" not found`)
+}
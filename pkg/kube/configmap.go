@@ -0,0 +1,115 @@
+package kube
+
+import (
+	"net/http"
+	"net/url"
+)
+
+type ObjectMeta struct {
+	Name            string            `json:"name"`
+	Namespace       string            `json:"namespace,omitempty"`
+	ResourceVersion string            `json:"resourceVersion,omitempty"`
+	Labels          map[string]string `json:"labels,omitempty"`
+	Annotations     map[string]string `json:"annotations,omitempty"`
+	OwnerReferences []OwnerReference  `json:"ownerReferences,omitempty"`
+}
+
+type OwnerReference struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Name       string `json:"name"`
+	Controller bool   `json:"controller,omitempty"`
+}
+
+// Returns the owner which controls the object, e.g. the ReplicaSet
+// of a pod
+func (meta *ObjectMeta) GetController() (
+	OwnerReference,
+	bool,
+) {
+	for _, owner := range meta.OwnerReferences {
+		if owner.Controller {
+			return owner, true
+		}
+	}
+	return OwnerReference{}, false
+}
+
+type ConfigMap struct {
+	APIVersion string            `json:"apiVersion"`
+	Kind       string            `json:"kind"`
+	Metadata   ObjectMeta        `json:"metadata"`
+	Data       map[string]string `json:"data"`
+}
+
+func NewConfigMap(
+	namespace string,
+	name string,
+	data map[string]string,
+) *ConfigMap {
+	return &ConfigMap{
+		APIVersion: "v1",
+		Kind:       "ConfigMap",
+		Metadata: ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Data: data,
+	}
+}
+
+func (c *Client) GetConfigMap(
+	namespace string,
+	name string,
+) (
+	*ConfigMap,
+	error,
+) {
+	cm := &ConfigMap{}
+	err := c.Do(http.MethodGet, configMapPath(namespace, name), nil, cm)
+	if err != nil {
+		return nil, err
+	}
+	return cm, nil
+}
+
+func (c *Client) CreateConfigMap(
+	cm *ConfigMap,
+) (
+	*ConfigMap,
+	error,
+) {
+	created := &ConfigMap{}
+	err := c.Do(http.MethodPost, configMapPath(cm.Metadata.Namespace, ""), cm, created)
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// Replaces the config map. The update fails with a conflict if the
+// resource version is outdated.
+func (c *Client) UpdateConfigMap(
+	cm *ConfigMap,
+) (
+	*ConfigMap,
+	error,
+) {
+	updated := &ConfigMap{}
+	err := c.Do(http.MethodPut, configMapPath(cm.Metadata.Namespace, cm.Metadata.Name), cm, updated)
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+func configMapPath(
+	namespace string,
+	name string,
+) string {
+	path := "/api/v1/namespaces/" + url.PathEscape(namespace) + "/configmaps"
+	if name != "" {
+		path += "/" + url.PathEscape(name)
+	}
+	return path
+}
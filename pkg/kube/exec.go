@@ -0,0 +1,169 @@
+package kube
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/websocket"
+
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/logging"
+)
+
+// Protocol of the exec subresource over WebSocket. Every message
+// starts with the number of its channel.
+const ExecProtocol = "v4.channel.k8s.io"
+
+// Channels of the exec protocol
+const (
+	ExecChannelStdin  = 0
+	ExecChannelStdout = 1
+	ExecChannelStderr = 2
+	ExecChannelError  = 3
+)
+
+// Limit of the standard error which is kept for the error message
+const execStderrLimit = 4 * 1024
+
+// Status which the API server sends on the error channel when the
+// command has finished
+type ExecStatus struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+	Reason  string `json:"reason"`
+}
+
+// Runs the command in the container of the pod like "kubectl exec"
+// and writes its standard output into stdout. The container can be
+// empty if the pod has only one.
+func (c *Client) Exec(
+	namespace string,
+	pod string,
+	container string,
+	command []string,
+	stdout io.Writer,
+) error {
+	conn, err := c.dialExec(namespace, pod, container, command)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if c.client.Timeout > 0 {
+		conn.SetDeadline(time.Now().Add(c.client.Timeout))
+	}
+
+	var stderr, status bytes.Buffer
+	for {
+		var message []byte
+		err := websocket.Message.Receive(conn, &message)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %v", logging.KUBE__REQUEST_HAS_FAILED, err)
+		}
+		if len(message) == 0 {
+			continue
+		}
+
+		switch message[0] {
+		case ExecChannelStdout:
+			if _, err := stdout.Write(message[1:]); err != nil {
+				return err
+			}
+		case ExecChannelStderr:
+			if stderr.Len() < execStderrLimit {
+				stderr.Write(message[1:])
+			}
+		case ExecChannelError:
+			status.Write(message[1:])
+		}
+	}
+
+	return execError(status.Bytes(), stderr.String())
+}
+
+func (c *Client) dialExec(
+	namespace string,
+	pod string,
+	container string,
+	command []string,
+) (
+	*websocket.Conn,
+	error,
+) {
+	host, err := url.Parse(c.host)
+	if err != nil {
+		return nil, errors.New(logging.KUBE__REQUEST_COULD_NOT_BE_CREATED)
+	}
+
+	scheme := "wss"
+	if host.Scheme == "http" {
+		scheme = "ws"
+	}
+
+	query := url.Values{
+		"command": command,
+		"stdout":  []string{"true"},
+		"stderr":  []string{"true"},
+	}
+	if container != "" {
+		query.Set("container", container)
+	}
+	location := scheme + "://" + host.Host + host.Path + podsPath(namespace) +
+		"/" + url.PathEscape(pod) + "/exec?" + query.Encode()
+
+	config, err := websocket.NewConfig(location, c.host)
+	if err != nil {
+		return nil, errors.New(logging.KUBE__REQUEST_COULD_NOT_BE_CREATED)
+	}
+	config.Protocol = []string{ExecProtocol}
+	config.Dialer = &net.Dialer{Timeout: c.client.Timeout}
+	if err := c.authorize(config.Header); err != nil {
+		return nil, err
+	}
+
+	// Use the CA of the API server
+	if transport, ok := c.client.Transport.(*http.Transport); ok && transport.TLSClientConfig != nil {
+		config.TlsConfig = transport.TLSClientConfig.Clone()
+	}
+
+	conn, err := websocket.DialConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", logging.KUBE__REQUEST_HAS_FAILED, err)
+	}
+	return conn, nil
+}
+
+// Returns the error of the command if the status is not a success
+func execError(
+	status []byte,
+	stderr string,
+) error {
+	// Older API servers close the connection without a status
+	if len(status) == 0 {
+		return nil
+	}
+
+	execStatus := ExecStatus{}
+	if err := json.Unmarshal(status, &execStatus); err != nil {
+		return errors.New(logging.KUBE__RESPONSE_COULD_NOT_BE_PARSED)
+	}
+	if execStatus.Status == "Success" {
+		return nil
+	}
+
+	message := execStatus.Message
+	if stderr = strings.TrimSpace(stderr); stderr != "" {
+		message += ": " + stderr
+	}
+	return fmt.Errorf("%s: %s", logging.KUBE__EXEC_HAS_FAILED, message)
+}
@@ -0,0 +1,96 @@
+package kube
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/websocket"
+
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/logging"
+)
+
+// API server which runs the commands of the exec subresource
+func newExecServer(
+	t *testing.T,
+	run func(command []string, conn *websocket.Conn),
+) *httptest.Server {
+	exec := websocket.Server{
+		Handshake: func(config *websocket.Config, r *http.Request) error {
+			assert.Equal(t, []string{ExecProtocol}, config.Protocol)
+			assert.Equal(t, "true", r.URL.Query().Get("stdout"))
+			return nil
+		},
+		Handler: func(conn *websocket.Conn) {
+			run(conn.Request().URL.Query()["command"], conn)
+		},
+	}
+	return httptest.NewServer(exec)
+}
+
+func sendExecMessage(
+	conn *websocket.Conn,
+	channel byte,
+	data string,
+) {
+	websocket.Message.Send(conn, append([]byte{channel}, data...))
+}
+
+func Test_CommandIsExecuted(t *testing.T) {
+	apiMock := newExecServer(t, func(command []string, conn *websocket.Conn) {
+		assert.Equal(t, "/api/v1/namespaces/cache/pods/redis-0/exec", conn.Request().URL.Path)
+		assert.Equal(t, "redis", conn.Request().URL.Query().Get("container"))
+		assert.Equal(t, []string{"redis-cli", "info"}, command)
+
+		sendExecMessage(conn, ExecChannelStdout, "# Server\r\n")
+		sendExecMessage(conn, ExecChannelStderr, "warning\n")
+		sendExecMessage(conn, ExecChannelStdout, "redis_version:7.0.0\r\n")
+		sendExecMessage(conn, ExecChannelError, `{"metadata":{},"status":"Success"}`)
+	})
+	defer apiMock.Close()
+
+	client := NewClient(apiMock.URL, "", "default", apiMock.Client())
+
+	var stdout bytes.Buffer
+	err := client.Exec("cache", "redis-0", "redis", []string{"redis-cli", "info"}, &stdout)
+	assert.Nil(t, err)
+	assert.Equal(t, "# Server\r\nredis_version:7.0.0\r\n", stdout.String())
+}
+
+func Test_FailedCommandIsReturned(t *testing.T) {
+	apiMock := newExecServer(t, func(command []string, conn *websocket.Conn) {
+		sendExecMessage(conn, ExecChannelStderr, "redis-cli: not found\n")
+		sendExecMessage(conn, ExecChannelError, `{"metadata":{},"status":"Failure",`+
+			`"message":"command terminated with non-zero exit code: exit status 127","reason":"NonZeroExitCode"}`)
+	})
+	defer apiMock.Close()
+
+	client := NewClient(apiMock.URL, "", "default", apiMock.Client())
+
+	var stdout bytes.Buffer
+	err := client.Exec("cache", "redis-0", "", []string{"redis-cli", "info"}, &stdout)
+	assert.NotNil(t, err)
+	assert.True(t, strings.HasPrefix(err.Error(), logging.KUBE__EXEC_HAS_FAILED))
+	assert.Contains(t, err.Error(), "exit status 127: redis-cli: not found")
+}
+
+func Test_PodsAreListedBySelector(t *testing.T) {
+	apiMock := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/api/v1/namespaces/cache/pods", r.URL.Path)
+			assert.Equal(t, "app=redis", r.URL.Query().Get("labelSelector"))
+			w.Write([]byte(`{"items":[{"metadata":{"name":"redis-0"},"status":{"phase":"Running"}}]}`))
+		}))
+	defer apiMock.Close()
+
+	client := NewClient(apiMock.URL, "", "default", apiMock.Client())
+
+	pods, err := client.ListPods("cache", "app=redis", "")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(pods))
+	assert.Equal(t, "redis-0", pods[0].Metadata.Name)
+	assert.Equal(t, PodPhaseRunning, pods[0].Status.Phase)
+}
@@ -0,0 +1,133 @@
+package kube
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/logging"
+)
+
+// Resource which the API serves for a kind, e.g. "deployments"
+type APIResource struct {
+	Name       string `json:"name"`
+	Kind       string `json:"kind"`
+	Namespaced bool   `json:"namespaced"`
+}
+
+type APIResourceList struct {
+	GroupVersion string        `json:"groupVersion"`
+	Resources    []APIResource `json:"resources"`
+}
+
+// Object of any kind with its metadata. The whole object is kept as
+// JSON.
+type Object struct {
+	Metadata ObjectMeta
+	Raw      json.RawMessage
+}
+
+// Returns the resource which serves the kind of the API version, e.g.
+// "deployments" for apps/v1 Deployment. The resources are discovered
+// once per API version.
+func (c *Client) FindResource(
+	apiVersion string,
+	kind string,
+) (
+	*APIResource,
+	error,
+) {
+	if c.resources == nil {
+		c.resources = map[string]*APIResourceList{}
+	}
+
+	list, ok := c.resources[apiVersion]
+	if !ok {
+		list = &APIResourceList{}
+		if err := c.Do(http.MethodGet, apiVersionPath(apiVersion), nil, list); err != nil {
+			return nil, err
+		}
+		c.resources[apiVersion] = list
+	}
+
+	for _, resource := range list.Resources {
+		// Skip subresources like deployments/status
+		if resource.Kind == kind && !strings.Contains(resource.Name, "/") {
+			return &resource, nil
+		}
+	}
+	return nil, fmt.Errorf("%s: %s %s", logging.KUBE__RESOURCE_IS_NOT_FOUND, apiVersion, kind)
+}
+
+// Returns the object with the name or, if the name is empty, the
+// objects which match the label selector. The namespace is ignored
+// for cluster scoped kinds.
+func (c *Client) GetObjects(
+	apiVersion string,
+	kind string,
+	namespace string,
+	name string,
+	selector string,
+) (
+	[]Object,
+	error,
+) {
+	resource, err := c.FindResource(apiVersion, kind)
+	if err != nil {
+		return nil, err
+	}
+
+	path := apiVersionPath(apiVersion)
+	if resource.Namespaced {
+		path += "/namespaces/" + url.PathEscape(namespace)
+	}
+	path += "/" + resource.Name
+
+	raws := make([]json.RawMessage, 0)
+	if name != "" {
+		raw := json.RawMessage{}
+		if err := c.Do(http.MethodGet, path+"/"+url.PathEscape(name), nil, &raw); err != nil {
+			return nil, err
+		}
+		raws = append(raws, raw)
+	} else {
+		if selector != "" {
+			path += "?" + url.Values{"labelSelector": []string{selector}}.Encode()
+		}
+		list := struct {
+			Items []json.RawMessage `json:"items"`
+		}{}
+		if err := c.Do(http.MethodGet, path, nil, &list); err != nil {
+			return nil, err
+		}
+		raws = append(raws, list.Items...)
+	}
+
+	objects := make([]Object, 0, len(raws))
+	for _, raw := range raws {
+		object := struct {
+			Metadata ObjectMeta `json:"metadata"`
+		}{}
+		if err := json.Unmarshal(raw, &object); err != nil {
+			return nil, fmt.Errorf("%s: %v", logging.KUBE__RESPONSE_COULD_NOT_BE_PARSED, err)
+		}
+		objects = append(objects, Object{
+			Metadata: object.Metadata,
+			Raw:      raw,
+		})
+	}
+	return objects, nil
+}
+
+// Returns the path of the API version, core kinds are served under
+// /api and all others under /apis
+func apiVersionPath(
+	apiVersion string,
+) string {
+	if !strings.Contains(apiVersion, "/") {
+		return "/api/" + apiVersion
+	}
+	return "/apis/" + apiVersion
+}
@@ -0,0 +1,78 @@
+package kube
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/logging"
+)
+
+func Test_ObjectsAreFetchedByKind(t *testing.T) {
+	discoveries := 0
+	apiMock := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/apis/apps/v1":
+				discoveries++
+				w.Write([]byte(`{"groupVersion":"apps/v1","resources":[` +
+					`{"name":"deployments/status","kind":"Deployment","namespaced":true},` +
+					`{"name":"deployments","kind":"Deployment","namespaced":true}]}`))
+			case "/apis/apps/v1/namespaces/shop/deployments/cart":
+				w.Write([]byte(`{"metadata":{"name":"cart","namespace":"shop"},"spec":{"replicas":3}}`))
+			case "/apis/apps/v1/namespaces/shop/deployments":
+				assert.Equal(t, "tier=backend", r.URL.Query().Get("labelSelector"))
+				w.Write([]byte(`{"items":[` +
+					`{"metadata":{"name":"cart","namespace":"shop"}},` +
+					`{"metadata":{"name":"checkout","namespace":"shop"}}]}`))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+	defer apiMock.Close()
+
+	client := NewClient(apiMock.URL, "", "default", apiMock.Client())
+
+	objects, err := client.GetObjects("apps/v1", "Deployment", "shop", "cart", "")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(objects))
+	assert.Equal(t, "cart", objects[0].Metadata.Name)
+	assert.Contains(t, string(objects[0].Raw), `"replicas":3`)
+
+	objects, err = client.GetObjects("apps/v1", "Deployment", "shop", "", "tier=backend")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(objects))
+	assert.Equal(t, "checkout", objects[1].Metadata.Name)
+
+	// The resources are discovered once
+	assert.Equal(t, 1, discoveries)
+
+	_, err = client.GetObjects("apps/v1", "StatefulSet", "shop", "", "")
+	assert.NotNil(t, err)
+	assert.True(t, strings.HasPrefix(err.Error(), logging.KUBE__RESOURCE_IS_NOT_FOUND))
+}
+
+func Test_ClusterScopedObjectsIgnoreNamespace(t *testing.T) {
+	apiMock := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/api/v1":
+				w.Write([]byte(`{"groupVersion":"v1","resources":[{"name":"nodes","kind":"Node","namespaced":false}]}`))
+			case "/api/v1/nodes":
+				w.Write([]byte(`{"items":[{"metadata":{"name":"node-1"}}]}`))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+	defer apiMock.Close()
+
+	client := NewClient(apiMock.URL, "", "default", apiMock.Client())
+
+	objects, err := client.GetObjects("v1", "Node", "default", "", "")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(objects))
+	assert.Equal(t, "node-1", objects[0].Metadata.Name)
+}
@@ -0,0 +1,69 @@
+package kube
+
+import (
+	"net/http"
+	"net/url"
+)
+
+const PodPhaseRunning = "Running"
+
+type PodSpec struct {
+	NodeName string `json:"nodeName"`
+}
+
+type PodStatus struct {
+	Phase string `json:"phase"`
+	PodIP string `json:"podIP"`
+}
+
+type Pod struct {
+	Metadata ObjectMeta `json:"metadata"`
+	Spec     PodSpec    `json:"spec"`
+	Status   PodStatus  `json:"status"`
+}
+
+type PodList struct {
+	Items []Pod `json:"items"`
+}
+
+// Lists the pods of the namespace which match the label selector,
+// e.g. "app=redis,tier!=cache", and the field selector, e.g.
+// "spec.nodeName=node-1". The pods of all namespaces are listed if
+// the namespace is empty.
+func (c *Client) ListPods(
+	namespace string,
+	labelSelector string,
+	fieldSelector string,
+) (
+	[]Pod,
+	error,
+) {
+	path := "/api/v1/pods"
+	if namespace != "" {
+		path = podsPath(namespace)
+	}
+
+	query := url.Values{}
+	if labelSelector != "" {
+		query.Set("labelSelector", labelSelector)
+	}
+	if fieldSelector != "" {
+		query.Set("fieldSelector", fieldSelector)
+	}
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	list := &PodList{}
+	err := c.Do(http.MethodGet, path, nil, list)
+	if err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+func podsPath(
+	namespace string,
+) string {
+	return "/api/v1/namespaces/" + url.PathEscape(namespace) + "/pods"
+}
@@ -0,0 +1,62 @@
+package kube
+
+import (
+	"net/http"
+	"net/url"
+)
+
+type ServiceSpec struct {
+	ClusterIP string            `json:"clusterIP"`
+	Selector  map[string]string `json:"selector,omitempty"`
+}
+
+type Service struct {
+	Metadata ObjectMeta  `json:"metadata"`
+	Spec     ServiceSpec `json:"spec"`
+}
+
+type ServiceList struct {
+	Items []Service `json:"items"`
+}
+
+func (c *Client) GetService(
+	namespace string,
+	name string,
+) (
+	*Service,
+	error,
+) {
+	service := &Service{}
+	err := c.Do(http.MethodGet, servicesPath(namespace)+"/"+url.PathEscape(name), nil, service)
+	if err != nil {
+		return nil, err
+	}
+	return service, nil
+}
+
+// Lists the services of the namespace or, if it is empty, of all
+// namespaces
+func (c *Client) ListServices(
+	namespace string,
+) (
+	[]Service,
+	error,
+) {
+	path := "/api/v1/services"
+	if namespace != "" {
+		path = servicesPath(namespace)
+	}
+
+	list := &ServiceList{}
+	err := c.Do(http.MethodGet, path, nil, list)
+	if err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+func servicesPath(
+	namespace string,
+) string {
+	return "/api/v1/namespaces/" + url.PathEscape(namespace) + "/services"
+}
@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -29,6 +30,7 @@ type logObject struct {
 
 type forwarder struct {
 	levels []logrus.Level
+	mux    *sync.Mutex
 	logs   []logrus.Entry
 
 	client       *http.Client
@@ -40,13 +42,18 @@ func newForwarder(
 	levels []logrus.Level,
 	licenseKey string,
 	logsEndpoint string,
+	transport http.RoundTripper,
 ) *forwarder {
 
 	// Create HTTP client
-	client := http.Client{Timeout: time.Duration(30 * time.Second)}
+	client := http.Client{
+		Timeout:   time.Duration(30 * time.Second),
+		Transport: transport,
+	}
 
 	return &forwarder{
 		levels:       levels,
+		mux:          &sync.Mutex{},
 		logs:         make([]logrus.Entry, 0),
 		client:       &client,
 		licenseKey:   licenseKey,
@@ -60,29 +67,40 @@ func (f *forwarder) Levels() []logrus.Level {
 
 func (f *forwarder) Fire(e *logrus.Entry) error {
 	copy := *e
+	f.mux.Lock()
 	f.logs = append(f.logs, copy)
+	f.mux.Unlock()
 	return nil
 }
 
 func (f *forwarder) flush() error {
+	// Take over the collected logs so that the
+	// logs of the next run are collected separately
+	f.mux.Lock()
+	logs := f.logs
+	f.logs = make([]logrus.Entry, 0)
+	f.mux.Unlock()
+
 	// Return if there are no logs
-	if len(f.logs) == 0 {
+	if len(logs) == 0 {
 		return nil
 	}
 
 	// Create New Relic logs
-	nrLogs := f.createNewRelicLogs()
+	nrLogs := f.createNewRelicLogs(logs)
 
 	// Flush data to New Relic
 	return f.sendToNewRelic(nrLogs)
 }
 
-func (f *forwarder) createNewRelicLogs() []logObject {
+func (f *forwarder) createNewRelicLogs(
+	logs []logrus.Entry,
+) []logObject {
 	lo := &logObject{
 		Common: &commonBlock{
 			Attributes: make(map[string]string),
 		},
-		Logs: make([]logBlock, 0, len(f.logs)),
+		Logs: make([]logBlock, 0, len(logs)),
 	}
 
 	// Create common block
@@ -91,7 +109,7 @@ func (f *forwarder) createNewRelicLogs() []logObject {
 	}
 
 	// Create logs block
-	for _, log := range f.logs {
+	for _, log := range logs {
 		logBlock := logBlock{
 			Timestamp:  log.Time.UnixMicro(),
 			Message:    log.Message,
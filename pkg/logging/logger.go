@@ -1,6 +1,7 @@
 package logging
 
 import (
+	"net/http"
 	"os"
 
 	"github.com/sirupsen/logrus"
@@ -10,25 +11,164 @@ const (
 	// config
 	CONFIG__LICENSE_KEY_IS_NOT_PROVIDED               = "license key is not provided! define config.data.newrelic.licenseKey in your helm deployment"
 	CONFIG__ACCOUNT_ID_IS_NOT_PROVIDED                = "account id not provided! Define config.data.newrelic.accountId in your helm deployment"
+	CONFIG__REGION_IS_NOT_SUPPORTED                   = "region must be one of: US, EU, FedRAMP"
+	CONFIG__REGION_COULD_NOT_BE_DERIVED               = "region could not be derived from the license key! Define config.data.newrelic.region in your helm deployment"
+	CONFIG__NEW_RELIC_ENDPOINT_IS_INVALID             = "new relic endpoint overrides must be absolute http or https urls"
+	CONFIG__PROXY_URL_IS_INVALID                      = "proxy url must be an absolute http or https url"
+	CONFIG__PROXY_CA_COULD_NOT_BE_READ                = "proxy ca file could not be read or contains no pem certificate"
+	CONFIG__SCRAPE_MAX_BODY_SIZE_IS_INVALID           = "scrape max body size must be a positive number of bytes"
+	CONFIG__SPOOL_IS_INVALID                          = "spool requires a directory and positive limits"
+	CONFIG__STATE_BACKEND_IS_NOT_SUPPORTED            = "state backend must be one of: memory, file, configmap"
+	CONFIG__STATE_FILE_PATH_IS_NOT_DEFINED            = "file state requires state.path"
+	CONFIG__STATE_CONFIG_MAP_IS_NOT_DEFINED           = "configmap state requires state.configMap"
+	CONFIG__ENDPOINT_DELTA_MODE_IS_NOT_SUPPORTED      = "endpoint delta mode must be one of: keys, snapshot"
+	CONFIG__ENDPOINT_DELTA_HEARTBEAT_IS_INVALID       = "endpoint delta heartbeat must be a positive duration"
+	CONFIG__ENDPOINT_STATE_IS_NOT_PERSISTENT          = "endpoint delta and counters require state.backend file or configmap if daemon mode is disabled"
+	CONFIG__ENDPOINT_COUNTER_KEY_IS_INVALID           = "endpoint counter key must be a valid pattern"
+	CONFIG__ENDPOINT_COUNTER_COMPUTE_IS_NOT_SUPPORTED = "endpoint counter compute must be one of: rate, delta"
+	CONFIG__ENDPOINT_DERIVE_NAME_IS_INVALID           = "endpoint derive names must be defined and unique"
+	CONFIG__ENDPOINT_DERIVE_EXPRESSION_IS_INVALID     = "endpoint derive expression could not be compiled"
+	CONFIG__ENDPOINT_DERIVE_TYPE_IS_NOT_SUPPORTED     = "endpoint derive type must be one of: string, number, bool"
 	CONFIG__CONFIG_PATH_IS_NOT_DEFINED                = "config path is not defined"
 	CONFIG__CONFIG_FILE_COULD_NOT_BE_READ             = "config file could not be read"
 	CONFIG__CONFIG_FILE_COULD_NOT_BE_PARSED_INTO_YAML = "config file could not be parsed into yaml format"
 	CONFIG__NO_ENDPOINT_IS_DEFINED                    = "no endpoint is defined"
 	CONFIG__ENDPOINT_INFO_IS_MISSING                  = "check your endpoint definitions! type, name and url must be defined"
-	CONFIG__ENDPOINT_TYPE_IS_NOT_SUPPORTED            = "only the following types are supported: kvp"
+	CONFIG__ENDPOINT_URL_SCHEME_IS_NOT_SUPPORTED      = "endpoint url scheme must be one of: http, https, file, tcp, unix"
+	CONFIG__ENDPOINT_URL_IS_INVALID                   = "endpoint url is invalid! file and unix urls require an absolute path, tcp urls require a host and port"
+	CONFIG__ENDPOINT_EXEC_IS_INVALID                  = "endpoint exec requires a selector and a command"
+	CONFIG__ENDPOINT_KUBERNETES_IS_INVALID            = "endpoint kubernetes requires an apiVersion and a kind, and accepts either a name or a selector"
+	CONFIG__ENDPOINT_KUBERNETES_TYPE_IS_NOT_SUPPORTED = "endpoint kubernetes supports the types json, yaml and auto only"
+	CONFIG__ENDPOINT_SOURCE_IS_AMBIGUOUS              = "endpoint must not define both exec and kubernetes"
+	CONFIG__DISCOVERY_ENDPOINT_IS_INVALID             = "discovery endpoints must not define exec or kubernetes"
+	CONFIG__DISCOVERY_TEMPLATE_IS_INVALID             = "discovery endpoint name and url must be valid templates"
+	CONFIG__DISCOVERY_NAMESPACE_IS_INVALID            = "discovery namespaces must be valid patterns"
+	CONFIG__ENRICH_ATTRIBUTE_IS_NOT_SUPPORTED         = "enrich attributes must be some of: namespace, pod, service, node, owner"
+	CONFIG__ENRICH_LABEL_IS_INVALID                   = "enrich labels must be valid patterns"
+	CONFIG__ENRICH_CACHE_TTL_IS_INVALID               = "enrich cache ttl must be a positive duration"
+	CONFIG__ENDPOINT_SOCKET_REQUIRES_SOCKET_URL       = "endpoint socket requires a tcp or unix url"
+	CONFIG__ENDPOINT_TYPE_IS_NOT_SUPPORTED            = "endpoint type is not supported"
+	CONFIG__ENDPOINT_OPTIONS_ARE_INVALID              = "endpoint options are invalid"
+	CONFIG__DAEMON_INTERVAL_IS_INVALID                = "daemon intervals must be positive durations"
+	CONFIG__OTLP_PROTOCOL_IS_NOT_SUPPORTED            = "otlp protocol must be one of: http/protobuf, http/json"
+	CONFIG__OTLP_SIGNAL_IS_NOT_SUPPORTED              = "otlp signal must be one of: metrics, logs"
+	CONFIG__DESTINATION_NAME_IS_INVALID               = "destination names must be unique and must not be default"
+	CONFIG__DESTINATION_ACCOUNT_ID_IS_NOT_PROVIDED    = "destination requires accountId"
+	CONFIG__DESTINATION_LICENSE_KEY_IS_NOT_PROVIDED   = "destination license key could not be read from licenseKeyEnv or licenseKeyFile"
+	CONFIG__ENDPOINT_DESTINATION_IS_NOT_DEFINED       = "endpoint refers to a destination which is not defined"
+	CONFIG__SINK_TYPE_IS_NOT_SUPPORTED                = "sink type must be one of: newrelic, otlp, stdout, file, webhook"
+	CONFIG__SINK_FILE_PATH_IS_NOT_DEFINED             = "file sink requires file.path"
+	CONFIG__SINK_WEBHOOK_URL_IS_NOT_DEFINED           = "webhook sink requires webhook.url"
+	CONFIG__SINK_DESTINATION_IS_NOT_DEFINED           = "newrelic sink destination must be defined in newrelic.destinations"
+	CONFIG__CONFIG_FILE_IS_RELOADED                   = "config file is reloaded"
+	CONFIG__CONFIG_FILE_COULD_NOT_BE_RELOADED         = "config file has changed but could not be reloaded, keeping the previous config"
+
+	// parse
+	PARSE__TYPE_IS_NOT_REGISTERED                 = "endpoint type is not registered"
+	PARSE__OPTIONS_COULD_NOT_BE_DECODED           = "endpoint options could not be decoded"
+	PARSE__KVP_SEPARATOR_IS_EMPTY                 = "kvp separator must not be empty"
+	PARSE__KVP_COMMENT_PREFIX_IS_EMPTY            = "kvp comment prefixes must not be empty"
+	PARSE__KVP_DUPLICATE_KEYS_POLICY_IS_INVALID   = "kvp duplicate keys policy must be one of: last, first, error"
+	PARSE__KVP_LINES_COULD_NOT_BE_PARSED          = "some kvp lines could not be parsed"
+	PARSE__JSON_BODY_IS_INVALID                   = "response body is not valid json"
+	PARSE__JSON_EXPRESSION_IS_INVALID             = "json expression could not be compiled"
+	PARSE__JSON_FAN_OUT_IS_NOT_AN_ARRAY           = "json fan out expression did not select an array"
+	PARSE__JSON_FAN_OUT_COULD_NOT_BE_EVALUATED    = "json fan out expression could not be evaluated"
+	PARSE__JSON_ATTRIBUTES_COULD_NOT_BE_EXTRACTED = "some json attributes could not be extracted"
+	PARSE__REGEX_PATTERN_IS_NOT_DEFINED           = "at least one regex pattern must be defined"
+	PARSE__REGEX_PATTERN_IS_INVALID               = "regex pattern could not be compiled"
+	PARSE__REGEX_PATTERN_HAS_NO_NAMED_GROUP       = "regex pattern must have at least one named capture group"
+	PARSE__CSV_DELIMITER_IS_INVALID               = "csv delimiter must be a single character other than a quote or a line break"
+	PARSE__CSV_COMMENT_PREFIX_IS_INVALID          = "csv comment prefix must be a single character other than the delimiter"
+	PARSE__CSV_COLUMN_NAMES_ARE_NOT_DEFINED       = "csv column names must be defined if there is no header"
+	PARSE__CSV_COLUMN_IS_INVALID                  = "csv columns must have unique names and one of the types: string, number, bool"
+	PARSE__CSV_BODY_IS_INVALID                    = "response body is not valid csv"
+	PARSE__CSV_COLUMN_IS_MISSING                  = "csv table does not have the configured column"
+	PARSE__CSV_ROWS_COULD_NOT_BE_PARSED           = "some csv rows could not be parsed"
+	PARSE__XML_BODY_IS_INVALID                    = "response body is not valid xml"
+	PARSE__XML_EXPRESSION_IS_INVALID              = "xpath expression could not be compiled"
+	PARSE__YAML_BODY_IS_INVALID                   = "response body is not valid yaml"
+	PARSE__INI_SEPARATOR_IS_EMPTY                 = "ini separator must not be empty"
+	PARSE__INI_COMMENT_PREFIX_IS_EMPTY            = "ini comment prefixes must not be empty"
+	PARSE__INI_LINES_COULD_NOT_BE_PARSED          = "some ini lines could not be parsed"
+	PARSE__PROMETHEUS_METRIC_PATTERN_IS_INVALID   = "prometheus metric patterns must be valid patterns"
+	PARSE__PROMETHEUS_LINES_COULD_NOT_BE_PARSED   = "some prometheus lines could not be parsed"
+	PARSE__PROMETHEUS_PROTOBUF_IS_INVALID         = "response body is not valid prometheus protobuf"
 
 	// scrape
-	SCRAPE__HTTP_REQUEST_COULD_NOT_BE_CREATED = "http request could not be created"
-	SCRAPE__HTTP_REQUEST_HAS_FAILED           = "http request has failed"
-	SCRAPE__ENDPOINT_RETURNED_NOT_OK_STATUS   = "http request has returned not OK status"
-	SCRAPE__RESPONSE_BODY_COULD_NOT_BE_PARSED = "response body could not be parsed"
+	SCRAPE__HTTP_REQUEST_COULD_NOT_BE_CREATED  = "http request could not be created"
+	SCRAPE__HTTP_REQUEST_HAS_FAILED            = "http request has failed"
+	SCRAPE__ENDPOINT_RETURNED_NOT_OK_STATUS    = "http request has returned not OK status"
+	SCRAPE__RESPONSE_BODY_COULD_NOT_BE_PARSED  = "response body could not be parsed"
+	SCRAPE__FILE_COULD_NOT_BE_READ             = "file could not be read"
+	SCRAPE__SOCKET_COULD_NOT_BE_CONNECTED      = "socket could not be connected"
+	SCRAPE__SOCKET_REQUEST_HAS_FAILED          = "socket request has failed"
+	SCRAPE__KUBE_CLIENT_COULD_NOT_BE_CREATED   = "kubernetes client could not be created"
+	SCRAPE__PODS_COULD_NOT_BE_LISTED           = "pods could not be listed"
+	SCRAPE__NO_RUNNING_POD_IS_FOUND            = "no running pod matches the selector"
+	SCRAPE__COMMAND_HAS_FAILED                 = "command could not be run in the pod"
+	SCRAPE__OBJECTS_COULD_NOT_BE_FETCHED       = "kubernetes objects could not be fetched"
+	SCRAPE__RESPONSE_BODY_COULD_NOT_BE_READ    = "response body could not be read"
+	SCRAPE__RESPONSE_BODY_IS_TOO_LARGE         = "response body exceeds the max body size"
+	SCRAPE__CONTENT_ENCODING_IS_NOT_SUPPORTED  = "response content encoding must be one of: gzip, deflate, zstd"
+	SCRAPE__RESPONSE_BODY_COULD_NOT_BE_DECODED = "response body could not be decompressed"
+	SCRAPE__PARSER_COULD_NOT_BE_CREATED        = "parser could not be created"
+	SCRAPE__ATTRIBUTE_COULD_NOT_BE_DERIVED     = "attribute could not be derived"
 
 	// forward
 	FORWARD__PAYLOAD_COULD_NOT_BE_CREATED      = "payload could not be created"
 	FORWARD__PAYLOAD_COULD_NOT_BE_ZIPPED       = "payload could not be zipped"
 	FORWARD__HTTP_REQUEST_COULD_NOT_BE_CREATED = "http request could not be created"
 	FORWARD__HTTP_REQUEST_HAS_FAILED           = "http request has failed"
+	FORWARD__DESTINATION_HAS_FAILED            = "events could not be forwarded to destination"
 	FORWARD__NEW_RELIC_RETURNED_NOT_OK_STATUS  = "http request has returned not OK status"
+	FORWARD__NEW_RELIC_REJECTED_EVENTS         = "events are rejected by New Relic and will not be retried"
+
+	// sink
+	SINK__HAS_FAILED                        = "sink has failed"
+	SINK__EVENTS_COULD_NOT_BE_CREATED       = "events could not be encoded"
+	SINK__EVENTS_COULD_NOT_BE_WRITTEN       = "events could not be written"
+	SINK__HTTP_REQUEST_COULD_NOT_BE_CREATED = "http request could not be created"
+	SINK__HTTP_REQUEST_HAS_FAILED           = "http request has failed"
+	SINK__WEBHOOK_RETURNED_NOT_OK_STATUS    = "webhook has returned not OK status"
+
+	// spool
+	SPOOL__DIRECTORY_COULD_NOT_BE_CREATED = "spool directory could not be created"
+	SPOOL__BATCH_COULD_NOT_BE_WRITTEN     = "batch could not be written into the spool"
+	SPOOL__BATCHES_COULD_NOT_BE_READ      = "batches could not be read from the spool"
+	SPOOL__BATCH_COULD_NOT_BE_REMOVED     = "batch could not be removed from the spool"
+	SPOOL__BATCHES_ARE_DISCARDED          = "spooled batches are discarded because they exceeded the maximum age or size or were rejected"
+
+	// state
+	STATE__COULD_NOT_BE_LOADED = "state could not be loaded"
+	STATE__COULD_NOT_BE_SAVED  = "state could not be saved"
+
+	// kube
+	KUBE__CLIENT_IS_NOT_IN_CLUSTER     = "kubernetes client could not be created, the scraper is not running in a pod"
+	KUBE__REQUEST_COULD_NOT_BE_CREATED = "kubernetes api request could not be created"
+	KUBE__REQUEST_HAS_FAILED           = "kubernetes api request has failed"
+	KUBE__API_RETURNED_NOT_OK_STATUS   = "kubernetes api has returned not OK status"
+	KUBE__RESPONSE_COULD_NOT_BE_PARSED = "kubernetes api response could not be parsed"
+	KUBE__RESOURCE_IS_NOT_FOUND        = "kubernetes api does not serve the kind"
+	KUBE__EXEC_HAS_FAILED              = "command has failed in the container"
+
+	// discover
+	DISCOVER__KUBE_CLIENT_COULD_NOT_BE_CREATED = "kubernetes client for discovery could not be created"
+	DISCOVER__PODS_COULD_NOT_BE_LISTED         = "pods of the discovery rule could not be listed"
+	DISCOVER__TEMPLATE_COULD_NOT_BE_RENDERED   = "discovery endpoint name or url could not be rendered for the pod"
+	DISCOVER__ENDPOINT_IS_DUPLICATE            = "discovered endpoint is already defined"
+	DISCOVER__ENDPOINT_IS_INVALID              = "discovered endpoint is invalid"
+
+	// enrich
+	ENRICH__KUBE_CLIENT_COULD_NOT_BE_CREATED = "kubernetes client for enrichment could not be created"
+	ENRICH__TARGET_COULD_NOT_BE_LOOKED_UP    = "kubernetes metadata of the endpoint could not be looked up"
+
+	// otlp
+	OTLP__PAYLOAD_COULD_NOT_BE_CREATED      = "otlp payload could not be created"
+	OTLP__PAYLOAD_COULD_NOT_BE_ZIPPED       = "otlp payload could not be zipped"
+	OTLP__HTTP_REQUEST_COULD_NOT_BE_CREATED = "otlp http request could not be created"
+	OTLP__HTTP_REQUEST_HAS_FAILED           = "otlp http request has failed"
+	OTLP__RECEIVER_RETURNED_NOT_OK_STATUS   = "otlp receiver has returned not OK status"
 
 	// logs
 	LOGS__PAYLOAD_COULD_NOT_BE_CREATED      = "payload could not be created"
@@ -53,6 +193,8 @@ func NewLogger(
 	switch logLevel {
 	case "DEBUG":
 		l.Level = logrus.DebugLevel
+	case "INFO":
+		l.Level = logrus.InfoLevel
 	default:
 		l.Level = logrus.ErrorLevel
 	}
@@ -67,6 +209,7 @@ func NewLoggerWithForwarder(
 	logLevel string,
 	licenseKey string,
 	logsEndpoint string,
+	transport http.RoundTripper,
 ) *Logger {
 	l := logrus.New()
 	l.Out = os.Stdout
@@ -75,11 +218,13 @@ func NewLoggerWithForwarder(
 	switch logLevel {
 	case "DEBUG":
 		l.Level = logrus.DebugLevel
+	case "INFO":
+		l.Level = logrus.InfoLevel
 	default:
 		l.Level = logrus.ErrorLevel
 	}
 
-	f := newForwarder(logrus.AllLevels, licenseKey, logsEndpoint)
+	f := newForwarder(logrus.AllLevels, licenseKey, logsEndpoint, transport)
 	l.AddHook(f)
 
 	return &Logger{
@@ -103,6 +248,8 @@ func (l *Logger) Log(
 	switch lvl {
 	case logrus.ErrorLevel:
 		l.log.WithFields(fields).Error(msg)
+	case logrus.InfoLevel:
+		l.log.WithFields(fields).Info(msg)
 	default:
 		l.log.WithFields(fields).Debug(msg)
 	}
@@ -129,6 +276,8 @@ func (l *Logger) LogWithFields(
 	switch lvl {
 	case logrus.ErrorLevel:
 		l.log.WithFields(fields).Error(msg)
+	case logrus.InfoLevel:
+		l.log.WithFields(fields).Info(msg)
 	default:
 		l.log.WithFields(fields).Debug(msg)
 	}
@@ -156,5 +305,8 @@ func getCommonAttributes() map[string]string {
 }
 
 func (l *Logger) Flush() error {
+	if l.forwarder == nil {
+		return nil
+	}
 	return l.forwarder.flush()
 }
@@ -0,0 +1,158 @@
+package parse
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+func init() {
+	Register(Definition{
+		Type: "auto",
+		Description: "Picks the format by the Content-Type of the response: JSON, OpenMetrics, " +
+			"the Prometheus text or protobuf format. If the Content-Type is missing or generic like " +
+			"`text/plain`, the body is sniffed: JSON documents are parsed as `json`, bodies which look " +
+			"like Prometheus metrics as `prometheus` and all others as `kvp`. The parsers use their defaults.",
+		Structured: true,
+		New:        newAutoParser,
+	})
+}
+
+const autoAccept = mediaTypeOpenMetrics + ";version=1.0.0;q=0.6," +
+	mediaTypeText + ";version=0.0.4;q=0.5," +
+	mediaTypeJson + ";q=0.5," +
+	"*/*;q=0.1"
+
+// Implements Parser, ContentParser, Negotiator and TypedParser
+// interfaces
+type AutoParser struct {
+	json       Parser
+	prometheus *PrometheusParser
+	kvp        Parser
+
+	// Parser which was picked for the last body
+	selected Parser
+}
+
+func newAutoParser(
+	options map[string]interface{},
+) (
+	Parser,
+	error,
+) {
+	if err := DecodeOptions(options, &struct{}{}); err != nil {
+		return nil, err
+	}
+
+	jsonParser, err := newJsonParser(nil)
+	if err != nil {
+		return nil, err
+	}
+	prometheusParser, err := newPrometheusParser(nil)
+	if err != nil {
+		return nil, err
+	}
+	kvpParser, err := newKvpParser(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AutoParser{
+		json:       jsonParser,
+		prometheus: prometheusParser.(*PrometheusParser),
+		kvp:        kvpParser,
+	}, nil
+}
+
+func (p *AutoParser) Accept() string {
+	return autoAccept
+}
+
+// Picks the format by sniffing the body
+func (p *AutoParser) Run(
+	data []byte,
+) (
+	[]map[string]string,
+	error,
+) {
+	return p.RunContent(data, "")
+}
+
+func (p *AutoParser) RunContent(
+	data []byte,
+	contentType string,
+) (
+	[]map[string]string,
+	error,
+) {
+	p.selected = p.pick(data, contentType)
+	return RunContent(p.selected, data, contentType)
+}
+
+// Returns the types of the parser which was picked for the last body
+func (p *AutoParser) Types() map[string]string {
+	if tp, ok := p.selected.(TypedParser); ok {
+		return tp.Types()
+	}
+	return nil
+}
+
+func (p *AutoParser) pick(
+	data []byte,
+	contentType string,
+) Parser {
+	mediaType, params := parseContentType(contentType)
+	switch {
+	case mediaType == mediaTypeJson || strings.HasSuffix(mediaType, "+json"):
+		return p.json
+	case mediaType == mediaTypeOpenMetrics || isPrometheusProtobuf(mediaType, params):
+		return p.prometheus
+	case mediaType == mediaTypeText && params["version"] == "0.0.4":
+		return p.prometheus
+	}
+
+	// Sniff the body
+	switch {
+	case looksLikeJson(data):
+		return p.json
+	case looksLikePrometheus(data):
+		return p.prometheus
+	default:
+		return p.kvp
+	}
+}
+
+func looksLikeJson(
+	data []byte,
+) bool {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 || (trimmed[0] != '{' && trimmed[0] != '[') {
+		return false
+	}
+	return json.Valid(trimmed)
+}
+
+// A body looks like Prometheus metrics if it has TYPE or HELP
+// comments or if all of its lines are samples
+func looksLikePrometheus(
+	data []byte,
+) bool {
+	samples := 0
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "# TYPE ") || strings.HasPrefix(line, "# HELP ") {
+			return true
+		}
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		if _, err := parsePrometheusSample(line); err != nil {
+			return false
+		}
+		samples++
+	}
+	return samples > 0
+}
@@ -0,0 +1,74 @@
+package parse
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_AutoPicksParserByContentType(t *testing.T) {
+	p, err := New("auto", nil)
+	assert.Nil(t, err)
+	auto := p.(ContentParser)
+
+	records, err := auto.RunContent([]byte(`{"status": "UP"}`), "application/json; charset=utf-8")
+	assert.Nil(t, err)
+	assert.Equal(t, []map[string]string{{"status": "UP"}}, records)
+
+	records, err = auto.RunContent([]byte(openMetricsText), "application/openmetrics-text; version=1.0.0")
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(records))
+	assert.Equal(t, "counter", records[0]["metricType"])
+
+	// The version of the Prometheus text format is trusted
+	records, err = auto.RunContent([]byte("status: UP\n"), "text/plain; version=0.0.4")
+	assert.NotNil(t, err)
+	assert.Equal(t, 0, len(records))
+}
+
+func Test_AutoForwardsTypesOfPickedParser(t *testing.T) {
+	p, err := New("auto", nil)
+	assert.Nil(t, err)
+	auto := p.(ContentParser)
+
+	_, err = auto.RunContent([]byte(openMetricsText), "application/openmetrics-text; version=1.0.0")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"metricValue": ValueTypeNumber}, p.(TypedParser).Types())
+
+	_, err = auto.RunContent([]byte("status: UP\n"), "")
+	assert.Nil(t, err)
+	assert.Empty(t, p.(TypedParser).Types())
+}
+
+func Test_AutoSniffsBody(t *testing.T) {
+	p, err := New("auto", nil)
+	assert.Nil(t, err)
+	auto := p.(ContentParser)
+
+	records, err := auto.RunContent([]byte("  [1, 2]"), "text/plain")
+	assert.Nil(t, err)
+	assert.Equal(t, []map[string]string{{"0": "1", "1": "2"}}, records)
+
+	records, err = auto.RunContent([]byte(prometheusText), "text/plain")
+	assert.Nil(t, err)
+	assert.Equal(t, 7, len(records))
+
+	records, err = auto.RunContent([]byte("up 1\nrequests_total{code=\"200\"} 5\n"), "")
+	assert.Nil(t, err)
+	assert.Equal(t, "requests_total", records[1]["metricName"])
+
+	records, err = auto.RunContent([]byte("status: UP\nversion: 1.2\n"), "")
+	assert.Nil(t, err)
+	assert.Equal(t, []map[string]string{{"status": "UP", "version": "1.2"}}, records)
+}
+
+func Test_AutoAcceptsMetricFormats(t *testing.T) {
+	p, err := New("auto", nil)
+	assert.Nil(t, err)
+	assert.Contains(t, p.(Negotiator).Accept(), "application/openmetrics-text")
+	assert.Contains(t, p.(Negotiator).Accept(), "application/json")
+
+	p, err = New("prometheus", nil)
+	assert.Nil(t, err)
+	assert.Contains(t, p.(Negotiator).Accept(), "proto=io.prometheus.client.MetricFamily")
+}
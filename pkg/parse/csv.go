@@ -0,0 +1,277 @@
+package parse
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/logging"
+)
+
+func init() {
+	Register(Definition{
+		Type: "csv",
+		Description: "Tables of comma separated values (or any other delimiter, e.g. tabs for TSV). " +
+			"Every row becomes a separate record whose attributes are named after the columns.",
+		Options: []Option{
+			{
+				Name:        "delimiter",
+				Type:        "string",
+				Default:     "`,`",
+				Description: "Single character which separates the fields, e.g. `\"\\t\"` for TSV.",
+			},
+			{
+				Name:        "header",
+				Type:        "bool",
+				Default:     "true",
+				Description: "Whether the first row contains the names of the columns.",
+			},
+			{
+				Name:        "columnNames",
+				Type:        "list",
+				Default:     "`[]`",
+				Description: "Names of the columns in their order. Required without a header, otherwise they replace the names of the header.",
+			},
+			{
+				Name:        "keyColumn",
+				Type:        "string",
+				Default:     "",
+				Description: "Column which identifies a row, e.g. the job id. Rows with an empty or a duplicated key are skipped and reported.",
+			},
+			{
+				Name:        "columns",
+				Type:        "list",
+				Default:     "`[]`",
+				Description: "Columns which become attributes. All columns become string attributes if empty.",
+			},
+			{
+				Name:        "columns[].name",
+				Type:        "string",
+				Description: "Name of the column.",
+			},
+			{
+				Name:    "columns[].type",
+				Type:    "string",
+				Default: "`string`",
+				Description: "Type of the values: `string`, `number` or `bool`. Numbers and bools are sent as such " +
+					"instead of strings. Values which cannot be converted are left out and reported.",
+			},
+			{
+				Name:        "commentPrefix",
+				Type:        "string",
+				Default:     "",
+				Description: "Single character which starts a comment line, e.g. `#`.",
+			},
+		},
+		New: newCsvParser,
+	})
+}
+
+type csvColumnOptions struct {
+	Name string `yaml:"name"`
+	Type string `yaml:"type"`
+}
+
+type csvOptions struct {
+	Delimiter     string             `yaml:"delimiter"`
+	Header        bool               `yaml:"header"`
+	ColumnNames   []string           `yaml:"columnNames"`
+	KeyColumn     string             `yaml:"keyColumn"`
+	Columns       []csvColumnOptions `yaml:"columns"`
+	CommentPrefix string             `yaml:"commentPrefix"`
+}
+
+// Implements Parser and TypedParser interfaces
+type CsvParser struct {
+	delimiter   rune
+	comment     rune
+	header      bool
+	columnNames []string
+	keyColumn   string
+	columns     []csvColumnOptions
+}
+
+func newCsvParser(
+	options map[string]interface{},
+) (
+	Parser,
+	error,
+) {
+	opts := csvOptions{
+		Delimiter: ",",
+		Header:    true,
+	}
+	if err := DecodeOptions(options, &opts); err != nil {
+		return nil, err
+	}
+
+	delimiter, ok := singleRune(opts.Delimiter)
+	if !ok || delimiter == '"' || delimiter == '\r' || delimiter == '\n' {
+		return nil, errors.New(logging.PARSE__CSV_DELIMITER_IS_INVALID)
+	}
+
+	var comment rune
+	if opts.CommentPrefix != "" {
+		comment, ok = singleRune(opts.CommentPrefix)
+		if !ok || comment == delimiter {
+			return nil, errors.New(logging.PARSE__CSV_COMMENT_PREFIX_IS_INVALID)
+		}
+	}
+
+	if !opts.Header && len(opts.ColumnNames) == 0 {
+		return nil, errors.New(logging.PARSE__CSV_COLUMN_NAMES_ARE_NOT_DEFINED)
+	}
+
+	names := map[string]bool{}
+	for _, column := range opts.Columns {
+		if column.Name == "" || names[column.Name] || !isValueType(column.Type) {
+			return nil, errors.New(logging.PARSE__CSV_COLUMN_IS_INVALID)
+		}
+		names[column.Name] = true
+	}
+
+	return &CsvParser{
+		delimiter:   delimiter,
+		comment:     comment,
+		header:      opts.Header,
+		columnNames: opts.ColumnNames,
+		keyColumn:   opts.KeyColumn,
+		columns:     opts.Columns,
+	}, nil
+}
+
+func singleRune(
+	value string,
+) (
+	rune,
+	bool,
+) {
+	if utf8.RuneCountInString(value) != 1 {
+		return 0, false
+	}
+	r, _ := utf8.DecodeRuneInString(value)
+	return r, r != utf8.RuneError
+}
+
+func (p *CsvParser) Run(
+	data []byte,
+) (
+	[]map[string]string,
+	error,
+) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comma = p.delimiter
+	reader.Comment = p.comment
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	names := p.columnNames
+	if p.header {
+		header, err := reader.Read()
+		if err == io.EOF {
+			return []map[string]string{}, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", logging.PARSE__CSV_BODY_IS_INVALID, err)
+		}
+		if len(names) == 0 {
+			names = trimFields(header)
+		}
+	}
+
+	// Map the configured columns to their positions
+	positions := make(map[string]int, len(names))
+	for i, name := range names {
+		positions[name] = i
+	}
+	columns := p.columns
+	if len(columns) == 0 {
+		columns = make([]csvColumnOptions, 0, len(names))
+		for _, name := range names {
+			columns = append(columns, csvColumnOptions{Name: name})
+		}
+	}
+	for _, column := range columns {
+		if _, ok := positions[column.Name]; !ok {
+			return nil, fmt.Errorf("%s: %s", logging.PARSE__CSV_COLUMN_IS_MISSING, column.Name)
+		}
+	}
+	if _, ok := positions[p.keyColumn]; p.keyColumn != "" && !ok {
+		return nil, fmt.Errorf("%s: %s", logging.PARSE__CSV_COLUMN_IS_MISSING, p.keyColumn)
+	}
+
+	records := make([]map[string]string, 0)
+	failures := make([]string, 0)
+	keys := map[string]bool{}
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// A malformed row cannot be skipped reliably
+			failures = append(failures, err.Error())
+			break
+		}
+		line, _ := reader.FieldPos(0)
+
+		if len(row) != len(names) {
+			failures = append(failures, fmt.Sprintf("line %d: %d fields instead of %d", line, len(row), len(names)))
+			continue
+		}
+		row = trimFields(row)
+
+		record := make(map[string]string, len(columns)+1)
+		if p.keyColumn != "" {
+			key := row[positions[p.keyColumn]]
+			if key == "" || keys[key] {
+				failures = append(failures, fmt.Sprintf("line %d: key %q is empty or duplicated", line, key))
+				continue
+			}
+			keys[key] = true
+			record[p.keyColumn] = key
+		}
+
+		for _, column := range columns {
+			value, err := convertValue(row[positions[column.Name]], column.Type)
+			if err != nil {
+				failures = append(failures, fmt.Sprintf("line %d: %s: %v", line, column.Name, err))
+				continue
+			}
+			record[column.Name] = value
+		}
+		records = append(records, record)
+	}
+
+	// Report the failed rows along with the parsed ones
+	if len(failures) > 0 {
+		return records, fmt.Errorf("%s: %s", logging.PARSE__CSV_ROWS_COULD_NOT_BE_PARSED, strings.Join(failures, "; "))
+	}
+
+	return records, nil
+}
+
+// Returns the types of the columns which are no strings
+func (p *CsvParser) Types() map[string]string {
+	types := map[string]string{}
+	for _, column := range p.columns {
+		if column.Type == ValueTypeNumber || column.Type == ValueTypeBool {
+			types[column.Name] = column.Type
+		}
+	}
+	return types
+}
+
+func trimFields(
+	fields []string,
+) []string {
+	trimmed := make([]string, len(fields))
+	for i, field := range fields {
+		trimmed[i] = strings.TrimSpace(field)
+	}
+	return trimmed
+}
@@ -0,0 +1,106 @@
+package parse
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/logging"
+)
+
+const batchJobs = `id,status,duration,succeeded
+job-1, RUNNING ,12.50,true
+job-2,FAILED,3,false
+`
+
+func Test_CsvRowsBecomeRecords(t *testing.T) {
+	p, err := New("csv", nil)
+	assert.Nil(t, err)
+
+	records, err := p.Run([]byte(batchJobs))
+	assert.Nil(t, err)
+	assert.Equal(t, []map[string]string{
+		{"id": "job-1", "status": "RUNNING", "duration": "12.50", "succeeded": "true"},
+		{"id": "job-2", "status": "FAILED", "duration": "3", "succeeded": "false"},
+	}, records)
+}
+
+func Test_CsvColumnsAreTyped(t *testing.T) {
+	p, err := New("csv", map[string]interface{}{
+		"keyColumn": "id",
+		"columns": []interface{}{
+			map[string]interface{}{"name": "duration", "type": "number"},
+			map[string]interface{}{"name": "succeeded", "type": "bool"},
+		},
+	})
+	assert.Nil(t, err)
+
+	body := batchJobs + "job-3,RUNNING,unknown,true\njob-1,FAILED,1,false\n,FAILED,1,false\njob-4,FAILED\n"
+	records, err := p.Run([]byte(body))
+	assert.Equal(t, []map[string]string{
+		{"id": "job-1", "duration": "12.5", "succeeded": "true"},
+		{"id": "job-2", "duration": "3", "succeeded": "false"},
+		{"id": "job-3", "succeeded": "true"},
+	}, records)
+
+	// The invalid value, the duplicated and empty keys and the short
+	// row are reported
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), logging.PARSE__CSV_ROWS_COULD_NOT_BE_PARSED)
+	assert.Contains(t, err.Error(), "line 4: duration")
+	assert.Contains(t, err.Error(), "line 5: key \"job-1\"")
+	assert.Contains(t, err.Error(), "line 6: key \"\"")
+	assert.Contains(t, err.Error(), "line 7: 2 fields instead of 4")
+
+	// The types are passed on to the sinks
+	assert.Equal(t, map[string]string{"duration": "number", "succeeded": "bool"},
+		p.(TypedParser).Types())
+}
+
+func Test_TsvWithoutHeaderIsParsed(t *testing.T) {
+	p, err := New("csv", map[string]interface{}{
+		"delimiter":     "\t",
+		"header":        false,
+		"columnNames":   []interface{}{"table", "rows"},
+		"commentPrefix": "#",
+	})
+	assert.Nil(t, err)
+
+	records, err := p.Run([]byte("# table\trows\norders\t120\nusers\t7\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, []map[string]string{
+		{"table": "orders", "rows": "120"},
+		{"table": "users", "rows": "7"},
+	}, records)
+}
+
+func Test_CsvColumnIsMissing(t *testing.T) {
+	p, err := New("csv", map[string]interface{}{
+		"keyColumn": "name",
+	})
+	assert.Nil(t, err)
+
+	records, err := p.Run([]byte(batchJobs))
+	assert.Nil(t, records)
+	assert.NotNil(t, err)
+	assert.Equal(t, logging.PARSE__CSV_COLUMN_IS_MISSING+": name", err.Error())
+}
+
+func Test_CsvOptionsAreValidated(t *testing.T) {
+	tests := []struct {
+		options  map[string]interface{}
+		expected string
+	}{
+		{map[string]interface{}{"delimiter": ";;"}, logging.PARSE__CSV_DELIMITER_IS_INVALID},
+		{map[string]interface{}{"commentPrefix": ","}, logging.PARSE__CSV_COMMENT_PREFIX_IS_INVALID},
+		{map[string]interface{}{"header": false}, logging.PARSE__CSV_COLUMN_NAMES_ARE_NOT_DEFINED},
+		{map[string]interface{}{
+			"columns": []interface{}{map[string]interface{}{"name": "duration", "type": "int"}},
+		}, logging.PARSE__CSV_COLUMN_IS_INVALID},
+	}
+
+	for _, test := range tests {
+		_, err := New("csv", test.options)
+		assert.NotNil(t, err)
+		assert.Equal(t, test.expected, err.Error())
+	}
+}
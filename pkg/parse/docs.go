@@ -0,0 +1,57 @@
+package parse
+
+import (
+	"fmt"
+	"io"
+)
+
+// Writes the markdown documentation of all registered endpoint types
+func WriteDocs(
+	w io.Writer,
+) error {
+	_, err := fmt.Fprint(w, "# Endpoint types\n\n"+
+		"<!-- Generated by `go run . -parser-docs`. Do not edit manually. -->\n")
+	if err != nil {
+		return err
+	}
+
+	for _, typ := range Types() {
+		def, _ := Lookup(typ)
+
+		_, err = fmt.Fprintf(w, "\n## %s\n\n%s\n", def.Type, def.Description)
+		if err != nil {
+			return err
+		}
+
+		if def.Structured {
+			_, err = fmt.Fprint(w, "\nThis type can read the objects of `kubernetes` endpoints.\n")
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(def.Options) == 0 {
+			_, err = fmt.Fprint(w, "\nThis type has no options.\n")
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		_, err = fmt.Fprint(w, "\n| Option | Type | Default | Description |\n"+
+			"| ------ | ---- | ------- | ----------- |\n")
+		if err != nil {
+			return err
+		}
+
+		for _, opt := range def.Options {
+			_, err = fmt.Fprintf(w, "| `%s` | %s | %s | %s |\n",
+				opt.Name, opt.Type, opt.Default, opt.Description)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
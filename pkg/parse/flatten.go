@@ -0,0 +1,65 @@
+package parse
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// Flattens nested objects and arrays into dotted keys like
+// "queues.0.name". Scalars are stored under the prefix itself
+// and null values are skipped.
+func flatten(
+	prefix string,
+	value interface{},
+	values map[string]string,
+) {
+	switch v := value.(type) {
+	case nil:
+		return
+	case map[string]interface{}:
+		for key, val := range v {
+			flatten(joinKey(prefix, key), val, values)
+		}
+	case map[interface{}]interface{}:
+		for key, val := range v {
+			flatten(joinKey(prefix, fmt.Sprintf("%v", key)), val, values)
+		}
+	case []interface{}:
+		for i, val := range v {
+			flatten(joinKey(prefix, strconv.Itoa(i)), val, values)
+		}
+	default:
+		if prefix == "" {
+			prefix = "value"
+		}
+		values[prefix] = formatScalar(v)
+	}
+}
+
+func joinKey(
+	prefix string,
+	key string,
+) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+func formatScalar(
+	value interface{},
+) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
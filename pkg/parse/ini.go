@@ -0,0 +1,153 @@
+package parse
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/logging"
+)
+
+func init() {
+	Register(Definition{
+		Type: "ini",
+		Description: "INI files with `[section]` headers. The keys are prefixed with their section " +
+			"like `section.key`, nested sections like `[database.pool]` result in dotted keys as in JSON. " +
+			"Keys before the first section have no prefix.",
+		Options: []Option{
+			{
+				Name:        "separator",
+				Type:        "string",
+				Default:     "`=`",
+				Description: "Separator between the key and the value. Only its first occurrence in a line is used.",
+			},
+			{
+				Name:        "commentPrefixes",
+				Type:        "list",
+				Default:     "`[\";\", \"#\"]`",
+				Description: "Lines starting with one of these prefixes are ignored.",
+			},
+			{
+				Name:        "stripQuotes",
+				Type:        "bool",
+				Default:     "true",
+				Description: "Removes the surrounding single or double quotes of the values.",
+			},
+		},
+		New: newIniParser,
+	})
+}
+
+type iniOptions struct {
+	Separator       string   `yaml:"separator"`
+	CommentPrefixes []string `yaml:"commentPrefixes"`
+	StripQuotes     bool     `yaml:"stripQuotes"`
+}
+
+// Implements Parser interface
+type IniParser struct {
+	separator       string
+	commentPrefixes []string
+	stripQuotes     bool
+}
+
+func newIniParser(
+	options map[string]interface{},
+) (
+	Parser,
+	error,
+) {
+	opts := iniOptions{
+		Separator:       "=",
+		CommentPrefixes: []string{";", "#"},
+		StripQuotes:     true,
+	}
+	if err := DecodeOptions(options, &opts); err != nil {
+		return nil, err
+	}
+
+	if opts.Separator == "" {
+		return nil, errors.New(logging.PARSE__INI_SEPARATOR_IS_EMPTY)
+	}
+
+	for _, prefix := range opts.CommentPrefixes {
+		if prefix == "" {
+			return nil, errors.New(logging.PARSE__INI_COMMENT_PREFIX_IS_EMPTY)
+		}
+	}
+
+	return &IniParser{
+		separator:       opts.Separator,
+		commentPrefixes: opts.CommentPrefixes,
+		stripQuotes:     opts.StripQuotes,
+	}, nil
+}
+
+func (p *IniParser) Run(
+	data []byte,
+) (
+	[]map[string]string,
+	error,
+) {
+
+	values := make(map[string]string)
+	failures := make([]string, 0)
+	section := ""
+
+	for i, line := range strings.Split(string(data), "\n") {
+
+		// Empty line or comment
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || p.isComment(trimmed) {
+			continue
+		}
+
+		// Section header
+		if strings.HasPrefix(trimmed, "[") {
+			if !strings.HasSuffix(trimmed, "]") {
+				failures = append(failures, fmt.Sprintf("line %d: section header is not closed", i+1))
+				continue
+			}
+			section = strings.TrimSpace(trimmed[1 : len(trimmed)-1])
+			continue
+		}
+
+		entries := strings.SplitN(trimmed, p.separator, 2)
+		if len(entries) == 1 {
+			failures = append(failures, fmt.Sprintf("line %d: separator is missing", i+1))
+			continue
+		}
+
+		key := strings.TrimSpace(entries[0])
+		if key == "" {
+			failures = append(failures, fmt.Sprintf("line %d: key is empty", i+1))
+			continue
+		}
+
+		value := strings.TrimSpace(entries[1])
+		if p.stripQuotes {
+			value = stripQuotes(value)
+		}
+
+		values[joinKey(section, key)] = value
+	}
+
+	// Report the failed lines along with the parsed values
+	if len(failures) > 0 {
+		return []map[string]string{values},
+			fmt.Errorf("%s: %s", logging.PARSE__INI_LINES_COULD_NOT_BE_PARSED, strings.Join(failures, "; "))
+	}
+
+	return []map[string]string{values}, nil
+}
+
+func (p *IniParser) isComment(
+	line string,
+) bool {
+	for _, prefix := range p.commentPrefixes {
+		if strings.HasPrefix(line, prefix) {
+			return true
+		}
+	}
+	return false
+}
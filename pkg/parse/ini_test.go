@@ -0,0 +1,56 @@
+package parse
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/logging"
+)
+
+func Test_IniSectionsAreFlattened(t *testing.T) {
+	p, err := New("ini", nil)
+	assert.Nil(t, err)
+
+	body := "; global settings\n" +
+		"version = 1.2\n" +
+		"\n" +
+		"[database]\n" +
+		"host = \"db.internal\"\n" +
+		"# the pool\n" +
+		"[database.pool]\n" +
+		"size=10\r\n"
+
+	records, err := p.Run([]byte(body))
+	assert.Nil(t, err)
+	assert.Equal(t, []map[string]string{{
+		"version":            "1.2",
+		"database.host":      "db.internal",
+		"database.pool.size": "10",
+	}}, records)
+}
+
+func Test_IniLinesCouldNotBeParsed(t *testing.T) {
+	p, err := New("ini", map[string]interface{}{
+		"separator":   ":",
+		"stripQuotes": false,
+	})
+	assert.Nil(t, err)
+
+	records, err := p.Run([]byte("[server\nport: '8080'\nmissing\n: empty\n"))
+	assert.Equal(t, []map[string]string{{"port": "'8080'"}}, records)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), logging.PARSE__INI_LINES_COULD_NOT_BE_PARSED)
+	assert.Contains(t, err.Error(), "line 1: section header is not closed")
+	assert.Contains(t, err.Error(), "line 3: separator is missing")
+	assert.Contains(t, err.Error(), "line 4: key is empty")
+}
+
+func Test_IniOptionsAreValidated(t *testing.T) {
+	_, err := New("ini", map[string]interface{}{"separator": ""})
+	assert.NotNil(t, err)
+	assert.Equal(t, logging.PARSE__INI_SEPARATOR_IS_EMPTY, err.Error())
+
+	_, err = New("ini", map[string]interface{}{"commentPrefixes": []interface{}{""}})
+	assert.NotNil(t, err)
+	assert.Equal(t, logging.PARSE__INI_COMMENT_PREFIX_IS_EMPTY, err.Error())
+}
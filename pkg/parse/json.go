@@ -0,0 +1,301 @@
+package parse
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/jmespath/go-jmespath"
+
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/logging"
+)
+
+func init() {
+	Register(Definition{
+		Type: "json",
+		Description: "JSON documents. Without extraction rules, the whole document is flattened " +
+			"into dotted keys like `queues.0.name`. Expressions starting with `$` are evaluated as " +
+			"JSONPath (root, child names, indexes and wildcards), all others as JMESPath.",
+		Options:    jsonExtractionOptions,
+		Structured: true,
+		New:        newJsonParser,
+	})
+}
+
+// Options of the types whose documents are decoded into the same
+// structure as JSON, e.g. YAML
+var jsonExtractionOptions = []Option{
+	{
+		Name: "fanOut",
+		Type: "string",
+		Description: "Expression which selects an array. Every element becomes a separate " +
+			"record, e.g. `$.queues[*]` creates one event per queue.",
+	},
+	{
+		Name: "attributes",
+		Type: "map",
+		Description: "Attribute names mapped to the expressions which extract their values. " +
+			"With `fanOut`, the expressions are evaluated against each element. Objects and " +
+			"arrays are flattened under the attribute name.",
+	},
+	{
+		Name: "shared",
+		Type: "map",
+		Description: "Attribute names mapped to expressions which are evaluated against the whole " +
+			"document and added to every record, e.g. the name of the broker for every queue. " +
+			"Attributes of the record take precedence.",
+	},
+}
+
+// Largest integer up to which float64 represents all integers
+const maxExactFloatInt = 1 << 53
+
+type jsonOptions struct {
+	FanOut     string            `yaml:"fanOut"`
+	Attributes map[string]string `yaml:"attributes"`
+	Shared     map[string]string `yaml:"shared"`
+}
+
+// Compiled JSONPath or JMESPath expression
+type jsonExpression interface {
+	Search(data interface{}) (interface{}, error)
+}
+
+type jsonAttribute struct {
+	name       string
+	expression jsonExpression
+}
+
+// Implements Parser interface
+type JsonParser struct {
+	fanOut     jsonExpression
+	attributes []jsonAttribute
+	shared     []jsonAttribute
+
+	// Whether any expression is evaluated as JMESPath
+	jmespath bool
+}
+
+func newJsonParser(
+	options map[string]interface{},
+) (
+	Parser,
+	error,
+) {
+	opts := jsonOptions{}
+	if err := DecodeOptions(options, &opts); err != nil {
+		return nil, err
+	}
+
+	p := &JsonParser{}
+
+	if opts.FanOut != "" {
+		expression, err := compileJsonExpression(opts.FanOut)
+		if err != nil {
+			return nil, err
+		}
+		p.fanOut = expression
+	}
+
+	expressions := []string{opts.FanOut}
+	for _, expression := range opts.Attributes {
+		expressions = append(expressions, expression)
+	}
+	for _, expression := range opts.Shared {
+		expressions = append(expressions, expression)
+	}
+	for _, expression := range expressions {
+		if expression != "" && !strings.HasPrefix(expression, "$") {
+			p.jmespath = true
+		}
+	}
+
+	attributes, err := compileJsonAttributes(opts.Attributes)
+	if err != nil {
+		return nil, err
+	}
+	p.attributes = attributes
+
+	shared, err := compileJsonAttributes(opts.Shared)
+	if err != nil {
+		return nil, err
+	}
+	p.shared = shared
+
+	return p, nil
+}
+
+func compileJsonAttributes(
+	expressions map[string]string,
+) (
+	[]jsonAttribute,
+	error,
+) {
+	// Sort the attributes to keep the reported errors stable
+	names := make([]string, 0, len(expressions))
+	for name := range expressions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	attributes := make([]jsonAttribute, 0, len(names))
+	for _, name := range names {
+		expression, err := compileJsonExpression(expressions[name])
+		if err != nil {
+			return nil, err
+		}
+		attributes = append(attributes, jsonAttribute{
+			name:       name,
+			expression: expression,
+		})
+	}
+	return attributes, nil
+}
+
+func compileJsonExpression(
+	expression string,
+) (
+	jsonExpression,
+	error,
+) {
+	if strings.HasPrefix(expression, "$") {
+		path, err := compileJsonPath(expression)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", logging.PARSE__JSON_EXPRESSION_IS_INVALID, err)
+		}
+		return path, nil
+	}
+
+	path, err := jmespath.Compile(expression)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", logging.PARSE__JSON_EXPRESSION_IS_INVALID, err)
+	}
+	return path, nil
+}
+
+func (p *JsonParser) Run(
+	data []byte,
+) (
+	[]map[string]string,
+	error,
+) {
+	// Keep the numbers as they are, float64 would round large integers
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, errors.New(logging.PARSE__JSON_BODY_IS_INVALID)
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, errors.New(logging.PARSE__JSON_BODY_IS_INVALID)
+	}
+
+	// JMESPath compares and computes numbers as float64 only
+	if p.jmespath {
+		doc = convertJsonNumbers(doc)
+	}
+
+	return p.extract(doc)
+}
+
+// Converts the numbers into float64 unless they are integers which
+// float64 cannot represent exactly, e.g. large ids
+func convertJsonNumbers(
+	value interface{},
+) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, val := range v {
+			v[key] = convertJsonNumbers(val)
+		}
+	case []interface{}:
+		for i, val := range v {
+			v[i] = convertJsonNumbers(val)
+		}
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			if i > maxExactFloatInt || i < -maxExactFloatInt {
+				return v
+			}
+			return float64(i)
+		}
+		if strings.ContainsAny(v.String(), ".eE") {
+			if f, err := v.Float64(); err == nil {
+				return f
+			}
+		}
+	}
+	return value
+}
+
+// Creates the records of a decoded document
+func (p *JsonParser) extract(
+	doc interface{},
+) (
+	[]map[string]string,
+	error,
+) {
+	// Select the elements which become separate records
+	elements := []interface{}{doc}
+	if p.fanOut != nil {
+		result, err := p.fanOut.Search(doc)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", logging.PARSE__JSON_FAN_OUT_COULD_NOT_BE_EVALUATED, err)
+		}
+		array, ok := result.([]interface{})
+		if !ok {
+			return nil, errors.New(logging.PARSE__JSON_FAN_OUT_IS_NOT_AN_ARRAY)
+		}
+		elements = array
+	}
+
+	// Extract the attributes which all records share
+	failures := make([]string, 0)
+	shared := make(map[string]string)
+	failures = extractJsonAttributes(p.shared, doc, shared, failures)
+
+	records := make([]map[string]string, 0, len(elements))
+	for _, element := range elements {
+		record := make(map[string]string, len(shared))
+		for key, value := range shared {
+			record[key] = value
+		}
+
+		if len(p.attributes) == 0 {
+			flatten("", element, record)
+		} else {
+			failures = extractJsonAttributes(p.attributes, element, record, failures)
+		}
+		records = append(records, record)
+	}
+
+	if len(failures) > 0 {
+		return records, fmt.Errorf("%s: %s", logging.PARSE__JSON_ATTRIBUTES_COULD_NOT_BE_EXTRACTED, strings.Join(failures, "; "))
+	}
+
+	return records, nil
+}
+
+// Adds the values of the attributes to the record and returns the
+// failures along with the given ones
+func extractJsonAttributes(
+	attributes []jsonAttribute,
+	data interface{},
+	record map[string]string,
+	failures []string,
+) []string {
+	for _, attribute := range attributes {
+		value, err := attribute.expression.Search(data)
+		if err != nil {
+			failures = append(failures, attribute.name+": "+err.Error())
+			continue
+		}
+		flatten(attribute.name, value, record)
+	}
+	return failures
+}
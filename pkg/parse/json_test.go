@@ -0,0 +1,165 @@
+package parse
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/logging"
+)
+
+const brokerStatus = `{
+  "broker": {"name": "broker-0", "uptime": 3600, "healthy": true},
+  "queues": [
+    {"name": "orders", "stats": {"depth": 12, "consumers": 3}},
+    {"name": "payments", "stats": {"depth": 0, "consumers": 1}}
+  ]
+}`
+
+func Test_JsonIsFlattened(t *testing.T) {
+	p, err := New("json", nil)
+	assert.Nil(t, err)
+
+	records, err := p.Run([]byte(brokerStatus))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(records))
+	assert.Equal(t, "broker-0", records[0]["broker.name"])
+	assert.Equal(t, "3600", records[0]["broker.uptime"])
+	assert.Equal(t, "true", records[0]["broker.healthy"])
+	assert.Equal(t, "payments", records[0]["queues.1.name"])
+	assert.Equal(t, "12", records[0]["queues.0.stats.depth"])
+}
+
+func Test_JsonIntegersKeepTheirPrecision(t *testing.T) {
+	body := `{"id": 9007199254740993, "ratio": 0.25, "jobs": [{"id": 1234567890123456789, "retries": 2}]}`
+
+	p, err := New("json", nil)
+	assert.Nil(t, err)
+
+	records, err := p.Run([]byte(body))
+	assert.Nil(t, err)
+	assert.Equal(t, "9007199254740993", records[0]["id"])
+	assert.Equal(t, "0.25", records[0]["ratio"])
+	assert.Equal(t, "1234567890123456789", records[0]["jobs.0.id"])
+
+	// JMESPath still compares the numbers
+	p, err = New("json", map[string]interface{}{
+		"fanOut": "jobs[?retries > `1`]",
+	})
+	assert.Nil(t, err)
+
+	records, err = p.Run([]byte(body))
+	assert.Nil(t, err)
+	assert.Equal(t, []map[string]string{{"id": "1234567890123456789", "retries": "2"}}, records)
+}
+
+func Test_JsonBodyIsInvalid(t *testing.T) {
+	p, err := New("json", nil)
+	assert.Nil(t, err)
+
+	records, err := p.Run([]byte("not json"))
+	assert.Nil(t, records)
+	assert.NotNil(t, err)
+	assert.Equal(t, logging.PARSE__JSON_BODY_IS_INVALID, err.Error())
+}
+
+func Test_JsonAttributesAreExtracted(t *testing.T) {
+	p, err := New("json", map[string]interface{}{
+		"attributes": map[string]interface{}{
+			"brokerName":     "$.broker.name",
+			"queueNames":     "queues[*].name",
+			"totalConsumers": "sum(queues[*].stats.consumers)",
+			"firstQueue":     "$.queues[0].stats",
+			"missing":        "$.broker.missing",
+		},
+	})
+	assert.Nil(t, err)
+
+	records, err := p.Run([]byte(brokerStatus))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(records))
+	assert.Equal(t, "broker-0", records[0]["brokerName"])
+	assert.Equal(t, "orders", records[0]["queueNames.0"])
+	assert.Equal(t, "payments", records[0]["queueNames.1"])
+	assert.Equal(t, "4", records[0]["totalConsumers"])
+	assert.Equal(t, "12", records[0]["firstQueue.depth"])
+	assert.NotContains(t, records[0], "missing")
+}
+
+func Test_JsonIsFannedOut(t *testing.T) {
+	for _, fanOut := range []string{"$.queues[*]", "queues"} {
+		p, err := New("json", map[string]interface{}{
+			"fanOut": fanOut,
+			"attributes": map[string]interface{}{
+				"queue": "$.name",
+				"depth": "stats.depth",
+			},
+		})
+		assert.Nil(t, err)
+
+		records, err := p.Run([]byte(brokerStatus))
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(records))
+		assert.Equal(t, "orders", records[0]["queue"])
+		assert.Equal(t, "12", records[0]["depth"])
+		assert.Equal(t, "payments", records[1]["queue"])
+		assert.Equal(t, "0", records[1]["depth"])
+	}
+}
+
+func Test_JsonSharedAttributesAreAddedToEveryRecord(t *testing.T) {
+	p, err := New("json", map[string]interface{}{
+		"fanOut": "$.queues[*]",
+		"attributes": map[string]interface{}{
+			"name":  "$.name",
+			"depth": "stats.depth",
+		},
+		"shared": map[string]interface{}{
+			"broker": "broker.name",
+			"name":   "$.broker.name",
+		},
+	})
+	assert.Nil(t, err)
+
+	records, err := p.Run([]byte(brokerStatus))
+	assert.Nil(t, err)
+	assert.Equal(t, []map[string]string{
+		{"broker": "broker-0", "name": "orders", "depth": "12"},
+		{"broker": "broker-0", "name": "payments", "depth": "0"},
+	}, records)
+}
+
+func Test_JsonFanOutIsNotAnArray(t *testing.T) {
+	p, err := New("json", map[string]interface{}{
+		"fanOut": "$.broker",
+	})
+	assert.Nil(t, err)
+
+	records, err := p.Run([]byte(brokerStatus))
+	assert.Nil(t, records)
+	assert.NotNil(t, err)
+	assert.Equal(t, logging.PARSE__JSON_FAN_OUT_IS_NOT_AN_ARRAY, err.Error())
+}
+
+func Test_JsonFanOutCouldNotBeEvaluated(t *testing.T) {
+	p, err := New("json", map[string]interface{}{
+		"fanOut": "abs(broker.name)",
+	})
+	assert.Nil(t, err)
+
+	records, err := p.Run([]byte(brokerStatus))
+	assert.Nil(t, records)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), logging.PARSE__JSON_FAN_OUT_COULD_NOT_BE_EVALUATED)
+}
+
+func Test_JsonExpressionIsInvalid(t *testing.T) {
+	for _, expression := range []string{"$..name", "$.queues[", "queues[?"} {
+		_, err := New("json", map[string]interface{}{
+			"attributes": map[string]interface{}{
+				"invalid": expression,
+			},
+		})
+		assert.NotNil(t, err, expression)
+		assert.Contains(t, err.Error(), logging.PARSE__JSON_EXPRESSION_IS_INVALID)
+	}
+}
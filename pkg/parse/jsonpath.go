@@ -0,0 +1,164 @@
+package parse
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Compiled JSONPath expression. Only the following subset is
+// supported: the root ($), child names (.name, ['name']), array
+// indexes ([0], [-1]) and wildcards (.*, [*]). If the path contains
+// a wildcard, the result is a list of all matched values.
+type jsonPath struct {
+	steps    []jsonPathStep
+	wildcard bool
+}
+
+type jsonPathStep struct {
+	name     string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+func compileJsonPath(
+	expression string,
+) (
+	*jsonPath,
+	error,
+) {
+	if !strings.HasPrefix(expression, "$") {
+		return nil, fmt.Errorf("path must start with $")
+	}
+
+	p := &jsonPath{}
+	rest := expression[1:]
+	for rest != "" {
+		var step jsonPathStep
+
+		switch {
+		case strings.HasPrefix(rest, ".."):
+			return nil, fmt.Errorf("recursive descent is not supported")
+
+		case strings.HasPrefix(rest, ".*"):
+			step.wildcard = true
+			rest = rest[2:]
+
+		case strings.HasPrefix(rest, "."):
+			end := strings.IndexAny(rest[1:], ".[")
+			if end == -1 {
+				end = len(rest) - 1
+			}
+			step.name = rest[1 : end+1]
+			if step.name == "" {
+				return nil, fmt.Errorf("empty name in %s", expression)
+			}
+			rest = rest[end+1:]
+
+		case strings.HasPrefix(rest, "["):
+			end := strings.Index(rest, "]")
+			if end == -1 {
+				return nil, fmt.Errorf("missing ] in %s", expression)
+			}
+			selector := strings.TrimSpace(rest[1:end])
+			rest = rest[end+1:]
+
+			switch {
+			case selector == "*":
+				step.wildcard = true
+			case len(selector) >= 2 && (selector[0] == '\'' || selector[0] == '"') && selector[len(selector)-1] == selector[0]:
+				step.name = selector[1 : len(selector)-1]
+			default:
+				index, err := strconv.Atoi(selector)
+				if err != nil {
+					return nil, fmt.Errorf("unsupported selector [%s]", selector)
+				}
+				step.index = index
+				step.isIndex = true
+			}
+
+		default:
+			return nil, fmt.Errorf("unexpected %q in %s", rest[0], expression)
+		}
+
+		if step.wildcard {
+			p.wildcard = true
+		}
+		p.steps = append(p.steps, step)
+	}
+
+	return p, nil
+}
+
+// Evaluates the path against decoded JSON data
+func (p *jsonPath) Search(
+	data interface{},
+) (
+	interface{},
+	error,
+) {
+	nodes := []interface{}{data}
+
+	for _, step := range p.steps {
+		next := make([]interface{}, 0, len(nodes))
+		for _, node := range nodes {
+			next = append(next, step.apply(node)...)
+		}
+		nodes = next
+	}
+
+	if p.wildcard {
+		return nodes, nil
+	}
+	if len(nodes) == 0 {
+		return nil, nil
+	}
+	return nodes[0], nil
+}
+
+func (s jsonPathStep) apply(
+	node interface{},
+) []interface{} {
+	switch v := node.(type) {
+	case map[string]interface{}:
+		if s.wildcard {
+			// Keep the order of the values stable
+			keys := make([]string, 0, len(v))
+			for key := range v {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+
+			values := make([]interface{}, 0, len(v))
+			for _, key := range keys {
+				values = append(values, v[key])
+			}
+			return values
+		}
+		if s.isIndex {
+			return nil
+		}
+		if val, ok := v[s.name]; ok {
+			return []interface{}{val}
+		}
+
+	case []interface{}:
+		if s.wildcard {
+			return v
+		}
+		if !s.isIndex {
+			return nil
+		}
+		index := s.index
+		if index < 0 {
+			index += len(v)
+		}
+		if index >= 0 && index < len(v) {
+			return []interface{}{v[index]}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,197 @@
+package parse
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/logging"
+)
+
+func init() {
+	Register(Definition{
+		Type:        "kvp",
+		Description: "Lines of key value pairs which are separated by a colon (`key: value`) or a configurable separator.",
+		Options: []Option{
+			{
+				Name:        "separator",
+				Type:        "string",
+				Default:     "`:`",
+				Description: "Separator between the key and the value. Only its first occurrence in a line is used.",
+			},
+			{
+				Name:        "commentPrefixes",
+				Type:        "list",
+				Default:     "`[]`",
+				Description: "Lines starting with one of these prefixes are ignored, e.g. `[\"#\", \"!\"]` for `.properties` files.",
+			},
+			{
+				Name:        "stripQuotes",
+				Type:        "bool",
+				Default:     "false",
+				Description: "Removes the surrounding single or double quotes of the values.",
+			},
+			{
+				Name:        "duplicateKeys",
+				Type:        "string",
+				Default:     "`last`",
+				Description: "What to do if a key occurs multiple times: `last` and `first` keep the corresponding value, `error` keeps the first value and reports the duplicates.",
+			},
+			{
+				Name:        "keyPrefix",
+				Type:        "string",
+				Default:     "",
+				Description: "Prefix which is added to all keys.",
+			},
+		},
+		New: newKvpParser,
+	})
+}
+
+const (
+	kvpDuplicateKeysLast  = "last"
+	kvpDuplicateKeysFirst = "first"
+	kvpDuplicateKeysError = "error"
+)
+
+type kvpOptions struct {
+	Separator       string   `yaml:"separator"`
+	CommentPrefixes []string `yaml:"commentPrefixes"`
+	StripQuotes     bool     `yaml:"stripQuotes"`
+	DuplicateKeys   string   `yaml:"duplicateKeys"`
+	KeyPrefix       string   `yaml:"keyPrefix"`
+}
+
+// Implements Parser interface
+type KvpParser struct {
+	separator       string
+	commentPrefixes []string
+	stripQuotes     bool
+	duplicateKeys   string
+	keyPrefix       string
+}
+
+func newKvpParser(
+	options map[string]interface{},
+) (
+	Parser,
+	error,
+) {
+	opts := kvpOptions{
+		Separator:     ":",
+		DuplicateKeys: kvpDuplicateKeysLast,
+	}
+	if err := DecodeOptions(options, &opts); err != nil {
+		return nil, err
+	}
+
+	if opts.Separator == "" {
+		return nil, errors.New(logging.PARSE__KVP_SEPARATOR_IS_EMPTY)
+	}
+
+	switch opts.DuplicateKeys {
+	case kvpDuplicateKeysLast, kvpDuplicateKeysFirst, kvpDuplicateKeysError:
+	default:
+		return nil, errors.New(logging.PARSE__KVP_DUPLICATE_KEYS_POLICY_IS_INVALID)
+	}
+
+	for _, prefix := range opts.CommentPrefixes {
+		if prefix == "" {
+			return nil, errors.New(logging.PARSE__KVP_COMMENT_PREFIX_IS_EMPTY)
+		}
+	}
+
+	return &KvpParser{
+		separator:       opts.Separator,
+		commentPrefixes: opts.CommentPrefixes,
+		stripQuotes:     opts.StripQuotes,
+		duplicateKeys:   opts.DuplicateKeys,
+		keyPrefix:       opts.KeyPrefix,
+	}, nil
+}
+
+func (p *KvpParser) Run(
+	data []byte,
+) (
+	[]map[string]string,
+	error,
+) {
+
+	values := make(map[string]string)
+	failures := make([]string, 0)
+
+	for i, line := range strings.Split(string(data), "\n") {
+
+		// Windows line endings
+		line = strings.TrimSuffix(line, "\r")
+
+		// Empty line or comment
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || p.isComment(trimmed) {
+			continue
+		}
+
+		entries := strings.SplitN(line, p.separator, 2)
+		if len(entries) == 1 {
+			failures = append(failures, fmt.Sprintf("line %d: separator is missing", i+1))
+			continue
+		}
+
+		key := strings.TrimSpace(entries[0])
+		if key == "" {
+			failures = append(failures, fmt.Sprintf("line %d: key is empty", i+1))
+			continue
+		}
+		key = p.keyPrefix + key
+
+		value := strings.TrimSpace(entries[1])
+		if p.stripQuotes {
+			value = stripQuotes(value)
+		}
+
+		if _, exists := values[key]; exists {
+			switch p.duplicateKeys {
+			case kvpDuplicateKeysFirst:
+				continue
+			case kvpDuplicateKeysError:
+				failures = append(failures, fmt.Sprintf("line %d: key %s is duplicated", i+1, key))
+				continue
+			}
+		}
+
+		values[key] = value
+	}
+
+	// Report the failed lines along with the parsed values
+	if len(failures) > 0 {
+		return []map[string]string{values},
+			fmt.Errorf("%s: %s", logging.PARSE__KVP_LINES_COULD_NOT_BE_PARSED, strings.Join(failures, "; "))
+	}
+
+	return []map[string]string{values}, nil
+}
+
+func (p *KvpParser) isComment(
+	line string,
+) bool {
+	for _, prefix := range p.commentPrefixes {
+		if strings.HasPrefix(line, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func stripQuotes(
+	value string,
+) string {
+	if len(value) < 2 {
+		return value
+	}
+
+	first, last := value[0], value[len(value)-1]
+	if (first == '"' || first == '\'') && first == last {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
@@ -0,0 +1,94 @@
+package parse
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/logging"
+)
+
+func Test_KvpDefaultsAreApplied(t *testing.T) {
+	p, err := New("kvp", nil)
+	assert.Nil(t, err)
+
+	records, err := p.Run([]byte("k1: v1\r\nk2:v2:with:colons\r\n\r\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(records))
+	assert.Equal(t, "v1", records[0]["k1"])
+	assert.Equal(t, "v2:with:colons", records[0]["k2"])
+}
+
+func Test_KvpPropertiesAreParsed(t *testing.T) {
+	p, err := New("kvp", map[string]interface{}{
+		"separator":       "=",
+		"commentPrefixes": []interface{}{"#", "!"},
+		"stripQuotes":     true,
+		"keyPrefix":       "app.",
+	})
+	assert.Nil(t, err)
+
+	body := "# comment = ignored\n" +
+		"! another comment\n" +
+		"db.url = \"jdbc:postgresql://db:5432/app\"\n" +
+		"db.user='admin'\n" +
+		"db.pool=10\n"
+
+	records, err := p.Run([]byte(body))
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(records[0]))
+	assert.Equal(t, "jdbc:postgresql://db:5432/app", records[0]["app.db.url"])
+	assert.Equal(t, "admin", records[0]["app.db.user"])
+	assert.Equal(t, "10", records[0]["app.db.pool"])
+}
+
+func Test_KvpDuplicateKeysPolicies(t *testing.T) {
+	body := []byte("k: first\nk: second\n")
+
+	p, err := New("kvp", nil)
+	assert.Nil(t, err)
+	records, err := p.Run(body)
+	assert.Nil(t, err)
+	assert.Equal(t, "second", records[0]["k"])
+
+	p, err = New("kvp", map[string]interface{}{"duplicateKeys": "first"})
+	assert.Nil(t, err)
+	records, err = p.Run(body)
+	assert.Nil(t, err)
+	assert.Equal(t, "first", records[0]["k"])
+
+	p, err = New("kvp", map[string]interface{}{"duplicateKeys": "error"})
+	assert.Nil(t, err)
+	records, err = p.Run(body)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "line 2")
+	assert.Equal(t, "first", records[0]["k"])
+}
+
+func Test_KvpFailedLinesAreReported(t *testing.T) {
+	p, err := New("kvp", nil)
+	assert.Nil(t, err)
+
+	records, err := p.Run([]byte("k1: v1\nbroken line\n: no key\n"))
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), logging.PARSE__KVP_LINES_COULD_NOT_BE_PARSED)
+	assert.Contains(t, err.Error(), "line 2")
+	assert.Contains(t, err.Error(), "line 3")
+
+	// The valid lines are still parsed
+	assert.Equal(t, 1, len(records[0]))
+	assert.Equal(t, "v1", records[0]["k1"])
+}
+
+func Test_KvpOptionsAreInvalid(t *testing.T) {
+	_, err := New("kvp", map[string]interface{}{"separator": ""})
+	assert.NotNil(t, err)
+	assert.Equal(t, logging.PARSE__KVP_SEPARATOR_IS_EMPTY, err.Error())
+
+	_, err = New("kvp", map[string]interface{}{"duplicateKeys": "merge"})
+	assert.NotNil(t, err)
+	assert.Equal(t, logging.PARSE__KVP_DUPLICATE_KEYS_POLICY_IS_INVALID, err.Error())
+
+	_, err = New("kvp", map[string]interface{}{"commentPrefixes": []interface{}{""}})
+	assert.NotNil(t, err)
+	assert.Equal(t, logging.PARSE__KVP_COMMENT_PREFIX_IS_EMPTY, err.Error())
+}
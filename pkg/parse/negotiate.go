@@ -0,0 +1,74 @@
+package parse
+
+import (
+	"mime"
+	"strings"
+)
+
+// Media types of the metric formats
+const (
+	mediaTypeJson        = "application/json"
+	mediaTypeOpenMetrics = "application/openmetrics-text"
+	mediaTypeText        = "text/plain"
+	mediaTypeProtobuf    = "application/vnd.google.protobuf"
+
+	// Protobuf message of the Prometheus exposition format
+	prometheusProtobufProto = "io.prometheus.client.MetricFamily"
+)
+
+// Parser which asks the endpoint for specific formats. The scraper
+// sends the returned value as the Accept header.
+type Negotiator interface {
+	Accept() string
+}
+
+// Parser which needs the Content-Type of the response to pick the
+// format of the body
+type ContentParser interface {
+	Parser
+	RunContent(data []byte, contentType string) ([]map[string]string, error)
+}
+
+// Runs the parser with the Content-Type of the response if it
+// supports it
+func RunContent(
+	p Parser,
+	data []byte,
+	contentType string,
+) (
+	[]map[string]string,
+	error,
+) {
+	if cp, ok := p.(ContentParser); ok {
+		return cp.RunContent(data, contentType)
+	}
+	return p.Run(data)
+}
+
+// Returns the media type and its parameters, e.g. the version of the
+// Prometheus text format. Invalid or empty content types return an
+// empty media type.
+func parseContentType(
+	contentType string,
+) (
+	string,
+	map[string]string,
+) {
+	if contentType == "" {
+		return "", map[string]string{}
+	}
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", map[string]string{}
+	}
+	return strings.ToLower(mediaType), params
+}
+
+func isPrometheusProtobuf(
+	mediaType string,
+	params map[string]string,
+) bool {
+	return mediaType == mediaTypeProtobuf &&
+		params["proto"] == prometheusProtobufProto &&
+		params["encoding"] == "delimited"
+}
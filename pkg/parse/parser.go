@@ -0,0 +1,41 @@
+package parse
+
+import (
+	"errors"
+	"fmt"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/logging"
+)
+
+// Converts the response body of an endpoint into records.
+// Every record is forwarded as a separate event. If some parts
+// of the body could not be parsed, the error is returned along
+// with the records which could be parsed.
+type Parser interface {
+	Run(data []byte) ([]map[string]string, error)
+}
+
+// Decodes the generic endpoint options into the option
+// object of a parser. Unknown options are rejected.
+func DecodeOptions(
+	options map[string]interface{},
+	out interface{},
+) error {
+	if len(options) == 0 {
+		return nil
+	}
+
+	bytes, err := yaml.Marshal(options)
+	if err != nil {
+		return errors.New(logging.PARSE__OPTIONS_COULD_NOT_BE_DECODED)
+	}
+
+	err = yaml.UnmarshalStrict(bytes, out)
+	if err != nil {
+		return fmt.Errorf("%s: %v", logging.PARSE__OPTIONS_COULD_NOT_BE_DECODED, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,361 @@
+package parse
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/logging"
+)
+
+func init() {
+	Register(Definition{
+		Type: "prometheus",
+		Description: "Metrics in the Prometheus text format, OpenMetrics or the Prometheus protobuf " +
+			"format. The formats are offered in the Accept header and the body is parsed according " +
+			"to the Content-Type of the response. Every sample becomes a separate record with its " +
+			"labels and the attributes `metricName`, `metricType` and `metricValue`, which is sent as " +
+			"a number. Histograms and summaries are split into their `_bucket`, `_sum` and `_count` samples.",
+		Options: []Option{
+			{
+				Name:    "metrics",
+				Type:    "list",
+				Default: "`[]`",
+				Description: "Patterns of the metric names which are kept, e.g. `[\"http_requests_*\"]`. " +
+					"A pattern matches the name of the sample or of its metric family. All metrics are kept if empty.",
+			},
+		},
+		New: newPrometheusParser,
+	})
+}
+
+const (
+	// Formats in the order of preference
+	prometheusAccept = mediaTypeProtobuf + ";proto=" + prometheusProtobufProto + ";encoding=delimited;q=0.7," +
+		mediaTypeOpenMetrics + ";version=1.0.0;q=0.6," +
+		mediaTypeText + ";version=0.0.4;q=0.5," +
+		"*/*;q=0.1"
+
+	// Attributes of a sample
+	prometheusMetricName  = "metricName"
+	prometheusMetricType  = "metricType"
+	prometheusMetricValue = "metricValue"
+
+	prometheusTypeUntyped = "untyped"
+)
+
+// Suffixes of the samples of a metric family, e.g. of histograms
+var prometheusSuffixes = []string{"_total", "_created", "_bucket", "_count", "_sum", "_gcount", "_gsum", "_info"}
+
+type prometheusOptions struct {
+	Metrics []string `yaml:"metrics"`
+}
+
+// Implements Parser, ContentParser, Negotiator and TypedParser
+// interfaces
+type PrometheusParser struct {
+	metrics []string
+}
+
+// Sample of a metric family
+type prometheusSample struct {
+	family string
+	name   string
+	typ    string
+	labels map[string]string
+	value  float64
+}
+
+func newPrometheusParser(
+	options map[string]interface{},
+) (
+	Parser,
+	error,
+) {
+	opts := prometheusOptions{}
+	if err := DecodeOptions(options, &opts); err != nil {
+		return nil, err
+	}
+
+	for _, pattern := range opts.Metrics {
+		if _, err := path.Match(pattern, ""); err != nil || pattern == "" {
+			return nil, fmt.Errorf("%s: %s", logging.PARSE__PROMETHEUS_METRIC_PATTERN_IS_INVALID, pattern)
+		}
+	}
+
+	return &PrometheusParser{
+		metrics: opts.Metrics,
+	}, nil
+}
+
+func (p *PrometheusParser) Accept() string {
+	return prometheusAccept
+}
+
+// Parses the body as Prometheus text format or OpenMetrics
+func (p *PrometheusParser) Run(
+	data []byte,
+) (
+	[]map[string]string,
+	error,
+) {
+	return p.RunContent(data, "")
+}
+
+func (p *PrometheusParser) RunContent(
+	data []byte,
+	contentType string,
+) (
+	[]map[string]string,
+	error,
+) {
+	var samples []prometheusSample
+	var err error
+
+	mediaType, params := parseContentType(contentType)
+	if isPrometheusProtobuf(mediaType, params) {
+		samples, err = parsePrometheusProtobuf(data)
+	} else {
+		samples, err = parsePrometheusText(data)
+	}
+
+	records := make([]map[string]string, 0, len(samples))
+	for _, sample := range samples {
+		if !p.keep(sample) {
+			continue
+		}
+		records = append(records, sample.record())
+	}
+
+	return records, err
+}
+
+// The values of the samples are always numbers
+func (p *PrometheusParser) Types() map[string]string {
+	return map[string]string{
+		prometheusMetricValue: ValueTypeNumber,
+	}
+}
+
+func (p *PrometheusParser) keep(
+	sample prometheusSample,
+) bool {
+	if len(p.metrics) == 0 {
+		return true
+	}
+	for _, pattern := range p.metrics {
+		if ok, _ := path.Match(pattern, sample.name); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, sample.family); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (s prometheusSample) record() map[string]string {
+	record := make(map[string]string, len(s.labels)+3)
+	for name, value := range s.labels {
+		record[name] = value
+	}
+	record[prometheusMetricName] = s.name
+	record[prometheusMetricType] = s.typ
+	record[prometheusMetricValue] = formatScalar(s.value)
+	return record
+}
+
+// Parses the Prometheus text format (0.0.4) and OpenMetrics. Both
+// only differ in details which are not relevant for the records,
+// e.g. the exemplars and the units.
+func parsePrometheusText(
+	data []byte,
+) (
+	[]prometheusSample,
+	error,
+) {
+	types := map[string]string{}
+	samples := make([]prometheusSample, 0)
+	failures := make([]string, 0)
+
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "#") {
+			fields := strings.Fields(line)
+
+			// End of an OpenMetrics body
+			if len(fields) == 2 && fields[1] == "EOF" {
+				break
+			}
+			if len(fields) >= 4 && fields[1] == "TYPE" {
+				types[fields[2]] = strings.ToLower(fields[3])
+			}
+			continue
+		}
+
+		sample, err := parsePrometheusSample(line)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("line %d: %v", i+1, err))
+			continue
+		}
+		sample.family, sample.typ = prometheusFamily(types, sample.name)
+		samples = append(samples, sample)
+	}
+
+	// Report the failed lines along with the parsed samples
+	if len(failures) > 0 {
+		return samples, fmt.Errorf("%s: %s", logging.PARSE__PROMETHEUS_LINES_COULD_NOT_BE_PARSED, strings.Join(failures, "; "))
+	}
+
+	return samples, nil
+}
+
+// Returns the metric family of a sample and its type
+func prometheusFamily(
+	types map[string]string,
+	name string,
+) (
+	string,
+	string,
+) {
+	if typ, ok := types[name]; ok {
+		return name, typ
+	}
+	for _, suffix := range prometheusSuffixes {
+		if !strings.HasSuffix(name, suffix) {
+			continue
+		}
+		family := strings.TrimSuffix(name, suffix)
+		if typ, ok := types[family]; ok {
+			return family, typ
+		}
+	}
+	return name, prometheusTypeUntyped
+}
+
+// Parses a line like `name{label="value"} 1.5 [timestamp] [# exemplar]`
+func parsePrometheusSample(
+	line string,
+) (
+	prometheusSample,
+	error,
+) {
+	sample := prometheusSample{
+		labels: map[string]string{},
+	}
+
+	end := strings.IndexAny(line, "{ \t")
+	if end == -1 {
+		return sample, fmt.Errorf("value is missing")
+	}
+	sample.name = line[:end]
+	if !isPrometheusName(sample.name) {
+		return sample, fmt.Errorf("invalid metric name %q", sample.name)
+	}
+
+	rest := line[end:]
+	if strings.HasPrefix(rest, "{") {
+		var err error
+		rest, err = parsePrometheusLabels(rest[1:], sample.labels)
+		if err != nil {
+			return sample, err
+		}
+	}
+
+	// Drop the exemplar of OpenMetrics
+	if i := strings.Index(rest, "#"); i != -1 {
+		rest = rest[:i]
+	}
+
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return sample, fmt.Errorf("value is missing")
+	}
+	value, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return sample, fmt.Errorf("invalid value %q", fields[0])
+	}
+	sample.value = value
+
+	return sample, nil
+}
+
+// Parses the labels after the opening brace and returns the rest of
+// the line after the closing brace
+func parsePrometheusLabels(
+	line string,
+	labels map[string]string,
+) (
+	string,
+	error,
+) {
+	for {
+		line = strings.TrimLeft(line, " \t")
+		if strings.HasPrefix(line, "}") {
+			return line[1:], nil
+		}
+
+		eq := strings.Index(line, "=")
+		if eq == -1 {
+			return "", fmt.Errorf("label value is missing")
+		}
+		name := strings.TrimSpace(line[:eq])
+		if !isPrometheusName(name) {
+			return "", fmt.Errorf("invalid label name %q", name)
+		}
+
+		line = strings.TrimLeft(line[eq+1:], " \t")
+		if !strings.HasPrefix(line, "\"") {
+			return "", fmt.Errorf("label value of %s is not quoted", name)
+		}
+
+		var value strings.Builder
+		i := 1
+		for ; i < len(line) && line[i] != '"'; i++ {
+			if line[i] == '\\' && i+1 < len(line) {
+				i++
+				switch line[i] {
+				case 'n':
+					value.WriteByte('\n')
+				default:
+					value.WriteByte(line[i])
+				}
+				continue
+			}
+			value.WriteByte(line[i])
+		}
+		if i == len(line) {
+			return "", fmt.Errorf("label value of %s is not closed", name)
+		}
+		labels[name] = value.String()
+
+		line = strings.TrimLeft(line[i+1:], " \t")
+		if strings.HasPrefix(line, ",") {
+			line = line[1:]
+		} else if !strings.HasPrefix(line, "}") {
+			return "", fmt.Errorf("labels are not closed")
+		}
+	}
+}
+
+func isPrometheusName(
+	name string,
+) bool {
+	if name == "" {
+		return false
+	}
+	for i, c := range name {
+		switch {
+		case c == '_' || c == ':' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z'):
+		case c >= '0' && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
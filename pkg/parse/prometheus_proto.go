@@ -0,0 +1,372 @@
+package parse
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/logging"
+)
+
+// Decoder of the Prometheus protobuf format: a sequence of
+// MetricFamily messages (io.prometheus.client, metrics.proto) which
+// are each prefixed with their length as varint. Only the fields
+// which are needed for the records are decoded, native histograms
+// are reduced to their count and sum.
+
+// Types of a MetricFamily
+var prometheusProtobufTypes = map[uint64]string{
+	0: "counter",
+	1: "gauge",
+	2: "summary",
+	3: prometheusTypeUntyped,
+	4: "histogram",
+	5: "gaugehistogram",
+}
+
+// Wire types of protobuf
+const (
+	protoVarint  = 0
+	protoFixed64 = 1
+	protoBytes   = 2
+	protoFixed32 = 5
+)
+
+type protoField struct {
+	number   uint64
+	wireType uint64
+	varint   uint64
+	bytes    []byte
+}
+
+// Reads the fields of a protobuf message one by one
+type protoReader struct {
+	data []byte
+}
+
+func (r *protoReader) varint() (
+	uint64,
+	error,
+) {
+	value, n := binary.Uvarint(r.data)
+	if n <= 0 {
+		return 0, errors.New("invalid varint")
+	}
+	r.data = r.data[n:]
+	return value, nil
+}
+
+func (r *protoReader) next() (
+	protoField,
+	error,
+) {
+	key, err := r.varint()
+	if err != nil {
+		return protoField{}, err
+	}
+
+	field := protoField{
+		number:   key >> 3,
+		wireType: key & 7,
+	}
+
+	switch field.wireType {
+	case protoVarint:
+		field.varint, err = r.varint()
+		return field, err
+
+	case protoFixed64:
+		if len(r.data) < 8 {
+			return field, errors.New("truncated fixed64")
+		}
+		field.varint = binary.LittleEndian.Uint64(r.data)
+		r.data = r.data[8:]
+
+	case protoFixed32:
+		if len(r.data) < 4 {
+			return field, errors.New("truncated fixed32")
+		}
+		field.varint = uint64(binary.LittleEndian.Uint32(r.data))
+		r.data = r.data[4:]
+
+	case protoBytes:
+		length, err := r.varint()
+		if err != nil {
+			return field, err
+		}
+		if uint64(len(r.data)) < length {
+			return field, errors.New("truncated message")
+		}
+		field.bytes = r.data[:length]
+		r.data = r.data[length:]
+
+	default:
+		return field, fmt.Errorf("unsupported wire type %d", field.wireType)
+	}
+
+	return field, nil
+}
+
+// Calls the function for every field of the message
+func readProto(
+	data []byte,
+	fn func(field protoField) error,
+) error {
+	r := &protoReader{data: data}
+	for len(r.data) > 0 {
+		field, err := r.next()
+		if err != nil {
+			return err
+		}
+		if err := fn(field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f protoField) double() float64 {
+	return math.Float64frombits(f.varint)
+}
+
+func parsePrometheusProtobuf(
+	data []byte,
+) (
+	[]prometheusSample,
+	error,
+) {
+	samples := make([]prometheusSample, 0)
+	r := &protoReader{data: data}
+	for len(r.data) > 0 {
+		length, err := r.varint()
+		if err != nil || uint64(len(r.data)) < length {
+			return samples, errors.New(logging.PARSE__PROMETHEUS_PROTOBUF_IS_INVALID)
+		}
+		message := r.data[:length]
+		r.data = r.data[length:]
+
+		familySamples, err := parsePrometheusFamily(message)
+		if err != nil {
+			return samples, fmt.Errorf("%s: %v", logging.PARSE__PROMETHEUS_PROTOBUF_IS_INVALID, err)
+		}
+		samples = append(samples, familySamples...)
+	}
+	return samples, nil
+}
+
+func parsePrometheusFamily(
+	data []byte,
+) (
+	[]prometheusSample,
+	error,
+) {
+	name := ""
+	typ := prometheusTypeUntyped
+	metrics := make([][]byte, 0)
+
+	err := readProto(data, func(field protoField) error {
+		switch field.number {
+		case 1:
+			name = string(field.bytes)
+		case 3:
+			if t, ok := prometheusProtobufTypes[field.varint]; ok {
+				typ = t
+			}
+		case 4:
+			metrics = append(metrics, field.bytes)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The metrics are decoded after the family since the fields may
+	// come in any order
+	samples := make([]prometheusSample, 0, len(metrics))
+	for _, metric := range metrics {
+		metricSamples, err := parsePrometheusMetric(name, typ, metric)
+		if err != nil {
+			return nil, err
+		}
+		samples = append(samples, metricSamples...)
+	}
+	return samples, nil
+}
+
+// Converts a metric into the samples of the text format
+func parsePrometheusMetric(
+	family string,
+	typ string,
+	data []byte,
+) (
+	[]prometheusSample,
+	error,
+) {
+	labels := map[string]string{}
+	var value float64
+	var count, sum float64
+	var buckets, quantiles [][2]float64
+
+	err := readProto(data, func(field protoField) error {
+		switch field.number {
+
+		// Label
+		case 1:
+			var labelName, labelValue string
+			err := readProto(field.bytes, func(f protoField) error {
+				switch f.number {
+				case 1:
+					labelName = string(f.bytes)
+				case 2:
+					labelValue = string(f.bytes)
+				}
+				return nil
+			})
+			labels[labelName] = labelValue
+			return err
+
+		// Gauge, counter and untyped
+		case 2, 3, 5:
+			return readProto(field.bytes, func(f protoField) error {
+				if f.number == 1 {
+					value = f.double()
+				}
+				return nil
+			})
+
+		// Summary
+		case 4:
+			return readProto(field.bytes, func(f protoField) error {
+				switch f.number {
+				case 1:
+					count = float64(f.varint)
+				case 2:
+					sum = f.double()
+				case 3:
+					quantile, err := readPrometheusPair(f.bytes, 1, 2)
+					quantiles = append(quantiles, quantile)
+					return err
+				}
+				return nil
+			})
+
+		// Histogram
+		case 7:
+			return readProto(field.bytes, func(f protoField) error {
+				switch f.number {
+				case 1:
+					count = float64(f.varint)
+				case 2:
+					sum = f.double()
+				case 4:
+					count = f.double()
+				case 3:
+					bucket, err := readPrometheusBucket(f.bytes)
+					buckets = append(buckets, bucket)
+					return err
+				}
+				return nil
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	newSample := func(name string, value float64, extra ...string) prometheusSample {
+		sampleLabels := make(map[string]string, len(labels)+1)
+		for k, v := range labels {
+			sampleLabels[k] = v
+		}
+		if len(extra) == 2 {
+			sampleLabels[extra[0]] = extra[1]
+		}
+		return prometheusSample{family: family, name: name, typ: typ, labels: sampleLabels, value: value}
+	}
+
+	switch typ {
+	case "summary":
+		samples := make([]prometheusSample, 0, len(quantiles)+2)
+		for _, q := range quantiles {
+			samples = append(samples, newSample(family, q[1], "quantile", formatPrometheusFloat(q[0])))
+		}
+		return append(samples,
+			newSample(family+"_sum", sum),
+			newSample(family+"_count", count)), nil
+
+	case "histogram", "gaugehistogram":
+		samples := make([]prometheusSample, 0, len(buckets)+3)
+		for _, b := range buckets {
+			samples = append(samples, newSample(family+"_bucket", b[1], "le", formatPrometheusFloat(b[0])))
+		}
+
+		// The +Inf bucket is implicit in protobuf
+		if len(buckets) == 0 || !math.IsInf(buckets[len(buckets)-1][0], 1) {
+			samples = append(samples, newSample(family+"_bucket", count, "le", "+Inf"))
+		}
+		return append(samples,
+			newSample(family+"_sum", sum),
+			newSample(family+"_count", count)), nil
+
+	default:
+		return []prometheusSample{newSample(family, value)}, nil
+	}
+}
+
+// Reads two double fields of a message, e.g. of a quantile
+func readPrometheusPair(
+	data []byte,
+	first uint64,
+	second uint64,
+) (
+	[2]float64,
+	error,
+) {
+	var pair [2]float64
+	err := readProto(data, func(f protoField) error {
+		switch f.number {
+		case first:
+			pair[0] = f.double()
+		case second:
+			pair[1] = f.double()
+		}
+		return nil
+	})
+	return pair, err
+}
+
+// Reads the upper bound and the cumulative count of a bucket
+func readPrometheusBucket(
+	data []byte,
+) (
+	[2]float64,
+	error,
+) {
+	var bucket [2]float64
+	err := readProto(data, func(f protoField) error {
+		switch f.number {
+		case 1:
+			bucket[1] = float64(f.varint)
+		case 2:
+			bucket[0] = f.double()
+		case 4:
+			bucket[1] = f.double()
+		}
+		return nil
+	})
+	return bucket, err
+}
+
+// Formats the bounds of buckets and quantiles like the text format
+func formatPrometheusFloat(
+	value float64,
+) string {
+	if math.IsInf(value, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
@@ -0,0 +1,213 @@
+package parse
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/logging"
+)
+
+const prometheusText = `# HELP http_requests_total The total number of requests.
+# TYPE http_requests_total counter
+http_requests_total{method="post",code="200"} 1027 1395066363000
+http_requests_total{method="post",code="400"}    3 1395066363000
+# TYPE request_duration_seconds histogram
+request_duration_seconds_bucket{le="0.5"} 24054
+request_duration_seconds_bucket{le="+Inf"} 144320
+request_duration_seconds_sum 53423
+request_duration_seconds_count 144320
+process_start_time_seconds 1.6e+09
+`
+
+const openMetricsText = `# TYPE http_requests counter
+# UNIT http_requests requests
+http_requests_total{path="/a\"b"} 10 # {trace_id="abc"} 1.0 1520879607.789
+http_requests_created{path="/a\"b"} 1520430000.123
+# TYPE up gauge
+up NaN
+# EOF
+ignored 1
+`
+
+func Test_PrometheusTextIsParsed(t *testing.T) {
+	p, err := New("prometheus", nil)
+	assert.Nil(t, err)
+
+	records, err := p.Run([]byte(prometheusText))
+	assert.Nil(t, err)
+	assert.Equal(t, []map[string]string{
+		{"metricName": "http_requests_total", "metricType": "counter", "metricValue": "1027", "method": "post", "code": "200"},
+		{"metricName": "http_requests_total", "metricType": "counter", "metricValue": "3", "method": "post", "code": "400"},
+		{"metricName": "request_duration_seconds_bucket", "metricType": "histogram", "metricValue": "24054", "le": "0.5"},
+		{"metricName": "request_duration_seconds_bucket", "metricType": "histogram", "metricValue": "144320", "le": "+Inf"},
+		{"metricName": "request_duration_seconds_sum", "metricType": "histogram", "metricValue": "53423"},
+		{"metricName": "request_duration_seconds_count", "metricType": "histogram", "metricValue": "144320"},
+		{"metricName": "process_start_time_seconds", "metricType": "untyped", "metricValue": "1600000000"},
+	}, records)
+
+	assert.Equal(t, map[string]string{"metricValue": ValueTypeNumber}, p.(TypedParser).Types())
+}
+
+func Test_OpenMetricsIsParsed(t *testing.T) {
+	p, err := New("prometheus", nil)
+	assert.Nil(t, err)
+
+	records, err := p.(ContentParser).RunContent([]byte(openMetricsText), "application/openmetrics-text; version=1.0.0; charset=utf-8")
+	assert.Nil(t, err)
+	assert.Equal(t, []map[string]string{
+		{"metricName": "http_requests_total", "metricType": "counter", "metricValue": "10", "path": "/a\"b"},
+		{"metricName": "http_requests_created", "metricType": "counter", "metricValue": "1520430000.123", "path": "/a\"b"},
+		{"metricName": "up", "metricType": "gauge", "metricValue": "NaN"},
+	}, records)
+}
+
+func Test_PrometheusMetricsAreFiltered(t *testing.T) {
+	p, err := New("prometheus", map[string]interface{}{
+		"metrics": []interface{}{"request_duration_*", "process_*"},
+	})
+	assert.Nil(t, err)
+
+	records, err := p.Run([]byte(prometheusText))
+	assert.Nil(t, err)
+	assert.Equal(t, 5, len(records))
+	assert.Equal(t, "request_duration_seconds_bucket", records[0]["metricName"])
+
+	_, err = New("prometheus", map[string]interface{}{
+		"metrics": []interface{}{"[invalid"},
+	})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), logging.PARSE__PROMETHEUS_METRIC_PATTERN_IS_INVALID)
+}
+
+func Test_PrometheusLinesCouldNotBeParsed(t *testing.T) {
+	p, err := New("prometheus", nil)
+	assert.Nil(t, err)
+
+	body := "up 1\nbroken{le=\"1\" 2\nmissing_value\n1invalid 1\ntemperature abc\n"
+	records, err := p.Run([]byte(body))
+	assert.Equal(t, 1, len(records))
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), logging.PARSE__PROMETHEUS_LINES_COULD_NOT_BE_PARSED)
+	for _, line := range []string{"line 2", "line 3", "line 4", "line 5"} {
+		assert.Contains(t, err.Error(), line)
+	}
+}
+
+func Test_PrometheusProtobufIsParsed(t *testing.T) {
+	counter := protoMessage(
+		protoString(1, "http_requests_total"),
+		protoVarintField(3, 0),
+		protoBytesField(4, protoMessage(
+			protoBytesField(1, protoMessage(protoString(1, "code"), protoString(2, "200"))),
+			protoBytesField(3, protoMessage(protoDouble(1, 1027))),
+		)),
+	)
+	histogram := protoMessage(
+		protoString(1, "request_duration_seconds"),
+		protoVarintField(3, 4),
+		protoBytesField(4, protoMessage(
+			protoBytesField(7, protoMessage(
+				protoVarintField(1, 144320),
+				protoDouble(2, 53423),
+				protoBytesField(3, protoMessage(protoVarintField(1, 24054), protoDouble(2, 0.5))),
+			)),
+		)),
+	)
+	summary := protoMessage(
+		protoString(1, "rpc_duration_seconds"),
+		protoVarintField(3, 2),
+		protoBytesField(4, protoMessage(
+			protoBytesField(4, protoMessage(
+				protoVarintField(1, 10),
+				protoDouble(2, 1.5),
+				protoBytesField(3, protoMessage(protoDouble(1, 0.99), protoDouble(2, 0.3))),
+			)),
+		)),
+	)
+
+	body := append(protoDelimited(counter), protoDelimited(histogram)...)
+	body = append(body, protoDelimited(summary)...)
+
+	p, err := New("prometheus", nil)
+	assert.Nil(t, err)
+
+	contentType := "application/vnd.google.protobuf; proto=io.prometheus.client.MetricFamily; encoding=delimited"
+	records, err := p.(ContentParser).RunContent(body, contentType)
+	assert.Nil(t, err)
+	assert.Equal(t, []map[string]string{
+		{"metricName": "http_requests_total", "metricType": "counter", "metricValue": "1027", "code": "200"},
+		{"metricName": "request_duration_seconds_bucket", "metricType": "histogram", "metricValue": "24054", "le": "0.5"},
+		{"metricName": "request_duration_seconds_bucket", "metricType": "histogram", "metricValue": "144320", "le": "+Inf"},
+		{"metricName": "request_duration_seconds_sum", "metricType": "histogram", "metricValue": "53423"},
+		{"metricName": "request_duration_seconds_count", "metricType": "histogram", "metricValue": "144320"},
+		{"metricName": "rpc_duration_seconds", "metricType": "summary", "metricValue": "0.3", "quantile": "0.99"},
+		{"metricName": "rpc_duration_seconds_sum", "metricType": "summary", "metricValue": "1.5"},
+		{"metricName": "rpc_duration_seconds_count", "metricType": "summary", "metricValue": "10"},
+	}, records)
+
+	// Truncated body
+	_, err = p.(ContentParser).RunContent(body[:len(body)-3], contentType)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), logging.PARSE__PROMETHEUS_PROTOBUF_IS_INVALID)
+}
+
+func protoMessage(
+	fields ...[]byte,
+) []byte {
+	message := make([]byte, 0)
+	for _, field := range fields {
+		message = append(message, field...)
+	}
+	return message
+}
+
+func protoVarintField(
+	number uint64,
+	value uint64,
+) []byte {
+	field := appendUvarint(nil, number<<3|protoVarint)
+	return appendUvarint(field, value)
+}
+
+func protoDouble(
+	number uint64,
+	value float64,
+) []byte {
+	field := appendUvarint(nil, number<<3|protoFixed64)
+	bits := make([]byte, 8)
+	binary.LittleEndian.PutUint64(bits, math.Float64bits(value))
+	return append(field, bits...)
+}
+
+func protoBytesField(
+	number uint64,
+	value []byte,
+) []byte {
+	field := appendUvarint(nil, number<<3|protoBytes)
+	field = appendUvarint(field, uint64(len(value)))
+	return append(field, value...)
+}
+
+func protoString(
+	number uint64,
+	value string,
+) []byte {
+	return protoBytesField(number, []byte(value))
+}
+
+func protoDelimited(
+	message []byte,
+) []byte {
+	return append(appendUvarint(nil, uint64(len(message))), message...)
+}
+
+func appendUvarint(
+	data []byte,
+	value uint64,
+) []byte {
+	buf := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutUvarint(buf, value)
+	return append(data, buf[:n]...)
+}
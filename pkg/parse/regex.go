@@ -0,0 +1,173 @@
+package parse
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/logging"
+)
+
+func init() {
+	Register(Definition{
+		Type: "regex",
+		Description: "Free-form text which is matched against regular expressions. " +
+			"The named capture groups of the patterns become the attribute keys and " +
+			"the captured texts become the values.",
+		Options: []Option{
+			{
+				Name:        "patterns",
+				Type:        "list",
+				Description: "Patterns which are applied to the response body. At least one is required.",
+			},
+			{
+				Name: "patterns[].regex",
+				Type: "string",
+				Description: "Regular expression (RE2 syntax) with at least one named capture " +
+					"group like `(?P<connections>\\d+)`.",
+			},
+			{
+				Name:    "patterns[].multiple",
+				Type:    "bool",
+				Default: "false",
+				Description: "If false, the first match is added to the common record. If true, " +
+					"every match becomes a separate record which also contains the common record.",
+			},
+		},
+		New: newRegexParser,
+	})
+}
+
+type regexPatternOptions struct {
+	Regex    string `yaml:"regex"`
+	Multiple bool   `yaml:"multiple"`
+}
+
+type regexOptions struct {
+	Patterns []regexPatternOptions `yaml:"patterns"`
+}
+
+type regexPattern struct {
+	regex    *regexp.Regexp
+	multiple bool
+}
+
+// Implements Parser interface
+type RegexParser struct {
+	patterns []regexPattern
+}
+
+func newRegexParser(
+	options map[string]interface{},
+) (
+	Parser,
+	error,
+) {
+	opts := regexOptions{}
+	if err := DecodeOptions(options, &opts); err != nil {
+		return nil, err
+	}
+
+	if len(opts.Patterns) == 0 {
+		return nil, errors.New(logging.PARSE__REGEX_PATTERN_IS_NOT_DEFINED)
+	}
+
+	p := &RegexParser{
+		patterns: make([]regexPattern, 0, len(opts.Patterns)),
+	}
+	for _, pattern := range opts.Patterns {
+		regex, err := regexp.Compile(pattern.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", logging.PARSE__REGEX_PATTERN_IS_INVALID, err)
+		}
+
+		if !hasNamedGroup(regex) {
+			return nil, fmt.Errorf("%s: %s", logging.PARSE__REGEX_PATTERN_HAS_NO_NAMED_GROUP, pattern.Regex)
+		}
+
+		p.patterns = append(p.patterns, regexPattern{
+			regex:    regex,
+			multiple: pattern.Multiple,
+		})
+	}
+
+	return p, nil
+}
+
+func hasNamedGroup(
+	regex *regexp.Regexp,
+) bool {
+	for _, name := range regex.SubexpNames() {
+		if name != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *RegexParser) Run(
+	data []byte,
+) (
+	[]map[string]string,
+	error,
+) {
+
+	// Values of the patterns which are matched once
+	common := make(map[string]string)
+
+	// Values of the patterns which are matched multiple times
+	multiples := make([]map[string]string, 0)
+
+	for _, pattern := range p.patterns {
+		if !pattern.multiple {
+			match := pattern.regex.FindSubmatch(data)
+			if match != nil {
+				addNamedGroups(common, pattern.regex, match)
+			}
+			continue
+		}
+
+		for _, match := range pattern.regex.FindAllSubmatch(data, -1) {
+			values := make(map[string]string)
+			addNamedGroups(values, pattern.regex, match)
+			multiples = append(multiples, values)
+		}
+	}
+
+	// Nothing is matched
+	if len(common) == 0 && len(multiples) == 0 {
+		return []map[string]string{}, nil
+	}
+
+	if len(multiples) == 0 {
+		return []map[string]string{common}, nil
+	}
+
+	// Every multiple match carries the common values as well
+	records := make([]map[string]string, 0, len(multiples))
+	for _, values := range multiples {
+		record := make(map[string]string, len(common)+len(values))
+		for key, val := range common {
+			record[key] = val
+		}
+		for key, val := range values {
+			record[key] = val
+		}
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+func addNamedGroups(
+	values map[string]string,
+	regex *regexp.Regexp,
+	match [][]byte,
+) {
+	for i, name := range regex.SubexpNames() {
+		if name == "" || match[i] == nil {
+			continue
+		}
+		values[name] = string(match[i])
+	}
+}
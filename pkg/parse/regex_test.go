@@ -0,0 +1,107 @@
+package parse
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/logging"
+)
+
+const nginxStubStatus = `Active connections: 291
+server accepts handled requests
+ 16630948 16630948 31070465
+Reading: 6 Writing: 179 Waiting: 106
+`
+
+const queueTable = `QUEUE    SIZE  CONSUMERS
+orders   12    3
+payments 0     1
+`
+
+func Test_RegexPatternIsNotDefined(t *testing.T) {
+	p, err := New("regex", nil)
+	assert.Nil(t, p)
+	assert.NotNil(t, err)
+	assert.Equal(t, logging.PARSE__REGEX_PATTERN_IS_NOT_DEFINED, err.Error())
+}
+
+func Test_RegexPatternIsInvalid(t *testing.T) {
+	p, err := New("regex", map[string]interface{}{
+		"patterns": []interface{}{
+			map[string]interface{}{"regex": "(?P<broken"},
+		},
+	})
+	assert.Nil(t, p)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), logging.PARSE__REGEX_PATTERN_IS_INVALID)
+}
+
+func Test_RegexPatternHasNoNamedGroup(t *testing.T) {
+	p, err := New("regex", map[string]interface{}{
+		"patterns": []interface{}{
+			map[string]interface{}{"regex": `Active connections: (\d+)`},
+		},
+	})
+	assert.Nil(t, p)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), logging.PARSE__REGEX_PATTERN_HAS_NO_NAMED_GROUP)
+}
+
+func Test_RegexSingleMatchesAreMerged(t *testing.T) {
+	p, err := New("regex", map[string]interface{}{
+		"patterns": []interface{}{
+			map[string]interface{}{"regex": `Active connections: (?P<activeConnections>\d+)`},
+			map[string]interface{}{"regex": `\s(?P<accepts>\d+) (?P<handled>\d+) (?P<requests>\d+)`},
+			map[string]interface{}{"regex": `Reading: (?P<reading>\d+) Writing: (?P<writing>\d+) Waiting: (?P<waiting>\d+)`},
+		},
+	})
+	assert.Nil(t, err)
+
+	records, err := p.Run([]byte(nginxStubStatus))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(records))
+	assert.Equal(t, "291", records[0]["activeConnections"])
+	assert.Equal(t, "16630948", records[0]["accepts"])
+	assert.Equal(t, "31070465", records[0]["requests"])
+	assert.Equal(t, "106", records[0]["waiting"])
+}
+
+func Test_RegexMultipleMatchesAreSeparateRecords(t *testing.T) {
+	p, err := New("regex", map[string]interface{}{
+		"patterns": []interface{}{
+			map[string]interface{}{"regex": `(?P<header>QUEUE)`},
+			map[string]interface{}{
+				"regex":    `(?m)^(?P<queue>[a-z]+)\s+(?P<size>\d+)\s+(?P<consumers>\d+)$`,
+				"multiple": true,
+			},
+		},
+	})
+	assert.Nil(t, err)
+
+	records, err := p.Run([]byte(queueTable))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(records))
+
+	assert.Equal(t, "orders", records[0]["queue"])
+	assert.Equal(t, "12", records[0]["size"])
+	assert.Equal(t, "3", records[0]["consumers"])
+	assert.Equal(t, "QUEUE", records[0]["header"])
+
+	assert.Equal(t, "payments", records[1]["queue"])
+	assert.Equal(t, "0", records[1]["size"])
+	assert.Equal(t, "1", records[1]["consumers"])
+	assert.Equal(t, "QUEUE", records[1]["header"])
+}
+
+func Test_RegexNothingIsMatched(t *testing.T) {
+	p, err := New("regex", map[string]interface{}{
+		"patterns": []interface{}{
+			map[string]interface{}{"regex": `Active connections: (?P<activeConnections>\d+)`},
+		},
+	})
+	assert.Nil(t, err)
+
+	records, err := p.Run([]byte("unrelated body"))
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(records))
+}
@@ -0,0 +1,99 @@
+package parse
+
+import (
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/logging"
+)
+
+// Describes an option which can be set under the
+// "options" block of an endpoint
+type Option struct {
+	Name        string
+	Type        string
+	Default     string
+	Description string
+}
+
+// Describes an endpoint type and how its parser is created
+type Definition struct {
+	// Name of the type which is used in the endpoint config
+	Type        string
+	Description string
+	Options     []Option
+
+	// Whether JSON documents can be parsed, so that the type can
+	// read the objects of the Kubernetes API
+	Structured bool
+
+	// Validates the endpoint options and creates the parser
+	New func(options map[string]interface{}) (Parser, error)
+}
+
+var registry = struct {
+	mux         *sync.RWMutex
+	definitions map[string]Definition
+}{
+	mux:         &sync.RWMutex{},
+	definitions: make(map[string]Definition),
+}
+
+// Registers an endpoint type. Custom parsers can be added by
+// calling it within the init function of their package.
+func Register(
+	def Definition,
+) {
+	registry.mux.Lock()
+	defer registry.mux.Unlock()
+
+	if def.Type == "" || def.New == nil {
+		panic("parse: type and constructor must be defined")
+	}
+	if _, exists := registry.definitions[def.Type]; exists {
+		panic("parse: type " + def.Type + " is already registered")
+	}
+	registry.definitions[def.Type] = def
+}
+
+// Returns the definition of the given endpoint type
+func Lookup(
+	typ string,
+) (
+	Definition,
+	bool,
+) {
+	registry.mux.RLock()
+	def, ok := registry.definitions[typ]
+	registry.mux.RUnlock()
+	return def, ok
+}
+
+// Returns the names of all registered endpoint types
+func Types() []string {
+	registry.mux.RLock()
+	types := make([]string, 0, len(registry.definitions))
+	for typ := range registry.definitions {
+		types = append(types, typ)
+	}
+	registry.mux.RUnlock()
+
+	sort.Strings(types)
+	return types
+}
+
+// Creates the parser for the given endpoint type and options
+func New(
+	typ string,
+	options map[string]interface{},
+) (
+	Parser,
+	error,
+) {
+	def, ok := Lookup(typ)
+	if !ok {
+		return nil, errors.New(logging.PARSE__TYPE_IS_NOT_REGISTERED)
+	}
+	return def.New(options)
+}
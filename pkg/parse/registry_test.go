@@ -0,0 +1,90 @@
+package parse
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/logging"
+)
+
+type testParser struct {
+	prefix string
+}
+
+func (p *testParser) Run(
+	data []byte,
+) (
+	[]map[string]string,
+	error,
+) {
+	return []map[string]string{
+		{
+			p.prefix + "body": string(data),
+		},
+	}, nil
+}
+
+func Test_TypeIsNotRegistered(t *testing.T) {
+	p, err := New("unknown", nil)
+	assert.Nil(t, p)
+	assert.NotNil(t, err)
+	assert.Equal(t, logging.PARSE__TYPE_IS_NOT_REGISTERED, err.Error())
+}
+
+func Test_CustomTypeIsRegistered(t *testing.T) {
+	Register(Definition{
+		Type: "test",
+		New: func(options map[string]interface{}) (Parser, error) {
+			opts := struct {
+				Prefix string `yaml:"prefix"`
+			}{}
+			if err := DecodeOptions(options, &opts); err != nil {
+				return nil, err
+			}
+			return &testParser{prefix: opts.Prefix}, nil
+		},
+	})
+	defer func() {
+		registry.mux.Lock()
+		delete(registry.definitions, "test")
+		registry.mux.Unlock()
+	}()
+
+	assert.Contains(t, Types(), "test")
+
+	p, err := New("test", map[string]interface{}{"prefix": "my."})
+	assert.Nil(t, err)
+	records, err := p.Run([]byte("value"))
+	assert.Nil(t, err)
+	assert.Equal(t, "value", records[0]["my.body"])
+}
+
+func Test_TypeIsRegisteredTwice(t *testing.T) {
+	assert.Panics(t, func() {
+		Register(Definition{
+			Type: "kvp",
+			New: func(map[string]interface{}) (Parser, error) {
+				return &KvpParser{}, nil
+			},
+		})
+	})
+}
+
+func Test_UnknownOptionIsRejected(t *testing.T) {
+	p, err := New("kvp", map[string]interface{}{"unknown": "value"})
+	assert.Nil(t, p)
+	assert.NotNil(t, err)
+}
+
+func Test_DocsAreUpToDate(t *testing.T) {
+	expected, err := ioutil.ReadFile("../../docs/parsers.md")
+	assert.Nil(t, err)
+
+	var actual bytes.Buffer
+	err = WriteDocs(&actual)
+	assert.Nil(t, err)
+	assert.Equal(t, string(expected), actual.String(),
+		"regenerate the docs with `go run . -parser-docs > docs/parsers.md`")
+}
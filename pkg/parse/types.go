@@ -0,0 +1,59 @@
+package parse
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Types which the values of attributes can be converted into. The
+// sinks send the values of numbers and bools as such, e.g. as JSON
+// numbers.
+const (
+	ValueTypeString = "string"
+	ValueTypeNumber = "number"
+	ValueTypeBool   = "bool"
+)
+
+// Parser which knows the types of some of its attributes. Attributes
+// which are not listed are strings.
+type TypedParser interface {
+	Types() map[string]string
+}
+
+func isValueType(
+	typ string,
+) bool {
+	switch typ {
+	case "", ValueTypeString, ValueTypeNumber, ValueTypeBool:
+		return true
+	}
+	return false
+}
+
+// Converts a value into the canonical format of the type so that
+// e.g. " 1.50" becomes "1.5" and "TRUE" becomes "true"
+func convertValue(
+	value string,
+	typ string,
+) (
+	string,
+	error,
+) {
+	switch typ {
+	case ValueTypeNumber:
+		num, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return "", fmt.Errorf("%q is not a number", value)
+		}
+		return strconv.FormatFloat(num, 'f', -1, 64), nil
+	case ValueTypeBool:
+		b, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return "", fmt.Errorf("%q is not a bool", value)
+		}
+		return strconv.FormatBool(b), nil
+	default:
+		return value, nil
+	}
+}
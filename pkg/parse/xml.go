@@ -0,0 +1,263 @@
+package parse
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/antchfx/xmlquery"
+	"github.com/antchfx/xpath"
+
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/logging"
+)
+
+func init() {
+	Register(Definition{
+		Type: "xml",
+		Description: "XML documents. Without extraction rules, the whole document is flattened " +
+			"into dotted keys like `status.queues.queue.0.name`, attributes of elements are " +
+			"added as `element.@attribute`. Values are extracted with XPath 1.0 expressions.",
+		Options: []Option{
+			{
+				Name: "fanOut",
+				Type: "string",
+				Description: "XPath expression which selects repeated elements. Every element becomes " +
+					"a separate record, e.g. `//queue` creates one event per queue. Expressions which do not " +
+					"select nodes, e.g. `count(//queue)`, are rejected.",
+			},
+			{
+				Name: "attributes",
+				Type: "map",
+				Description: "Attribute names mapped to the XPath expressions which extract their values, " +
+					"e.g. `@name` or `count(connection)`. With `fanOut`, the expressions are evaluated " +
+					"relative to each element. Multiple matched nodes are indexed under the attribute name.",
+			},
+			{
+				Name: "shared",
+				Type: "map",
+				Description: "Attribute names mapped to XPath expressions which are evaluated against the " +
+					"whole document and added to every record. Attributes of the record take precedence.",
+			},
+		},
+		New: newXmlParser,
+	})
+}
+
+type xmlOptions struct {
+	FanOut     string            `yaml:"fanOut"`
+	Attributes map[string]string `yaml:"attributes"`
+	Shared     map[string]string `yaml:"shared"`
+}
+
+type xmlAttribute struct {
+	name       string
+	expression *xpath.Expr
+}
+
+// Implements Parser interface
+type XmlParser struct {
+	fanOut     *xpath.Expr
+	attributes []xmlAttribute
+	shared     []xmlAttribute
+}
+
+func newXmlParser(
+	options map[string]interface{},
+) (
+	Parser,
+	error,
+) {
+	opts := xmlOptions{}
+	if err := DecodeOptions(options, &opts); err != nil {
+		return nil, err
+	}
+
+	p := &XmlParser{}
+
+	if opts.FanOut != "" {
+		expression, err := xpath.Compile(opts.FanOut)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", logging.PARSE__XML_EXPRESSION_IS_INVALID, err)
+		}
+
+		// The type of the result does not depend on the document, so
+		// an empty one tells whether the expression selects nodes
+		empty := &xmlquery.Node{Type: xmlquery.DocumentNode}
+		if _, ok := expression.Evaluate(xmlquery.CreateXPathNavigator(empty)).(*xpath.NodeIterator); !ok {
+			return nil, fmt.Errorf("%s: fan out does not select nodes", logging.PARSE__XML_EXPRESSION_IS_INVALID)
+		}
+		p.fanOut = expression
+	}
+
+	attributes, err := compileXmlAttributes(opts.Attributes)
+	if err != nil {
+		return nil, err
+	}
+	p.attributes = attributes
+
+	shared, err := compileXmlAttributes(opts.Shared)
+	if err != nil {
+		return nil, err
+	}
+	p.shared = shared
+
+	return p, nil
+}
+
+func compileXmlAttributes(
+	expressions map[string]string,
+) (
+	[]xmlAttribute,
+	error,
+) {
+	// Sort the attributes to keep the reported errors stable
+	names := make([]string, 0, len(expressions))
+	for name := range expressions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	attributes := make([]xmlAttribute, 0, len(names))
+	for _, name := range names {
+		expression, err := xpath.Compile(expressions[name])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s: %v", logging.PARSE__XML_EXPRESSION_IS_INVALID, name, err)
+		}
+		attributes = append(attributes, xmlAttribute{
+			name:       name,
+			expression: expression,
+		})
+	}
+	return attributes, nil
+}
+
+func (p *XmlParser) Run(
+	data []byte,
+) (
+	[]map[string]string,
+	error,
+) {
+	doc, err := xmlquery.Parse(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", logging.PARSE__XML_BODY_IS_INVALID, err)
+	}
+	if xmlquery.FindOne(doc, "/*") == nil {
+		return nil, errors.New(logging.PARSE__XML_BODY_IS_INVALID)
+	}
+
+	// Select the elements which become separate records
+	elements := []*xmlquery.Node{doc}
+	if p.fanOut != nil {
+		elements = make([]*xmlquery.Node, 0)
+		iterator := p.fanOut.Select(xmlquery.CreateXPathNavigator(doc))
+		for iterator.MoveNext() {
+			navigator := iterator.Current().(*xmlquery.NodeNavigator)
+			elements = append(elements, navigator.Current())
+		}
+	}
+
+	// Extract the attributes which all records share
+	shared := make(map[string]string)
+	for _, attribute := range p.shared {
+		extractXmlAttribute(attribute, doc, shared)
+	}
+
+	records := make([]map[string]string, 0, len(elements))
+	for _, element := range elements {
+		record := make(map[string]string, len(shared))
+		for key, value := range shared {
+			record[key] = value
+		}
+
+		if len(p.attributes) == 0 {
+			flattenXml("", element, record)
+		} else {
+			for _, attribute := range p.attributes {
+				extractXmlAttribute(attribute, element, record)
+			}
+		}
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// Adds the result of the expression to the record. Expressions which
+// do not match any node add nothing.
+func extractXmlAttribute(
+	attribute xmlAttribute,
+	node *xmlquery.Node,
+	record map[string]string,
+) {
+	switch result := attribute.expression.Evaluate(xmlquery.CreateXPathNavigator(node)).(type) {
+	case float64:
+		record[attribute.name] = strconv.FormatFloat(result, 'f', -1, 64)
+	case string:
+		record[attribute.name] = result
+	case bool:
+		record[attribute.name] = strconv.FormatBool(result)
+	case *xpath.NodeIterator:
+		values := make([]string, 0)
+		for result.MoveNext() {
+			values = append(values, strings.TrimSpace(result.Current().Value()))
+		}
+
+		switch len(values) {
+		case 0:
+		case 1:
+			record[attribute.name] = values[0]
+		default:
+			for i, value := range values {
+				record[attribute.name+"."+strconv.Itoa(i)] = value
+			}
+		}
+	}
+}
+
+// Flattens the elements into dotted keys. Repeated elements are
+// indexed like arrays in JSON.
+func flattenXml(
+	prefix string,
+	node *xmlquery.Node,
+	record map[string]string,
+) {
+	for _, attr := range node.Attr {
+		record[joinKey(prefix, "@"+attr.Name.Local)] = attr.Value
+	}
+
+	// Count the children per name to detect repeated elements
+	counts := map[string]int{}
+	text := ""
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		switch child.Type {
+		case xmlquery.ElementNode:
+			counts[child.Data]++
+		case xmlquery.TextNode, xmlquery.CharDataNode:
+			text += child.Data
+		}
+	}
+
+	if len(counts) == 0 {
+		if text = strings.TrimSpace(text); text != "" && prefix != "" {
+			record[prefix] = text
+		}
+		return
+	}
+
+	indexes := map[string]int{}
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type != xmlquery.ElementNode {
+			continue
+		}
+
+		key := joinKey(prefix, child.Data)
+		if counts[child.Data] > 1 {
+			key = joinKey(key, strconv.Itoa(indexes[child.Data]))
+			indexes[child.Data]++
+		}
+		flattenXml(key, child, record)
+	}
+}
@@ -0,0 +1,119 @@
+package parse
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/logging"
+)
+
+const servletStatus = `<?xml version="1.0" encoding="UTF-8"?>
+<status server="tomcat-0">
+  <jvm><memory free="120" total="512"/></jvm>
+  <connector name="http-8080">
+    <threadInfo maxThreads="200" currentThreadsBusy="12"/>
+    <requestInfo>4711</requestInfo>
+  </connector>
+  <connector name="ajp-8009">
+    <threadInfo maxThreads="100" currentThreadsBusy="0"/>
+    <requestInfo>0</requestInfo>
+  </connector>
+</status>`
+
+func Test_XmlIsFlattened(t *testing.T) {
+	p, err := New("xml", nil)
+	assert.Nil(t, err)
+
+	records, err := p.Run([]byte(servletStatus))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(records))
+	assert.Equal(t, "tomcat-0", records[0]["status.@server"])
+	assert.Equal(t, "120", records[0]["status.jvm.memory.@free"])
+	assert.Equal(t, "http-8080", records[0]["status.connector.0.@name"])
+	assert.Equal(t, "0", records[0]["status.connector.1.requestInfo"])
+}
+
+func Test_XmlAttributesAreExtracted(t *testing.T) {
+	p, err := New("xml", map[string]interface{}{
+		"attributes": map[string]interface{}{
+			"server":     "/status/@server",
+			"freeMemory": "number(//memory/@free)",
+			"connectors": "count(//connector)",
+			"busy":       "sum(//threadInfo/@currentThreadsBusy) > 0",
+			"names":      "//connector/@name",
+			"missing":    "//missing",
+		},
+	})
+	assert.Nil(t, err)
+
+	records, err := p.Run([]byte(servletStatus))
+	assert.Nil(t, err)
+	assert.Equal(t, []map[string]string{{
+		"server":     "tomcat-0",
+		"freeMemory": "120",
+		"connectors": "2",
+		"busy":       "true",
+		"names.0":    "http-8080",
+		"names.1":    "ajp-8009",
+	}}, records)
+}
+
+func Test_XmlIsFannedOut(t *testing.T) {
+	p, err := New("xml", map[string]interface{}{
+		"fanOut": "//connector",
+		"attributes": map[string]interface{}{
+			"connector":  "@name",
+			"busy":       "threadInfo/@currentThreadsBusy",
+			"requests":   "requestInfo",
+			"maxThreads": "threadInfo/@maxThreads",
+		},
+		"shared": map[string]interface{}{
+			"server": "/status/@server",
+		},
+	})
+	assert.Nil(t, err)
+
+	records, err := p.Run([]byte(servletStatus))
+	assert.Nil(t, err)
+	assert.Equal(t, []map[string]string{
+		{"server": "tomcat-0", "connector": "http-8080", "busy": "12", "requests": "4711", "maxThreads": "200"},
+		{"server": "tomcat-0", "connector": "ajp-8009", "busy": "0", "requests": "0", "maxThreads": "100"},
+	}, records)
+}
+
+func Test_XmlBodyIsInvalid(t *testing.T) {
+	p, err := New("xml", nil)
+	assert.Nil(t, err)
+
+	for _, body := range []string{"<status><open></status>", "not xml"} {
+		records, err := p.Run([]byte(body))
+		assert.Nil(t, records)
+		assert.NotNil(t, err, body)
+		assert.Contains(t, err.Error(), logging.PARSE__XML_BODY_IS_INVALID)
+	}
+}
+
+func Test_XpathExpressionIsInvalid(t *testing.T) {
+	_, err := New("xml", map[string]interface{}{
+		"fanOut": "//connector[",
+	})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), logging.PARSE__XML_EXPRESSION_IS_INVALID)
+
+	// The fan out has to select nodes
+	for _, fanOut := range []string{"count(//connector)", "name(/*)", "boolean(//connector)"} {
+		_, err = New("xml", map[string]interface{}{
+			"fanOut": fanOut,
+		})
+		assert.NotNil(t, err, fanOut)
+		assert.Contains(t, err.Error(), logging.PARSE__XML_EXPRESSION_IS_INVALID)
+	}
+
+	_, err = New("xml", map[string]interface{}{
+		"attributes": map[string]interface{}{
+			"invalid": "count(",
+		},
+	})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), logging.PARSE__XML_EXPRESSION_IS_INVALID)
+}
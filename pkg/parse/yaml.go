@@ -0,0 +1,121 @@
+package parse
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/logging"
+)
+
+func init() {
+	Register(Definition{
+		Type: "yaml",
+		Description: "YAML documents which are handled like JSON. Without extraction rules, the " +
+			"nesting is flattened into dotted keys like `database.pool.size`. Every document of a " +
+			"multi-document body (separated by `---`) is processed on its own.",
+		Options:    jsonExtractionOptions,
+		Structured: true,
+		New:        newYamlParser,
+	})
+}
+
+// Implements Parser interface
+type YamlParser struct {
+	json *JsonParser
+}
+
+func newYamlParser(
+	options map[string]interface{},
+) (
+	Parser,
+	error,
+) {
+	p, err := newJsonParser(options)
+	if err != nil {
+		return nil, err
+	}
+	return &YamlParser{
+		json: p.(*JsonParser),
+	}, nil
+}
+
+func (p *YamlParser) Run(
+	data []byte,
+) (
+	[]map[string]string,
+	error,
+) {
+	records := make([]map[string]string, 0)
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var doc interface{}
+		err := decoder.Decode(&doc)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", logging.PARSE__YAML_BODY_IS_INVALID, err)
+		}
+
+		// Skip empty documents
+		if doc == nil {
+			continue
+		}
+
+		docRecords, err := p.json.extract(normalizeYaml(doc))
+		records = append(records, docRecords...)
+		if err != nil {
+			return records, err
+		}
+	}
+
+	return records, nil
+}
+
+// Converts the decoded YAML into the types of decoded JSON so that
+// the JSON expressions can be evaluated against it
+func normalizeYaml(
+	value interface{},
+) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, val := range v {
+			m[fmt.Sprintf("%v", key)] = normalizeYaml(val)
+		}
+		return m
+	case []interface{}:
+		a := make([]interface{}, len(v))
+		for i, val := range v {
+			a[i] = normalizeYaml(val)
+		}
+		return a
+	case int:
+		return normalizeYamlInt(int64(v))
+	case int64:
+		return normalizeYamlInt(v)
+	case uint64:
+		if v <= 1<<53 {
+			return float64(v)
+		}
+		return strconv.FormatUint(v, 10)
+	default:
+		return v
+	}
+}
+
+// Integers which cannot be represented exactly as a float are kept
+// as strings, e.g. large ids
+func normalizeYamlInt(
+	v int64,
+) interface{} {
+	if v >= -1<<53 && v <= 1<<53 {
+		return float64(v)
+	}
+	return strconv.FormatInt(v, 10)
+}
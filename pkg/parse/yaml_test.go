@@ -0,0 +1,67 @@
+package parse
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/logging"
+)
+
+const configStatus = `database:
+  host: db.internal
+  pool:
+    size: 10
+    ratio: 0.75
+  replicas: [db-0, db-1]
+id: 9007199254740993
+enabled: true
+`
+
+func Test_YamlIsFlattened(t *testing.T) {
+	p, err := New("yaml", nil)
+	assert.Nil(t, err)
+
+	records, err := p.Run([]byte(configStatus))
+	assert.Nil(t, err)
+	assert.Equal(t, []map[string]string{{
+		"database.host":       "db.internal",
+		"database.pool.size":  "10",
+		"database.pool.ratio": "0.75",
+		"database.replicas.0": "db-0",
+		"database.replicas.1": "db-1",
+		"id":                  "9007199254740993",
+		"enabled":             "true",
+	}}, records)
+}
+
+func Test_YamlAttributesAreExtractedPerDocument(t *testing.T) {
+	p, err := New("yaml", map[string]interface{}{
+		"fanOut": "$.queues[*]",
+		"attributes": map[string]interface{}{
+			"queue": "name",
+			"depth": "depth",
+		},
+		"shared": map[string]interface{}{
+			"broker": "$.broker",
+		},
+	})
+	assert.Nil(t, err)
+
+	body := "broker: b-0\nqueues:\n  - name: orders\n    depth: 12\n---\n---\nbroker: b-1\nqueues:\n  - name: payments\n    depth: 0\n"
+	records, err := p.Run([]byte(body))
+	assert.Nil(t, err)
+	assert.Equal(t, []map[string]string{
+		{"broker": "b-0", "queue": "orders", "depth": "12"},
+		{"broker": "b-1", "queue": "payments", "depth": "0"},
+	}, records)
+}
+
+func Test_YamlBodyIsInvalid(t *testing.T) {
+	p, err := New("yaml", nil)
+	assert.Nil(t, err)
+
+	records, err := p.Run([]byte("database:\n  host: [db"))
+	assert.Nil(t, records)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), logging.PARSE__YAML_BODY_IS_INVALID)
+}
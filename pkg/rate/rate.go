@@ -0,0 +1,252 @@
+package rate
+
+import (
+	"encoding/json"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/config"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/parse"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/state"
+)
+
+const (
+	// Prefix of the state keys of the calculator
+	keyPrefix = "rate."
+
+	// Suffixes of the computed keys
+	rateSuffix  = ".rate"
+	deltaSuffix = ".delta"
+)
+
+// Counter values of the last scrape of an endpoint. The records are
+// stored by their identity.
+type endpointState struct {
+	Time    time.Time                     `json:"time"`
+	Records map[string]map[string]float64 `json:"records"`
+}
+
+// Adds the rates and deltas of the counters of the endpoints
+type Calculator struct {
+	config *config.Config
+	store  state.Store
+	now    func() time.Time
+}
+
+func NewCalculator(
+	cfg *config.Config,
+	store state.Store,
+) *Calculator {
+	return &Calculator{
+		config: cfg,
+		store:  store,
+		now:    time.Now,
+	}
+}
+
+// Adds "<key>.rate" (per second) and "<key>.delta" to the records.
+// Nothing is added on the first scrape since there is no previous
+// sample yet.
+func (c *Calculator) Run(
+	evs *config.EndpointValues,
+) *config.EndpointValues {
+
+	// Leave the state untouched if no endpoint has counters
+	if !c.isEnabled() {
+		return evs
+	}
+
+	data, err := c.store.Load()
+	if err != nil {
+		c.config.Logger.LogWithFields(logrus.ErrorLevel, err.Error(),
+			map[string]string{
+				"stateBackend": c.config.State.Backend,
+			})
+		return evs
+	}
+
+	now := c.now()
+	computed := config.NewEndpointValues()
+	for _, endpoint := range evs.GetEndpoints() {
+		records := evs.GetEndpointValues(endpoint)
+		if len(endpoint.Counters) == 0 {
+			computed.AddEndpointTypes(endpoint, evs.GetEndpointTypes(endpoint))
+			computed.AddEndpointValues(endpoint, records)
+			continue
+		}
+
+		key := state.EndpointKey(keyPrefix, endpoint)
+		last := decodeState(data[key])
+
+		current := endpointState{
+			Time:    now,
+			Records: make(map[string]map[string]float64, len(records)),
+		}
+		results := make([]map[string]string, 0, len(records))
+		types := map[string]string{}
+		for key, typ := range evs.GetEndpointTypes(endpoint) {
+			types[key] = typ
+		}
+		keys := state.RecordKeys(endpoint.Identity, records)
+		for i, record := range records {
+			lastRecord := last.Records[keys[i]]
+
+			result, counters := computeRecord(endpoint.Counters, record, lastRecord, now.Sub(last.Time))
+			results = append(results, result)
+			current.Records[keys[i]] = counters
+
+			// The computed keys are sent as numbers
+			for key := range counters {
+				for _, suffix := range []string{rateSuffix, deltaSuffix} {
+					if _, ok := result[key+suffix]; ok {
+						types[key+suffix] = parse.ValueTypeNumber
+					}
+				}
+			}
+		}
+
+		computed.AddEndpointTypes(endpoint, types)
+		computed.AddEndpointValues(endpoint, results)
+		data[key] = encodeState(current)
+	}
+
+	c.removeStaleStates(data)
+
+	err = c.store.Save(data)
+	if err != nil {
+		c.config.Logger.LogWithFields(logrus.ErrorLevel, err.Error(),
+			map[string]string{
+				"stateBackend": c.config.State.Backend,
+			})
+	}
+
+	return computed
+}
+
+func (c *Calculator) isEnabled() bool {
+	for _, endpoint := range c.config.Endpoints {
+		if len(endpoint.Counters) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Removes the states of the endpoints which have no counters
+// anymore so that the state does not grow
+func (c *Calculator) removeStaleStates(
+	data map[string]string,
+) {
+	keys := map[string]bool{}
+	for _, endpoint := range c.config.Endpoints {
+		if len(endpoint.Counters) > 0 {
+			keys[state.EndpointKey(keyPrefix, endpoint)] = true
+		}
+	}
+
+	for key := range data {
+		if strings.HasPrefix(key, keyPrefix) && !keys[key] {
+			delete(data, key)
+		}
+	}
+}
+
+// Returns the record with the computed keys and the current values
+// of its counters. The record is compared with the last one of the
+// same identity.
+func computeRecord(
+	counters []config.CounterInput,
+	record map[string]string,
+	lastRecord map[string]float64,
+	elapsed time.Duration,
+) (
+	map[string]string,
+	map[string]float64,
+) {
+	result := make(map[string]string, len(record))
+	for key, value := range record {
+		result[key] = value
+	}
+
+	current := map[string]float64{}
+	for key, value := range record {
+		counter := matchCounter(counters, key)
+		if counter == nil {
+			continue
+		}
+
+		// Non-numeric values cannot be counters
+		number, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			continue
+		}
+		current[key] = number
+
+		last, ok := lastRecord[key]
+		if !ok || elapsed <= 0 {
+			continue
+		}
+
+		// The counter is reset if it decreases, e.g. after a restart
+		// of the application. It has started from zero then.
+		delta := number - last
+		if delta < 0 {
+			delta = number
+		}
+
+		for _, compute := range counter.Compute {
+			switch compute {
+			case config.CounterComputeRate:
+				result[key+rateSuffix] = formatFloat(delta / elapsed.Seconds())
+			case config.CounterComputeDelta:
+				result[key+deltaSuffix] = formatFloat(delta)
+			}
+		}
+	}
+
+	return result, current
+}
+
+func matchCounter(
+	counters []config.CounterInput,
+	key string,
+) *config.CounterInput {
+	for i := range counters {
+		if ok, _ := path.Match(counters[i].Key, key); ok {
+			return &counters[i]
+		}
+	}
+	return nil
+}
+
+func formatFloat(
+	value float64,
+) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+func decodeState(
+	value string,
+) *endpointState {
+	s := &endpointState{}
+	if value == "" {
+		return s
+	}
+
+	// Start over if the state is corrupt
+	if err := json.Unmarshal([]byte(value), s); err != nil {
+		return &endpointState{}
+	}
+	return s
+}
+
+func encodeState(
+	s endpointState,
+) string {
+	value, _ := json.Marshal(s)
+	return string(value)
+}
@@ -0,0 +1,170 @@
+package rate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/config"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/logging"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/parse"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/state"
+)
+
+func Test_RatesAreComputedBetweenScrapes(t *testing.T) {
+	now := time.Now()
+	cfg := createConfig([]string{config.CounterComputeRate, config.CounterComputeDelta})
+	calculator := NewCalculator(cfg, state.NewMemoryStore())
+	calculator.now = func() time.Time { return now }
+
+	// Nothing is computed on the first scrape
+	evs := calculator.Run(createEndpointValues(cfg, map[string]string{
+		"requests_total": "100",
+		"bytes_total":    "1000",
+		"version":        "1",
+	}))
+	record := evs.GetEndpointValues(cfg.Endpoints[0])[0]
+	assert.Equal(t, "100", record["requests_total"])
+	_, ok := record["requests_total.rate"]
+	assert.False(t, ok)
+
+	now = now.Add(10 * time.Second)
+	evs = calculator.Run(createEndpointValues(cfg, map[string]string{
+		"requests_total": "150",
+		"bytes_total":    "3000",
+		"version":        "1",
+	}))
+	record = evs.GetEndpointValues(cfg.Endpoints[0])[0]
+	assert.Equal(t, "150", record["requests_total"])
+	assert.Equal(t, "5", record["requests_total.rate"])
+	assert.Equal(t, "50", record["requests_total.delta"])
+	assert.Equal(t, "200", record["bytes_total.rate"])
+	assert.Equal(t, "2000", record["bytes_total.delta"])
+
+	// Keys which are no counters are left untouched
+	_, ok = record["version.rate"]
+	assert.False(t, ok)
+
+	assert.Equal(t, map[string]string{
+		"requests_total.rate":  parse.ValueTypeNumber,
+		"requests_total.delta": parse.ValueTypeNumber,
+		"bytes_total.rate":     parse.ValueTypeNumber,
+		"bytes_total.delta":    parse.ValueTypeNumber,
+	}, evs.GetEndpointTypes(cfg.Endpoints[0]))
+}
+
+func Test_CounterResetIsHandled(t *testing.T) {
+	now := time.Now()
+	cfg := createConfig([]string{config.CounterComputeDelta})
+	calculator := NewCalculator(cfg, state.NewMemoryStore())
+	calculator.now = func() time.Time { return now }
+
+	calculator.Run(createEndpointValues(cfg, map[string]string{"requests_total": "100"}))
+
+	// The application has restarted and counted 20 requests since
+	now = now.Add(10 * time.Second)
+	evs := calculator.Run(createEndpointValues(cfg, map[string]string{"requests_total": "20"}))
+	record := evs.GetEndpointValues(cfg.Endpoints[0])[0]
+	assert.Equal(t, "20", record["requests_total.delta"])
+
+	// Only the configured computations are added
+	_, ok := record["requests_total.rate"]
+	assert.False(t, ok)
+}
+
+func Test_ChangingStringsKeepTheRecord(t *testing.T) {
+	now := time.Now()
+	cfg := createConfig([]string{config.CounterComputeRate})
+	calculator := NewCalculator(cfg, state.NewMemoryStore())
+	calculator.now = func() time.Time { return now }
+
+	calculator.Run(createEndpointValues(cfg, map[string]string{
+		"requests_total": "100",
+		"last_update":    now.Format(time.RFC3339),
+	}))
+
+	now = now.Add(10 * time.Second)
+	evs := calculator.Run(createEndpointValues(cfg, map[string]string{
+		"requests_total": "150",
+		"last_update":    now.Format(time.RFC3339),
+	}))
+	record := evs.GetEndpointValues(cfg.Endpoints[0])[0]
+	assert.Equal(t, "5", record["requests_total.rate"])
+}
+
+func Test_RecordsAreMatchedByIdentity(t *testing.T) {
+	now := time.Now()
+	cfg := createConfig([]string{config.CounterComputeDelta})
+	cfg.Endpoints[0].Identity = []string{"queue"}
+	calculator := NewCalculator(cfg, state.NewMemoryStore())
+	calculator.now = func() time.Time { return now }
+
+	evs := config.NewEndpointValues()
+	evs.AddEndpointValues(cfg.Endpoints[0], []map[string]string{
+		{"queue": "orders", "messages_total": "100"},
+		{"queue": "payments", "messages_total": "10"},
+	})
+	calculator.Run(evs)
+
+	// The records have swapped their order
+	now = now.Add(10 * time.Second)
+	evs = config.NewEndpointValues()
+	evs.AddEndpointValues(cfg.Endpoints[0], []map[string]string{
+		{"queue": "payments", "messages_total": "15"},
+		{"queue": "orders", "messages_total": "130"},
+	})
+	records := calculator.Run(evs).GetEndpointValues(cfg.Endpoints[0])
+	assert.Equal(t, "payments", records[0]["queue"])
+	assert.Equal(t, "5", records[0]["messages_total.delta"])
+	assert.Equal(t, "orders", records[1]["queue"])
+	assert.Equal(t, "30", records[1]["messages_total.delta"])
+}
+
+func Test_PreviousSampleIsPersisted(t *testing.T) {
+	now := time.Now()
+	cfg := createConfig([]string{config.CounterComputeRate})
+	store := state.NewMemoryStore()
+
+	// Every run of the cron job creates a new calculator
+	calculator := NewCalculator(cfg, store)
+	calculator.now = func() time.Time { return now }
+	calculator.Run(createEndpointValues(cfg, map[string]string{"requests_total": "0"}))
+
+	now = now.Add(time.Minute)
+	calculator = NewCalculator(cfg, store)
+	calculator.now = func() time.Time { return now }
+	evs := calculator.Run(createEndpointValues(cfg, map[string]string{"requests_total": "120"}))
+	record := evs.GetEndpointValues(cfg.Endpoints[0])[0]
+	assert.Equal(t, "2", record["requests_total.rate"])
+}
+
+func createConfig(
+	compute []string,
+) *config.Config {
+	return &config.Config{
+		State: &config.StateInput{Backend: config.StateBackendMemory},
+		Endpoints: []config.Endpoint{
+			{
+				Type: "kvp",
+				Name: "Metrics",
+				URL:  "metrics",
+				Counters: []config.CounterInput{
+					{
+						Key:     "*_total",
+						Compute: compute,
+					},
+				},
+			},
+		},
+		Logger: logging.NewLogger("ERROR"),
+	}
+}
+
+func createEndpointValues(
+	cfg *config.Config,
+	values map[string]string,
+) *config.EndpointValues {
+	evs := config.NewEndpointValues()
+	evs.AddEndpointValues(cfg.Endpoints[0], []map[string]string{values})
+	return evs
+}
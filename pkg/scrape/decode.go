@@ -0,0 +1,160 @@
+package scraper
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/logging"
+)
+
+// Encodings which are offered to the endpoints. They are decoded by
+// the scraper instead of the transport, so that the size limit
+// applies to the decompressed body.
+const acceptEncoding = "gzip, deflate, zstd"
+
+// Content types of pre-compressed files and their encodings
+var compressedContentTypes = map[string]string{
+	"application/gzip":   "gzip",
+	"application/x-gzip": "gzip",
+	"application/zstd":   "zstd",
+}
+
+// Reads the response body, decompresses it according to its
+// encodings and returns the content type of the decompressed body
+var readResponseBody = func(
+	res *http.Response,
+	maxBodySize int64,
+) (
+	[]byte,
+	string,
+	error,
+) {
+	contentType := res.Header.Get("Content-Type")
+	encodings := contentEncodings(res.Header.Values("Content-Encoding"))
+
+	// Files which are served compressed without a content encoding
+	if len(encodings) == 0 {
+		mediaType, _, _ := mime.ParseMediaType(contentType)
+		if encoding, ok := compressedContentTypes[strings.ToLower(mediaType)]; ok {
+			encodings = []string{encoding}
+			contentType = ""
+		}
+	}
+
+	body, err := decodeBody(res.Body, encodings, maxBodySize)
+	return body, contentType, err
+}
+
+// Returns the encodings in the order they were applied. The identity
+// encoding is dropped.
+func contentEncodings(
+	values []string,
+) []string {
+	encodings := make([]string, 0)
+	for _, value := range values {
+		for _, encoding := range strings.Split(value, ",") {
+			encoding = strings.ToLower(strings.TrimSpace(encoding))
+			if encoding == "" || encoding == "identity" {
+				continue
+			}
+			encodings = append(encodings, encoding)
+		}
+	}
+	return encodings
+}
+
+func decodeBody(
+	body io.Reader,
+	encodings []string,
+	maxBodySize int64,
+) (
+	[]byte,
+	error,
+) {
+	reader := body
+
+	// The last applied encoding is decoded first
+	for i := len(encodings) - 1; i >= 0; i-- {
+		decoder, err := newDecoder(encodings[i], reader)
+		if err != nil {
+			return nil, err
+		}
+		defer decoder.Close()
+		reader = decoder
+	}
+
+	// Read one byte more than allowed to detect bodies which exceed
+	// the limit without decompressing them entirely
+	data, err := ioutil.ReadAll(io.LimitReader(reader, maxBodySize+1))
+	if err != nil {
+		if len(encodings) > 0 {
+			return nil, fmt.Errorf("%s: %v", logging.SCRAPE__RESPONSE_BODY_COULD_NOT_BE_DECODED, err)
+		}
+		return nil, err
+	}
+	if int64(len(data)) > maxBodySize {
+		return nil, errors.New(logging.SCRAPE__RESPONSE_BODY_IS_TOO_LARGE)
+	}
+
+	return data, nil
+}
+
+func newDecoder(
+	encoding string,
+	reader io.Reader,
+) (
+	io.ReadCloser,
+	error,
+) {
+	switch encoding {
+	case "gzip", "x-gzip":
+		decoder, err := gzip.NewReader(reader)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", logging.SCRAPE__RESPONSE_BODY_COULD_NOT_BE_DECODED, err)
+		}
+		return decoder, nil
+
+	case "deflate":
+		return newDeflateDecoder(reader)
+
+	case "zstd":
+		decoder, err := zstd.NewReader(reader, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", logging.SCRAPE__RESPONSE_BODY_COULD_NOT_BE_DECODED, err)
+		}
+		return decoder.IOReadCloser(), nil
+
+	default:
+		return nil, fmt.Errorf("%s: %s", logging.SCRAPE__CONTENT_ENCODING_IS_NOT_SUPPORTED, encoding)
+	}
+}
+
+// Deflate is defined as zlib stream, but some servers send raw
+// deflate data. The zlib header tells them apart.
+func newDeflateDecoder(
+	reader io.Reader,
+) (
+	io.ReadCloser,
+	error,
+) {
+	buffered := bufio.NewReader(reader)
+	header, _ := buffered.Peek(2)
+	if len(header) == 2 && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		decoder, err := zlib.NewReader(buffered)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", logging.SCRAPE__RESPONSE_BODY_COULD_NOT_BE_DECODED, err)
+		}
+		return decoder, nil
+	}
+	return flate.NewReader(buffered), nil
+}
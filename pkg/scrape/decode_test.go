@@ -0,0 +1,135 @@
+package scraper
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/config"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/logging"
+)
+
+func Test_CompressedBodiesAreDecoded(t *testing.T) {
+	body := []byte("k1:v1\nk2:v2\n")
+
+	var gzipped bytes.Buffer
+	gw := gzip.NewWriter(&gzipped)
+	gw.Write(body)
+	gw.Close()
+
+	var zlibbed bytes.Buffer
+	zw := zlib.NewWriter(&zlibbed)
+	zw.Write(body)
+	zw.Close()
+
+	var deflated bytes.Buffer
+	fw, _ := flate.NewWriter(&deflated, flate.DefaultCompression)
+	fw.Write(body)
+	fw.Close()
+
+	encoder, _ := zstd.NewWriter(nil)
+	zstded := encoder.EncodeAll(body, nil)
+	encoder.Close()
+
+	var gzippedZstd bytes.Buffer
+	gw = gzip.NewWriter(&gzippedZstd)
+	gw.Write(zstded)
+	gw.Close()
+
+	tests := []struct {
+		name            string
+		contentEncoding string
+		contentType     string
+		data            []byte
+	}{
+		{name: "gzip", contentEncoding: "gzip", data: gzipped.Bytes()},
+		{name: "zlib deflate", contentEncoding: "deflate", data: zlibbed.Bytes()},
+		{name: "raw deflate", contentEncoding: "deflate", data: deflated.Bytes()},
+		{name: "zstd", contentEncoding: "zstd", data: zstded},
+		{name: "zstd and gzip", contentEncoding: "zstd, gzip", data: gzippedZstd.Bytes()},
+		{name: "gzip file", contentType: "application/gzip", data: gzipped.Bytes()},
+		{name: "identity", contentEncoding: "identity", data: body},
+	}
+
+	for _, test := range tests {
+		endpointServerMock := httptest.NewServer(http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, acceptEncoding, r.Header.Get("Accept-Encoding"))
+				if test.contentEncoding != "" {
+					w.Header().Set("Content-Encoding", test.contentEncoding)
+				}
+				if test.contentType != "" {
+					w.Header().Set("Content-Type", test.contentType)
+				}
+				w.WriteHeader(http.StatusOK)
+				w.Write(test.data)
+			}))
+
+		cfg := createConfig([]string{
+			endpointServerMock.URL,
+		})
+		scraper := NewScraper(cfg)
+		evs := scraper.Run()
+		endpointServerMock.Close()
+
+		values := evs.GetEndpointValues(cfg.Endpoints[0])
+		assert.Equal(t, []map[string]string{
+			{"k1": "v1", "k2": "v2"},
+		}, values, test.name)
+	}
+}
+
+func Test_BodySizeLimitAppliesToDecompressedBody(t *testing.T) {
+	var gzipped bytes.Buffer
+	gw := gzip.NewWriter(&gzipped)
+	gw.Write([]byte(strings.Repeat("k:v\n", 1024)))
+	gw.Close()
+
+	// The compressed body fits into the limit, the decompressed not
+	headers := http.Header{"Content-Encoding": []string{"gzip"}}
+	assert.Less(t, gzipped.Len(), 1024)
+
+	res := &http.Response{Header: headers}
+	res.Body = ioutil.NopCloser(bytes.NewReader(gzipped.Bytes()))
+	_, _, err := readResponseBody(res, 1024)
+	assert.NotNil(t, err)
+	assert.Equal(t, logging.SCRAPE__RESPONSE_BODY_IS_TOO_LARGE, err.Error())
+
+	res.Body = ioutil.NopCloser(bytes.NewReader(gzipped.Bytes()))
+	data, _, err := readResponseBody(res, 4096)
+	assert.Nil(t, err)
+	assert.Equal(t, 4096, len(data))
+
+	// Uncompressed bodies are limited as well
+	res = &http.Response{Header: http.Header{}, Body: ioutil.NopCloser(bytes.NewReader([]byte("k:v\n")))}
+	_, _, err = readResponseBody(res, 3)
+	assert.NotNil(t, err)
+	assert.Equal(t, logging.SCRAPE__RESPONSE_BODY_IS_TOO_LARGE, err.Error())
+}
+
+func Test_UnsupportedEncodingIsRejected(t *testing.T) {
+	res := &http.Response{
+		Header: http.Header{"Content-Encoding": []string{"br"}},
+		Body:   ioutil.NopCloser(bytes.NewReader([]byte("k:v\n"))),
+	}
+	_, _, err := readResponseBody(res, config.DefaultScrapeMaxBodySize)
+	assert.NotNil(t, err)
+	assert.Equal(t, logging.SCRAPE__CONTENT_ENCODING_IS_NOT_SUPPORTED+": br", err.Error())
+
+	// Corrupt bodies are reported as such
+	res = &http.Response{
+		Header: http.Header{"Content-Encoding": []string{"gzip"}},
+		Body:   ioutil.NopCloser(bytes.NewReader([]byte("k:v\n"))),
+	}
+	_, _, err = readResponseBody(res, config.DefaultScrapeMaxBodySize)
+	assert.NotNil(t, err)
+	assert.True(t, strings.HasPrefix(err.Error(), logging.SCRAPE__RESPONSE_BODY_COULD_NOT_BE_DECODED))
+}
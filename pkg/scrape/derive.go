@@ -0,0 +1,78 @@
+package scraper
+
+import (
+	"github.com/sirupsen/logrus"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/config"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/expr"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/logging"
+)
+
+// Adds the derived attributes of the endpoint to its records and
+// returns their types. An attribute which cannot be derived for a
+// record is left out and does not affect the other attributes.
+func (s *EndpointScraper) derive(
+	endpoint config.Endpoint,
+	records []map[string]string,
+) map[string]string {
+	types := map[string]string{}
+	for i := range endpoint.Derive {
+		derive := &endpoint.Derive[i]
+		expression, err := derive.GetExpression()
+		if err != nil {
+			s.logDeriveError(endpoint, *derive, err)
+			continue
+		}
+
+		for _, record := range records {
+			value, err := evaluate(expression, derive.Type, record)
+			if err != nil {
+				s.logDeriveError(endpoint, *derive, err)
+				continue
+			}
+			record[derive.Name] = value.String()
+
+			// Results of different kinds can only be sent as strings
+			kind := value.Kind().String()
+			if typ, ok := types[derive.Name]; ok && typ != kind {
+				kind = expr.KindString.String()
+			}
+			types[derive.Name] = kind
+		}
+	}
+	return types
+}
+
+func evaluate(
+	expression *expr.Expression,
+	kind string,
+	record map[string]string,
+) (
+	expr.Value,
+	error,
+) {
+	value, err := expression.Eval(record)
+	if err != nil || kind == "" {
+		return value, err
+	}
+
+	k, err := expr.ParseKind(kind)
+	if err != nil {
+		return expr.Value{}, err
+	}
+	return value.Convert(k)
+}
+
+func (s *EndpointScraper) logDeriveError(
+	endpoint config.Endpoint,
+	derive config.DeriveInput,
+	err error,
+) {
+	s.config.Logger.LogWithFields(logrus.ErrorLevel, logging.SCRAPE__ATTRIBUTE_COULD_NOT_BE_DERIVED,
+		map[string]string{
+			"endpointType": endpoint.Type,
+			"endpointName": endpoint.Name,
+			"endpointUrl":  endpoint.URL,
+			"deriveName":   derive.Name,
+			"error":        err.Error(),
+		})
+}
@@ -0,0 +1,67 @@
+package scraper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/config"
+)
+
+func Test_AttributesAreDerived(t *testing.T) {
+	cfg := createConfig([]string{"http://localhost"})
+	endpoint := cfg.Endpoints[0]
+	endpoint.Derive = []config.DeriveInput{
+		{Name: "pool_utilization", Expression: "active / max * 100"},
+		{Name: "healthy", Expression: "status == 'OK'"},
+		{Name: "pool_full", Expression: "pool_utilization >= 100"},
+		{Name: "broken", Expression: "active / missing"},
+		{Name: "active_flag", Expression: "active", Type: "bool"},
+	}
+
+	records := []map[string]string{
+		{"active": "5", "max": "10", "status": "OK"},
+		{"active": "10", "max": "10", "status": "DOWN"},
+	}
+
+	scraper := NewScraper(cfg)
+	types := scraper.derive(endpoint, records)
+
+	assert.Equal(t, "50", records[0]["pool_utilization"])
+	assert.Equal(t, "true", records[0]["healthy"])
+	assert.Equal(t, "false", records[0]["pool_full"])
+	assert.Equal(t, "100", records[1]["pool_utilization"])
+	assert.Equal(t, "false", records[1]["healthy"])
+	assert.Equal(t, "true", records[1]["pool_full"])
+
+	// Failing expressions do not affect the others
+	_, ok := records[0]["broken"]
+	assert.False(t, ok)
+	_, ok = records[0]["active_flag"]
+	assert.False(t, ok)
+
+	// The types of the results are passed on to the sinks
+	assert.Equal(t, map[string]string{
+		"pool_utilization": "number",
+		"healthy":          "bool",
+		"pool_full":        "bool",
+	}, types)
+}
+
+func Test_DerivedTypesOfMixedResultsAreStrings(t *testing.T) {
+	cfg := createConfig([]string{"http://localhost"})
+	endpoint := cfg.Endpoints[0]
+	endpoint.Derive = []config.DeriveInput{
+		{Name: "state", Expression: "if(active > 0, active, 'idle')"},
+	}
+
+	records := []map[string]string{
+		{"active": "5"},
+		{"active": "0"},
+	}
+
+	scraper := NewScraper(cfg)
+	types := scraper.derive(endpoint, records)
+	assert.Equal(t, "5", records[0]["state"])
+	assert.Equal(t, "idle", records[1]["state"])
+	assert.Equal(t, map[string]string{"state": "string"}, types)
+}
@@ -0,0 +1,102 @@
+package scraper
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/sirupsen/logrus"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/config"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/kube"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/logging"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/parse"
+)
+
+// Attributes which identify the pod of a record
+const (
+	execPodName      = "podName"
+	execPodNamespace = "podNamespace"
+)
+
+// Buffer which rejects writes beyond its limit
+type limitedBuffer struct {
+	bytes.Buffer
+	limit int64
+}
+
+func (b *limitedBuffer) Write(
+	data []byte,
+) (
+	int,
+	error,
+) {
+	if int64(b.Len()+len(data)) > b.limit {
+		return 0, errors.New(logging.SCRAPE__RESPONSE_BODY_IS_TOO_LARGE)
+	}
+	return b.Buffer.Write(data)
+}
+
+// Runs the command of the endpoint in all running pods which match
+// its selector. The output of every pod is parsed separately and its
+// records are marked with the pod.
+func (s *EndpointScraper) scrapeExec(
+	p parse.Parser,
+	endpoint config.Endpoint,
+) {
+	if !s.createKubeClient(endpoint) {
+		return
+	}
+
+	exec := endpoint.Exec
+	namespace := exec.Namespace
+	if namespace == "" {
+		namespace = s.kube.Namespace()
+	}
+
+	pods, err := s.kube.ListPods(namespace, exec.Selector, "")
+	if err != nil {
+		s.logSourceError(logging.SCRAPE__PODS_COULD_NOT_BE_LISTED, endpoint, err)
+		return
+	}
+
+	var records []map[string]string
+	running := 0
+	for _, pod := range pods {
+		if pod.Status.Phase != kube.PodPhaseRunning {
+			continue
+		}
+		running++
+
+		stdout := &limitedBuffer{limit: s.maxBodySize()}
+		err := s.kube.Exec(namespace, pod.Metadata.Name, exec.Container, exec.Command, stdout)
+		if err != nil {
+			s.config.Logger.LogWithFields(logrus.ErrorLevel, logging.SCRAPE__COMMAND_HAS_FAILED,
+				map[string]string{
+					"endpointType": endpoint.Type,
+					"endpointName": endpoint.Name,
+					"endpointUrl":  endpoint.URL,
+					"podName":      pod.Metadata.Name,
+					"error":        err.Error(),
+				})
+			continue
+		}
+
+		podRecords := s.parseRecords(p, endpoint, stdout.Bytes(), "")
+		for _, record := range podRecords {
+			record[execPodName] = pod.Metadata.Name
+			record[execPodNamespace] = namespace
+		}
+		records = append(records, podRecords...)
+	}
+
+	if running == 0 {
+		s.logSourceError(logging.SCRAPE__NO_RUNNING_POD_IS_FOUND, endpoint, nil)
+		return
+	}
+
+	// Keep the records of the pods which could be scraped
+	if records == nil {
+		return
+	}
+
+	s.addRecords(p, endpoint, records)
+}
@@ -0,0 +1,110 @@
+package scraper
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/websocket"
+
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/config"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/kube"
+)
+
+// API server which lists the pods and runs the commands of the exec
+// subresource with the WebSocket protocol
+func newExecApiServer(
+	pods string,
+	outputs map[string]string,
+) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/namespaces/cache/pods", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("labelSelector") != "app=redis" {
+			w.Write([]byte(`{"items":[]}`))
+			return
+		}
+		w.Write([]byte(pods))
+	})
+	mux.Handle("/api/v1/namespaces/cache/pods/", websocket.Server{
+		Handshake: func(config *websocket.Config, r *http.Request) error {
+			return nil
+		},
+		Handler: func(conn *websocket.Conn) {
+			pod := strings.Split(conn.Request().URL.Path, "/")[6]
+			command := strings.Join(conn.Request().URL.Query()["command"], " ")
+
+			output, ok := outputs[pod]
+			if !ok || command != "redis-cli info" {
+				websocket.Message.Send(conn, append([]byte{kube.ExecChannelError},
+					`{"status":"Failure","message":"command terminated with non-zero exit code"}`...))
+				return
+			}
+			websocket.Message.Send(conn, append([]byte{kube.ExecChannelStdout}, output...))
+			websocket.Message.Send(conn, append([]byte{kube.ExecChannelError}, `{"status":"Success"}`...))
+		},
+	})
+	return httptest.NewServer(mux)
+}
+
+func Test_CommandsAreExecutedInPods(t *testing.T) {
+	apiMock := newExecApiServer(`{"items":[`+
+		`{"metadata":{"name":"redis-0"},"status":{"phase":"Running"}},`+
+		`{"metadata":{"name":"redis-1"},"status":{"phase":"Running"}},`+
+		`{"metadata":{"name":"redis-2"},"status":{"phase":"Pending"}},`+
+		`{"metadata":{"name":"redis-3"},"status":{"phase":"Running"}}]}`,
+		map[string]string{
+			"redis-0": "# Server\r\nrole:master\r\n",
+			"redis-1": "# Server\r\nrole:replica\r\n",
+		})
+	defer apiMock.Close()
+
+	newDefaultClientMock := kube.NewDefaultClient
+	defer func() {
+		kube.NewDefaultClient = newDefaultClientMock
+	}()
+	kube.NewDefaultClient = func() (*kube.Client, error) {
+		return kube.NewClient(apiMock.URL, "", "cache", apiMock.Client()), nil
+	}
+
+	cfg := createConfig([]string{""})
+	cfg.Endpoints[0].Exec = &config.ExecInput{
+		Selector:  "app=redis",
+		Container: "redis",
+		Command:   []string{"redis-cli", "info"},
+	}
+	cfg.Endpoints[0].URL = cfg.Endpoints[0].Exec.URL()
+	scraper := NewScraper(cfg)
+	evs := scraper.Run()
+
+	// The failed command of redis-3 does not drop the other pods
+	assert.Equal(t, []map[string]string{
+		{"role": "master", "podName": "redis-0", "podNamespace": "cache"},
+		{"role": "replica", "podName": "redis-1", "podNamespace": "cache"},
+	}, evs.GetEndpointValues(cfg.Endpoints[0]))
+}
+
+func Test_ExecWithoutRunningPodsIsSkipped(t *testing.T) {
+	apiMock := newExecApiServer(`{"items":[]}`, nil)
+	defer apiMock.Close()
+
+	newDefaultClientMock := kube.NewDefaultClient
+	defer func() {
+		kube.NewDefaultClient = newDefaultClientMock
+	}()
+	kube.NewDefaultClient = func() (*kube.Client, error) {
+		return kube.NewClient(apiMock.URL, "", "cache", apiMock.Client()), nil
+	}
+
+	cfg := createConfig([]string{""})
+	cfg.Endpoints[0].Exec = &config.ExecInput{
+		Namespace: "cache",
+		Selector:  "app=redis",
+		Command:   []string{"redis-cli", "info"},
+	}
+	scraper := NewScraper(cfg)
+	evs := scraper.Run()
+
+	assert.Equal(t, 0, len(evs.Values))
+}
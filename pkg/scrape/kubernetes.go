@@ -0,0 +1,72 @@
+package scraper
+
+import (
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/config"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/kube"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/logging"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/parse"
+)
+
+// Attributes which identify the object of a record
+const (
+	objectKind      = "objectKind"
+	objectName      = "objectName"
+	objectNamespace = "objectNamespace"
+)
+
+// Creates the client of the Kubernetes API for the first endpoint
+// which needs it
+func (s *EndpointScraper) createKubeClient(
+	endpoint config.Endpoint,
+) bool {
+	if s.kube != nil {
+		return true
+	}
+
+	client, err := kube.NewDefaultClient()
+	if err != nil {
+		s.logSourceError(logging.SCRAPE__KUBE_CLIENT_COULD_NOT_BE_CREATED, endpoint, err)
+		return false
+	}
+	s.kube = client
+	return true
+}
+
+// Fetches the objects of the endpoint from the Kubernetes API. Every
+// object is parsed separately and its records are marked with the
+// object.
+func (s *EndpointScraper) scrapeKubernetes(
+	p parse.Parser,
+	endpoint config.Endpoint,
+) {
+	if !s.createKubeClient(endpoint) {
+		return
+	}
+
+	kubernetes := endpoint.Kubernetes
+	namespace := kubernetes.Namespace
+	if namespace == "" {
+		namespace = s.kube.Namespace()
+	}
+
+	objects, err := s.kube.GetObjects(kubernetes.APIVersion, kubernetes.Kind, namespace, kubernetes.Name, kubernetes.Selector)
+	if err != nil {
+		s.logSourceError(logging.SCRAPE__OBJECTS_COULD_NOT_BE_FETCHED, endpoint, err)
+		return
+	}
+
+	records := make([]map[string]string, 0)
+	for _, object := range objects {
+		objectRecords := s.parseRecords(p, endpoint, object.Raw, "application/json")
+		for _, record := range objectRecords {
+			record[objectKind] = kubernetes.Kind
+			record[objectName] = object.Metadata.Name
+			if object.Metadata.Namespace != "" {
+				record[objectNamespace] = object.Metadata.Namespace
+			}
+		}
+		records = append(records, objectRecords...)
+	}
+
+	s.addRecords(p, endpoint, records)
+}
@@ -0,0 +1,69 @@
+package scraper
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/config"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/kube"
+)
+
+func Test_KubernetesObjectsAreScraped(t *testing.T) {
+	apiMock := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/apis/apps/v1":
+				w.Write([]byte(`{"resources":[{"name":"deployments","kind":"Deployment","namespaced":true}]}`))
+			case "/apis/apps/v1/namespaces/shop/deployments":
+				w.Write([]byte(`{"items":[` +
+					`{"metadata":{"name":"cart","namespace":"shop"},"spec":{"replicas":3},"status":{"readyReplicas":2}},` +
+					`{"metadata":{"name":"checkout","namespace":"shop"},"spec":{"replicas":1},"status":{}}]}`))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+	defer apiMock.Close()
+
+	newDefaultClientMock := kube.NewDefaultClient
+	defer func() {
+		kube.NewDefaultClient = newDefaultClientMock
+	}()
+	kube.NewDefaultClient = func() (*kube.Client, error) {
+		return kube.NewClient(apiMock.URL, "", "shop", apiMock.Client()), nil
+	}
+
+	cfg := createConfig([]string{""})
+	cfg.Endpoints[0].Type = "json"
+	cfg.Endpoints[0].Options = map[string]interface{}{
+		"attributes": map[string]interface{}{
+			"replicas":      "spec.replicas",
+			"readyReplicas": "status.readyReplicas",
+		},
+	}
+	cfg.Endpoints[0].Kubernetes = &config.KubernetesInput{
+		APIVersion: "apps/v1",
+		Kind:       "Deployment",
+	}
+	cfg.Endpoints[0].URL = cfg.Endpoints[0].Kubernetes.URL()
+	scraper := NewScraper(cfg)
+	evs := scraper.Run()
+
+	assert.Equal(t, []map[string]string{
+		{
+			"replicas":        "3",
+			"readyReplicas":   "2",
+			"objectKind":      "Deployment",
+			"objectName":      "cart",
+			"objectNamespace": "shop",
+		},
+		{
+			"replicas":        "1",
+			"objectKind":      "Deployment",
+			"objectName":      "checkout",
+			"objectNamespace": "shop",
+		},
+	}, evs.GetEndpointValues(cfg.Endpoints[0]))
+}
@@ -1,5 +0,0 @@
-package scraper
-
-type Parser interface {
-	Run(data []byte) map[string]string
-}
@@ -1,30 +1,27 @@
 package scraper
 
 import (
-	"io"
-	"io/ioutil"
 	"net/http"
 	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/config"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/kube"
 	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/logging"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/parse"
 )
 
+// Time limit of scraping an endpoint
+const scrapeTimeout = 30 * time.Second
+
 // Object which is responsible for scraping
 type EndpointScraper struct {
 	config *config.Config
 	client *http.Client
 	evs    *config.EndpointValues
-}
 
-var readResponseBody = func(
-	body io.ReadCloser,
-) (
-	[]byte,
-	error,
-) {
-	return ioutil.ReadAll(body)
+	// Client of the Kubernetes API, created for the first exec
+	kube *kube.Client
 }
 
 // Creates new scraper for endpoints
@@ -32,8 +29,18 @@ func NewScraper(
 	cfg *config.Config,
 ) *EndpointScraper {
 
-	// Create HTTP client
-	client := http.Client{Timeout: time.Duration(30 * time.Second)}
+	// Create HTTP client which connects directly to the endpoints,
+	// even if a proxy is defined in the environment
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+
+	// Compressed bodies are decoded by the scraper to enforce the
+	// size limit on the decompressed body
+	transport.DisableCompression = true
+	client := http.Client{
+		Timeout:   scrapeTimeout,
+		Transport: transport,
+	}
 
 	evs := config.NewEndpointValues()
 
@@ -60,10 +67,10 @@ func (s *EndpointScraper) Run() *config.EndpointValues {
 				"endpointUrl":  endpoint.URL,
 			})
 
-		// Create HTTP request
-		req, err := http.NewRequest(http.MethodGet, endpoint.URL, nil)
+		// Create the parser which is registered for the endpoint type
+		p, err := parse.New(endpoint.Type, endpoint.Options)
 		if err != nil {
-			s.config.Logger.LogWithFields(logrus.ErrorLevel, logging.SCRAPE__HTTP_REQUEST_COULD_NOT_BE_CREATED,
+			s.config.Logger.LogWithFields(logrus.ErrorLevel, logging.SCRAPE__PARSER_COULD_NOT_BE_CREATED,
 				map[string]string{
 					"endpointType": endpoint.Type,
 					"endpointName": endpoint.Name,
@@ -73,60 +80,95 @@ func (s *EndpointScraper) Run() *config.EndpointValues {
 			continue
 		}
 
-		// Perform HTTP request
-		res, err := s.client.Do(req)
-		if err != nil {
-			s.config.Logger.LogWithFields(logrus.ErrorLevel, logging.SCRAPE__HTTP_REQUEST_HAS_FAILED,
-				map[string]string{
-					"endpointType": endpoint.Type,
-					"endpointName": endpoint.Name,
-					"endpointUrl":  endpoint.URL,
-					"error":        err.Error(),
-				})
+		// Commands run in every selected pod and are parsed per pod
+		if endpoint.GetScheme() == config.SourceSchemeExec {
+			s.scrapeExec(p, endpoint)
 			continue
 		}
-		defer res.Body.Close()
 
-		// Check if call was successful
-		if res.StatusCode != http.StatusOK {
-			s.config.Logger.LogWithFields(logrus.ErrorLevel, logging.SCRAPE__ENDPOINT_RETURNED_NOT_OK_STATUS,
-				map[string]string{
-					"endpointType": endpoint.Type,
-					"endpointName": endpoint.Name,
-					"endpointUrl":  endpoint.URL,
-				})
+		// Objects are fetched from the API and parsed per object
+		if endpoint.GetScheme() == config.SourceSchemeKubernetes {
+			s.scrapeKubernetes(p, endpoint)
 			continue
 		}
 
-		// Extract response body
-		body, err := readResponseBody(res.Body)
-		if err != nil {
-			s.config.Logger.LogWithFields(logrus.ErrorLevel, logging.SCRAPE__RESPONSE_BODY_COULD_NOT_BE_PARSED,
-				map[string]string{
-					"endpointType": endpoint.Type,
-					"endpointName": endpoint.Name,
-					"endpointUrl":  endpoint.URL,
-					"error":        err.Error(),
-				})
+		// Read the body from the source of the endpoint
+		body, contentType, ok := s.fetch(p, endpoint)
+		if !ok {
 			continue
 		}
 
-		// Parse response body
-		switch endpoint.Type {
-		case "kvp":
-			s.parse(&KvpParser{}, endpoint, body)
-		}
+		// Parse body
+		s.parse(p, endpoint, body, contentType)
 	}
 
 	return s.evs
 }
 
+// Returns the configured limit of the response bodies
+func (s *EndpointScraper) maxBodySize() int64 {
+	if s.config.Scrape == nil || s.config.Scrape.MaxBodySize <= 0 {
+		return config.DefaultScrapeMaxBodySize
+	}
+	return s.config.Scrape.MaxBodySize
+}
+
 func (s *EndpointScraper) parse(
-	p Parser,
+	p parse.Parser,
+	endpoint config.Endpoint,
+	data []byte,
+	contentType string,
+) {
+	records := s.parseRecords(p, endpoint, data, contentType)
+
+	// Keep the records which could be parsed
+	if records == nil {
+		return
+	}
+
+	s.addRecords(p, endpoint, records)
+}
+
+// Runs the parser and logs the failures. The records which could be
+// parsed are returned anyway.
+func (s *EndpointScraper) parseRecords(
+	p parse.Parser,
 	endpoint config.Endpoint,
 	data []byte,
+	contentType string,
+) []map[string]string {
+	records, err := parse.RunContent(p, data, contentType)
+	if err != nil {
+		s.config.Logger.LogWithFields(logrus.ErrorLevel, logging.SCRAPE__RESPONSE_BODY_COULD_NOT_BE_PARSED,
+			map[string]string{
+				"endpointType": endpoint.Type,
+				"endpointName": endpoint.Name,
+				"endpointUrl":  endpoint.URL,
+				"error":        err.Error(),
+			})
+	}
+	return records
+}
+
+func (s *EndpointScraper) addRecords(
+	p parse.Parser,
+	endpoint config.Endpoint,
+	records []map[string]string,
 ) {
-	s.evs.AddEndpointValues(endpoint, p.Run(data))
+	types := map[string]string{}
+	if tp, ok := p.(parse.TypedParser); ok {
+		for key, typ := range tp.Types() {
+			types[key] = typ
+		}
+	}
+
+	// Compute the derived attributes
+	for key, typ := range s.derive(endpoint, records) {
+		types[key] = typ
+	}
+
+	s.evs.AddEndpointValues(endpoint, records)
+	s.evs.AddEndpointTypes(endpoint, types)
 
 	s.config.Logger.LogWithFields(logrus.DebugLevel, "Endpoint values are parsed.",
 		map[string]string{
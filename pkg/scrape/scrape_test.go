@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -61,13 +62,14 @@ func Test_EndpointsAreScrapedSuccessfully(t *testing.T) {
 	assert.Equal(t, 2, len(evs.Values))
 
 	for endpoint, values := range scraper.evs.Values {
+		assert.Equal(t, 1, len(values))
 		if endpoint.URL == endpointServerMock1.URL {
-			assert.Equal(t, "v1", values["k1"])
-			assert.Equal(t, "v2", values["k2"])
+			assert.Equal(t, "v1", values[0]["k1"])
+			assert.Equal(t, "v2", values[0]["k2"])
 		}
 		if endpoint.URL == endpointServerMock2.URL {
-			assert.Equal(t, "v3", values["k3"])
-			assert.Equal(t, "v4", values["k4"])
+			assert.Equal(t, "v3", values[0]["k3"])
+			assert.Equal(t, "v4", values[0]["k4"])
 		}
 	}
 }
@@ -94,3 +96,30 @@ func createConfig(
 		Endpoints: eps,
 	}
 }
+
+func Test_ContentIsNegotiated(t *testing.T) {
+	endpointServerMock := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if !strings.Contains(r.Header.Get("Accept"), "application/openmetrics-text") {
+				w.WriteHeader(http.StatusNotAcceptable)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/openmetrics-text; version=1.0.0")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte("# TYPE up gauge\nup 1\n# EOF\n"))
+		}))
+	defer endpointServerMock.Close()
+
+	cfg := createConfig([]string{
+		endpointServerMock.URL,
+	})
+	cfg.Endpoints[0].Type = "auto"
+	scraper := NewScraper(cfg)
+	evs := scraper.Run()
+
+	values := evs.GetEndpointValues(cfg.Endpoints[0])
+	assert.Equal(t, []map[string]string{
+		{"metricName": "up", "metricType": "gauge", "metricValue": "1"},
+	}, values)
+}
@@ -0,0 +1,231 @@
+package scraper
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/config"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/logging"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/parse"
+)
+
+// Extensions of compressed files and their encodings
+var compressedFileExtensions = map[string]string{
+	".gz":  "gzip",
+	".zst": "zstd",
+}
+
+// Reads the body of the endpoint from the source which its URL
+// refers to and returns its content type. Failures are logged.
+func (s *EndpointScraper) fetch(
+	p parse.Parser,
+	endpoint config.Endpoint,
+) (
+	[]byte,
+	string,
+	bool,
+) {
+	switch endpoint.GetScheme() {
+	case config.SourceSchemeFile:
+		return s.fetchFile(endpoint)
+	case config.SourceSchemeTcp, config.SourceSchemeUnix:
+		return s.fetchSocket(endpoint)
+	default:
+		return s.fetchHttp(p, endpoint)
+	}
+}
+
+func (s *EndpointScraper) fetchHttp(
+	p parse.Parser,
+	endpoint config.Endpoint,
+) (
+	[]byte,
+	string,
+	bool,
+) {
+	// Create HTTP request
+	req, err := http.NewRequest(http.MethodGet, endpoint.URL, nil)
+	if err != nil {
+		s.logSourceError(logging.SCRAPE__HTTP_REQUEST_COULD_NOT_BE_CREATED, endpoint, err)
+		return nil, "", false
+	}
+
+	// Ask for the formats which the parser prefers
+	if n, ok := p.(parse.Negotiator); ok {
+		req.Header.Set("Accept", n.Accept())
+	}
+
+	req.Header.Set("Accept-Encoding", acceptEncoding)
+
+	// Perform HTTP request
+	res, err := s.client.Do(req)
+	if err != nil {
+		s.logSourceError(logging.SCRAPE__HTTP_REQUEST_HAS_FAILED, endpoint, err)
+		return nil, "", false
+	}
+	defer res.Body.Close()
+
+	// Check if call was successful
+	if res.StatusCode != http.StatusOK {
+		s.logSourceError(logging.SCRAPE__ENDPOINT_RETURNED_NOT_OK_STATUS, endpoint, nil)
+		return nil, "", false
+	}
+
+	// Extract response body
+	body, contentType, err := readResponseBody(res, s.maxBodySize())
+	if err != nil {
+		s.logSourceError(logging.SCRAPE__RESPONSE_BODY_COULD_NOT_BE_READ, endpoint, err)
+		return nil, "", false
+	}
+
+	return body, contentType, true
+}
+
+// Reads a file, e.g. a status file which a sidecar writes into a
+// shared volume. The content type is derived from the extension.
+func (s *EndpointScraper) fetchFile(
+	endpoint config.Endpoint,
+) (
+	[]byte,
+	string,
+	bool,
+) {
+	u, err := url.Parse(endpoint.URL)
+	if err != nil {
+		s.logSourceError(logging.SCRAPE__FILE_COULD_NOT_BE_READ, endpoint, err)
+		return nil, "", false
+	}
+
+	file, err := os.Open(u.Path)
+	if err != nil {
+		s.logSourceError(logging.SCRAPE__FILE_COULD_NOT_BE_READ, endpoint, err)
+		return nil, "", false
+	}
+	defer file.Close()
+
+	// Compressed files are decoded like compressed responses
+	ext := strings.ToLower(filepath.Ext(u.Path))
+	encodings := []string{}
+	if encoding, ok := compressedFileExtensions[ext]; ok {
+		encodings = append(encodings, encoding)
+		ext = strings.ToLower(filepath.Ext(strings.TrimSuffix(u.Path, filepath.Ext(u.Path))))
+	}
+
+	body, err := decodeBody(file, encodings, s.maxBodySize())
+	if err != nil {
+		s.logSourceError(logging.SCRAPE__FILE_COULD_NOT_BE_READ, endpoint, err)
+		return nil, "", false
+	}
+
+	return body, mime.TypeByExtension(ext), true
+}
+
+// Connects to a tcp or unix socket, sends the request of the
+// endpoint and reads the response
+func (s *EndpointScraper) fetchSocket(
+	endpoint config.Endpoint,
+) (
+	[]byte,
+	string,
+	bool,
+) {
+	u, err := url.Parse(endpoint.URL)
+	if err != nil {
+		s.logSourceError(logging.SCRAPE__SOCKET_COULD_NOT_BE_CONNECTED, endpoint, err)
+		return nil, "", false
+	}
+
+	network, address := config.SourceSchemeTcp, u.Host
+	if endpoint.GetScheme() == config.SourceSchemeUnix {
+		network, address = config.SourceSchemeUnix, u.Path
+	}
+
+	conn, err := net.DialTimeout(network, address, scrapeTimeout)
+	if err != nil {
+		s.logSourceError(logging.SCRAPE__SOCKET_COULD_NOT_BE_CONNECTED, endpoint, err)
+		return nil, "", false
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(scrapeTimeout))
+
+	socket := endpoint.Socket
+	if socket == nil {
+		socket = &config.SocketInput{}
+	}
+
+	if socket.Send != "" {
+		if _, err := io.WriteString(conn, socket.Send); err != nil {
+			s.logSourceError(logging.SCRAPE__SOCKET_REQUEST_HAS_FAILED, endpoint, err)
+			return nil, "", false
+		}
+	}
+
+	body, err := readSocket(conn, socket.ReadUntil, s.maxBodySize())
+	if err != nil {
+		s.logSourceError(logging.SCRAPE__SOCKET_REQUEST_HAS_FAILED, endpoint, err)
+		return nil, "", false
+	}
+
+	return body, "", true
+}
+
+// Reads the response until it ends with the terminator or, if there
+// is none, until the connection is closed
+func readSocket(
+	reader io.Reader,
+	terminator string,
+	maxBodySize int64,
+) (
+	[]byte,
+	error,
+) {
+	if terminator == "" {
+		return decodeBody(reader, nil, maxBodySize)
+	}
+
+	var body bytes.Buffer
+	chunk := make([]byte, 4096)
+	for {
+		n, err := reader.Read(chunk)
+		body.Write(chunk[:n])
+		if int64(body.Len()) > maxBodySize {
+			return nil, errors.New(logging.SCRAPE__RESPONSE_BODY_IS_TOO_LARGE)
+		}
+		if bytes.HasSuffix(body.Bytes(), []byte(terminator)) {
+			return bytes.TrimSuffix(body.Bytes(), []byte(terminator)), nil
+		}
+		if err == io.EOF {
+			return nil, fmt.Errorf("connection is closed before %q is received", terminator)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (s *EndpointScraper) logSourceError(
+	message string,
+	endpoint config.Endpoint,
+	err error,
+) {
+	fields := map[string]string{
+		"endpointType": endpoint.Type,
+		"endpointName": endpoint.Name,
+		"endpointUrl":  endpoint.URL,
+	}
+	if err != nil {
+		fields["error"] = err.Error()
+	}
+	s.config.Logger.LogWithFields(logrus.ErrorLevel, message, fields)
+}
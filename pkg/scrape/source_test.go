@@ -0,0 +1,130 @@
+package scraper
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/config"
+)
+
+func Test_FilesAreScraped(t *testing.T) {
+	dir := t.TempDir()
+
+	statusPath := filepath.Join(dir, "status.json")
+	ioutil.WriteFile(statusPath, []byte(`{"status": "UP"}`), 0644)
+
+	var gzipped bytes.Buffer
+	gw := gzip.NewWriter(&gzipped)
+	gw.Write([]byte("k1:v1\n"))
+	gw.Close()
+	compressedPath := filepath.Join(dir, "status.properties.gz")
+	ioutil.WriteFile(compressedPath, gzipped.Bytes(), 0644)
+
+	cfg := createConfig([]string{
+		"file://" + statusPath,
+		"file://" + compressedPath,
+		"file://" + filepath.Join(dir, "missing.txt"),
+	})
+	cfg.Endpoints[0].Type = "auto"
+	scraper := NewScraper(cfg)
+	evs := scraper.Run()
+
+	assert.Equal(t, 2, len(evs.Values))
+	assert.Equal(t, []map[string]string{
+		{"status": "UP"},
+	}, evs.GetEndpointValues(cfg.Endpoints[0]))
+	assert.Equal(t, []map[string]string{
+		{"k1": "v1"},
+	}, evs.GetEndpointValues(cfg.Endpoints[1]))
+}
+
+func Test_TcpSocketIsScraped(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+
+	// Answers the stats command without closing the connection
+	go serveSocket(listener, "stats\r\n", "k1:v1\r\nk2:v2\r\nEND\r\n")
+
+	cfg := createConfig([]string{
+		"tcp://" + listener.Addr().String(),
+	})
+	cfg.Endpoints[0].Socket = &config.SocketInput{
+		Send:      "stats\r\n",
+		ReadUntil: "END\r\n",
+	}
+	scraper := NewScraper(cfg)
+	evs := scraper.Run()
+
+	assert.Equal(t, []map[string]string{
+		{"k1": "v1", "k2": "v2"},
+	}, evs.GetEndpointValues(cfg.Endpoints[0]))
+}
+
+func Test_UnixSocketIsScraped(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.sock")
+	listener, err := net.Listen("unix", path)
+	assert.Nil(t, err)
+	defer listener.Close()
+
+	// Closes the connection after the response
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		conn.Write([]byte("k1:v1\n"))
+		conn.Close()
+	}()
+
+	cfg := createConfig([]string{
+		"unix://" + path,
+	})
+	scraper := NewScraper(cfg)
+	evs := scraper.Run()
+
+	assert.Equal(t, []map[string]string{
+		{"k1": "v1"},
+	}, evs.GetEndpointValues(cfg.Endpoints[0]))
+}
+
+func Test_SocketResponseIsLimited(t *testing.T) {
+	_, err := readSocket(strings.NewReader("k1:v1\nk2:v2\nEND\n"), "END\n", 8)
+	assert.NotNil(t, err)
+
+	// The response must end with the terminator
+	_, err = readSocket(strings.NewReader("k1:v1\n"), "END\n", 1024)
+	assert.NotNil(t, err)
+
+	body, err := readSocket(strings.NewReader("k1:v1\nEND\n"), "END\n", 1024)
+	assert.Nil(t, err)
+	assert.Equal(t, "k1:v1\n", string(body))
+}
+
+func serveSocket(
+	listener net.Listener,
+	request string,
+	response string,
+) {
+	conn, err := listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil || line != request {
+		return
+	}
+	conn.Write([]byte(response))
+
+	// Keep the connection open until the scraper closes it
+	ioutil.ReadAll(conn)
+}
@@ -0,0 +1,282 @@
+package spool
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/logging"
+)
+
+// File which holds the number of discarded batches
+const discardedFile = "discarded"
+
+var invalidQueueChars = regexp.MustCompile(`[^a-zA-Z0-9._-]`)
+
+// Batch of events which could not be sent
+type Batch struct {
+	CreatedAt time.Time                `json:"createdAt"`
+	Events    []map[string]interface{} `json:"events"`
+
+	path string
+	size int64
+}
+
+// Durable buffer of batches. Every queue is a subdirectory whose
+// batches are replayed in the order they are written.
+type Spool struct {
+	dir      string
+	maxAge   time.Duration
+	maxBytes int64
+	now      func() time.Time
+	seq      int
+}
+
+func New(
+	dir string,
+	maxAge time.Duration,
+	maxBytes int64,
+) *Spool {
+	return &Spool{
+		dir:      dir,
+		maxAge:   maxAge,
+		maxBytes: maxBytes,
+		now:      time.Now,
+	}
+}
+
+// Writes the events as a new batch at the end of the queue
+func (s *Spool) Write(
+	queue string,
+	events []map[string]interface{},
+) error {
+	queueDir := s.queueDir(queue)
+	if err := os.MkdirAll(queueDir, 0755); err != nil {
+		return errors.New(logging.SPOOL__DIRECTORY_COULD_NOT_BE_CREATED)
+	}
+
+	now := s.now()
+	content, err := json.Marshal(&Batch{
+		CreatedAt: now,
+		Events:    events,
+	})
+	if err != nil {
+		return errors.New(logging.SPOOL__BATCH_COULD_NOT_BE_WRITTEN)
+	}
+
+	// The name keeps the batches in order within the same nanosecond
+	s.seq++
+	name := fmt.Sprintf("%020d-%06d.json", now.UnixNano(), s.seq%1000000)
+
+	// Write into a temporary file first so that a crash does not
+	// leave a partial batch behind
+	tmp := filepath.Join(queueDir, name+".tmp")
+	if err := ioutil.WriteFile(tmp, content, 0644); err != nil {
+		return errors.New(logging.SPOOL__BATCH_COULD_NOT_BE_WRITTEN)
+	}
+	if err := os.Rename(tmp, filepath.Join(queueDir, name)); err != nil {
+		os.Remove(tmp)
+		return errors.New(logging.SPOOL__BATCH_COULD_NOT_BE_WRITTEN)
+	}
+
+	return s.prune()
+}
+
+// Returns the batches of the queue, oldest first
+func (s *Spool) Read(
+	queue string,
+) (
+	[]*Batch,
+	error,
+) {
+	if err := s.prune(); err != nil {
+		return nil, err
+	}
+
+	paths, err := s.listQueue(s.queueDir(queue))
+	if err != nil {
+		return nil, err
+	}
+
+	batches := make([]*Batch, 0, len(paths))
+	for _, path := range paths {
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, errors.New(logging.SPOOL__BATCHES_COULD_NOT_BE_READ)
+		}
+
+		batch := &Batch{}
+		if err := json.Unmarshal(content, batch); err != nil {
+
+			// A corrupt batch can never be sent
+			os.Remove(path)
+			s.addDiscarded(1)
+			continue
+		}
+		batch.path = path
+		batches = append(batches, batch)
+	}
+
+	return batches, nil
+}
+
+// Removes a batch after it is sent
+func (s *Spool) Remove(
+	batch *Batch,
+) error {
+	if err := os.Remove(batch.path); err != nil && !os.IsNotExist(err) {
+		return errors.New(logging.SPOOL__BATCH_COULD_NOT_BE_REMOVED)
+	}
+	return nil
+}
+
+// Removes a batch which can never be sent and counts it as discarded.
+// A batch which has not been written is only counted.
+func (s *Spool) Discard(
+	batch *Batch,
+) error {
+	if err := s.Remove(batch); err != nil {
+		return err
+	}
+	s.addDiscarded(1)
+	return nil
+}
+
+// Returns the number of batches which are discarded because they
+// exceeded the maximum age or size of the spool or were rejected
+func (s *Spool) Discarded() int {
+	content, err := ioutil.ReadFile(filepath.Join(s.dir, discardedFile))
+	if err != nil {
+		return 0
+	}
+	discarded, err := strconv.Atoi(strings.TrimSpace(string(content)))
+	if err != nil {
+		return 0
+	}
+	return discarded
+}
+
+// Discards the expired batches and the oldest batches until the
+// spool fits into its maximum size
+func (s *Spool) prune() error {
+	queues, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return errors.New(logging.SPOOL__BATCHES_COULD_NOT_BE_READ)
+	}
+
+	batches := make([]*Batch, 0)
+	for _, queue := range queues {
+		if !queue.IsDir() {
+			continue
+		}
+		paths, err := s.listQueue(filepath.Join(s.dir, queue.Name()))
+		if err != nil {
+			return err
+		}
+		for _, path := range paths {
+			info, err := os.Stat(path)
+			if err != nil {
+				continue
+			}
+			batches = append(batches, &Batch{
+				CreatedAt: batchTime(path),
+				path:      path,
+				size:      info.Size(),
+			})
+		}
+	}
+
+	// Oldest first across all queues
+	sort.SliceStable(batches, func(i, j int) bool {
+		return filepath.Base(batches[i].path) < filepath.Base(batches[j].path)
+	})
+
+	var total int64
+	for _, batch := range batches {
+		total += batch.size
+	}
+
+	discarded := 0
+	now := s.now()
+	for _, batch := range batches {
+		expired := s.maxAge > 0 && now.Sub(batch.CreatedAt) > s.maxAge
+		tooLarge := s.maxBytes > 0 && total > s.maxBytes
+		if !expired && !tooLarge {
+			continue
+		}
+		if err := os.Remove(batch.path); err != nil && !os.IsNotExist(err) {
+			return errors.New(logging.SPOOL__BATCH_COULD_NOT_BE_REMOVED)
+		}
+		total -= batch.size
+		discarded++
+	}
+
+	s.addDiscarded(discarded)
+	return nil
+}
+
+// Returns the batch files of a queue, oldest first
+func (s *Spool) listQueue(
+	queueDir string,
+) (
+	[]string,
+	error,
+) {
+	files, err := ioutil.ReadDir(queueDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []string{}, nil
+		}
+		return nil, errors.New(logging.SPOOL__BATCHES_COULD_NOT_BE_READ)
+	}
+
+	// ReadDir returns the files sorted by name
+	paths := make([]string, 0, len(files))
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+		paths = append(paths, filepath.Join(queueDir, file.Name()))
+	}
+	return paths, nil
+}
+
+func (s *Spool) queueDir(
+	queue string,
+) string {
+	return filepath.Join(s.dir, invalidQueueChars.ReplaceAllString(queue, "_"))
+}
+
+func (s *Spool) addDiscarded(
+	count int,
+) {
+	if count == 0 {
+		return
+	}
+	total := s.Discarded() + count
+	os.MkdirAll(s.dir, 0755)
+	ioutil.WriteFile(filepath.Join(s.dir, discardedFile), []byte(strconv.Itoa(total)), 0644)
+}
+
+// Returns the creation time which is encoded in the file name
+func batchTime(
+	path string,
+) time.Time {
+	name := filepath.Base(path)
+	nanos, err := strconv.ParseInt(strings.SplitN(name, "-", 2)[0], 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(0, nanos)
+}
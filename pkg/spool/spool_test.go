@@ -0,0 +1,120 @@
+package spool
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_BatchesAreReadInOrder(t *testing.T) {
+	s := New(t.TempDir(), time.Hour, 0)
+
+	for _, value := range []string{"first", "second", "third"} {
+		err := s.Write("newrelic-default", []map[string]interface{}{{"value": value}})
+		assert.Nil(t, err)
+	}
+
+	batches, err := s.Read("newrelic-default")
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(batches))
+	assert.Equal(t, "first", batches[0].Events[0]["value"])
+	assert.Equal(t, "second", batches[1].Events[0]["value"])
+	assert.Equal(t, "third", batches[2].Events[0]["value"])
+
+	// Queues are independent
+	batches, err = s.Read("newrelic-team")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(batches))
+}
+
+func Test_BatchIsRemoved(t *testing.T) {
+	s := New(t.TempDir(), time.Hour, 0)
+
+	err := s.Write("queue", []map[string]interface{}{{"value": "first"}})
+	assert.Nil(t, err)
+
+	batches, err := s.Read("queue")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(batches))
+
+	err = s.Remove(batches[0])
+	assert.Nil(t, err)
+
+	batches, err = s.Read("queue")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(batches))
+	assert.Equal(t, 0, s.Discarded())
+}
+
+func Test_RejectedBatchIsDiscarded(t *testing.T) {
+	s := New(filepath.Join(t.TempDir(), "spool"), time.Hour, 0)
+
+	err := s.Write("queue", []map[string]interface{}{{"value": "first"}})
+	assert.Nil(t, err)
+
+	batches, err := s.Read("queue")
+	assert.Nil(t, err)
+
+	err = s.Discard(batches[0])
+	assert.Nil(t, err)
+
+	batches, err = s.Read("queue")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(batches))
+	assert.Equal(t, 1, s.Discarded())
+
+	// Batches which were never written are counted as well
+	s = New(filepath.Join(t.TempDir(), "spool"), time.Hour, 0)
+	err = s.Discard(&Batch{Events: []map[string]interface{}{{"value": "second"}}})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, s.Discarded())
+}
+
+func Test_ExpiredBatchesAreDiscarded(t *testing.T) {
+	now := time.Now()
+	s := New(t.TempDir(), time.Hour, 0)
+	s.now = func() time.Time { return now }
+
+	err := s.Write("queue", []map[string]interface{}{{"value": "old"}})
+	assert.Nil(t, err)
+
+	now = now.Add(2 * time.Hour)
+	err = s.Write("queue", []map[string]interface{}{{"value": "new"}})
+	assert.Nil(t, err)
+
+	batches, err := s.Read("queue")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(batches))
+	assert.Equal(t, "new", batches[0].Events[0]["value"])
+	assert.Equal(t, 1, s.Discarded())
+}
+
+func Test_OldestBatchesAreDiscardedIfSpoolIsFull(t *testing.T) {
+	dir := t.TempDir()
+
+	// Determine the size of a single batch
+	s := New(dir, time.Hour, 0)
+	err := s.Write("queue", []map[string]interface{}{{"value": "0"}})
+	assert.Nil(t, err)
+	batches, _ := s.Read("queue")
+	info, err := os.Stat(batches[0].path)
+	assert.Nil(t, err)
+	s.Remove(batches[0])
+
+	// Allow two batches only
+	s = New(dir, time.Hour, info.Size()*5/2)
+	for _, value := range []string{"1", "2", "3"} {
+		err := s.Write("queue", []map[string]interface{}{{"value": value}})
+		assert.Nil(t, err)
+	}
+
+	batches, err = s.Read("queue")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(batches))
+	assert.Equal(t, "2", batches[0].Events[0]["value"])
+	assert.Equal(t, "3", batches[1].Events[0]["value"])
+	assert.Equal(t, 1, s.Discarded())
+}
@@ -0,0 +1,76 @@
+package state
+
+import (
+	"hash/fnv"
+	"sort"
+	"strconv"
+
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/config"
+)
+
+// Returns the key of an endpoint which is valid in the data of a
+// config map. The prefix separates the states of the features.
+func EndpointKey(
+	prefix string,
+	endpoint config.Endpoint,
+) string {
+	h := fnv.New64a()
+	h.Write([]byte(endpoint.Type + "\n" + endpoint.Name + "\n" + endpoint.URL))
+	return prefix + strconv.FormatUint(h.Sum64(), 16)
+}
+
+// Returns the values of the configured keys which identify a record
+// across the runs, e.g. the name of a queue
+func RecordIdentity(
+	identity []string,
+	record map[string]string,
+) map[string]string {
+	attributes := map[string]string{}
+	for _, key := range identity {
+		if value, ok := record[key]; ok {
+			attributes[key] = value
+		}
+	}
+	return attributes
+}
+
+// Returns the keys of the records by their identity. Without any
+// configured identity, the records are told apart by their order, so
+// that changing values do not make a record look new. Records with the
+// same identity are told apart by their order as well.
+func RecordKeys(
+	identity []string,
+	records []map[string]string,
+) []string {
+	keys := make([]string, 0, len(records))
+	if len(identity) == 0 {
+		for i := range records {
+			keys = append(keys, strconv.Itoa(i))
+		}
+		return keys
+	}
+
+	seen := map[string]int{}
+	for _, record := range records {
+		attributes := RecordIdentity(identity, record)
+
+		names := make([]string, 0, len(attributes))
+		for name := range attributes {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		h := fnv.New64a()
+		for _, name := range names {
+			h.Write([]byte(name + "=" + attributes[name] + "\n"))
+		}
+		key := strconv.FormatUint(h.Sum64(), 16)
+
+		seen[key]++
+		if seen[key] > 1 {
+			key += "." + strconv.Itoa(seen[key]-1)
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
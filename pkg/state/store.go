@@ -0,0 +1,196 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/config"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/kube"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/logging"
+)
+
+// Persists the state between the runs as a flat map so that it
+// fits into the data of a config map
+type Store interface {
+	Load() (map[string]string, error)
+	Save(data map[string]string) error
+}
+
+// Creates the store of the configured backend
+func NewStore(
+	cfg *config.Config,
+) Store {
+	switch cfg.State.Backend {
+	case config.StateBackendFile:
+		return &fileStore{path: cfg.State.Path}
+	case config.StateBackendConfigMap:
+		return &configMapStore{name: cfg.State.ConfigMap}
+	default:
+		return NewMemoryStore()
+	}
+}
+
+// Keeps the state for the lifetime of the process
+type memoryStore struct {
+	mux  *sync.Mutex
+	data map[string]string
+}
+
+func NewMemoryStore() Store {
+	return &memoryStore{
+		mux:  &sync.Mutex{},
+		data: map[string]string{},
+	}
+}
+
+func (s *memoryStore) Load() (
+	map[string]string,
+	error,
+) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return copyData(s.data), nil
+}
+
+func (s *memoryStore) Save(
+	data map[string]string,
+) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.data = copyData(data)
+	return nil
+}
+
+// Keeps the state in a JSON file, e.g. on a persistent volume
+type fileStore struct {
+	path string
+}
+
+func (s *fileStore) Load() (
+	map[string]string,
+	error,
+) {
+	content, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return map[string]string{}, nil
+		}
+		return nil, errors.New(logging.STATE__COULD_NOT_BE_LOADED)
+	}
+
+	data := map[string]string{}
+	if err := json.Unmarshal(content, &data); err != nil {
+		return nil, errors.New(logging.STATE__COULD_NOT_BE_LOADED)
+	}
+	return data, nil
+}
+
+func (s *fileStore) Save(
+	data map[string]string,
+) error {
+	content, err := json.Marshal(data)
+	if err != nil {
+		return errors.New(logging.STATE__COULD_NOT_BE_SAVED)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return errors.New(logging.STATE__COULD_NOT_BE_SAVED)
+	}
+
+	// Replace the file at once so that a crash does not corrupt it
+	tmp := s.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, content, 0644); err != nil {
+		return errors.New(logging.STATE__COULD_NOT_BE_SAVED)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		os.Remove(tmp)
+		return errors.New(logging.STATE__COULD_NOT_BE_SAVED)
+	}
+	return nil
+}
+
+// Keeps the state in a config map in the namespace of the pod
+type configMapStore struct {
+	name string
+
+	// Created on first use so that the store can be created
+	// outside of a cluster
+	client *kube.Client
+
+	// Version of the last loaded config map, empty if it does not exist
+	resourceVersion string
+}
+
+func (s *configMapStore) Load() (
+	map[string]string,
+	error,
+) {
+	if err := s.connect(); err != nil {
+		return nil, err
+	}
+
+	cm, err := s.client.GetConfigMap(s.client.Namespace(), s.name)
+	if err != nil {
+		if kube.IsNotFound(err) {
+			s.resourceVersion = ""
+			return map[string]string{}, nil
+		}
+		return nil, errors.New(logging.STATE__COULD_NOT_BE_LOADED)
+	}
+
+	s.resourceVersion = cm.Metadata.ResourceVersion
+	if cm.Data == nil {
+		return map[string]string{}, nil
+	}
+	return cm.Data, nil
+}
+
+func (s *configMapStore) Save(
+	data map[string]string,
+) error {
+	if err := s.connect(); err != nil {
+		return err
+	}
+
+	cm := kube.NewConfigMap(s.client.Namespace(), s.name, data)
+
+	var err error
+	if s.resourceVersion == "" {
+		cm, err = s.client.CreateConfigMap(cm)
+	} else {
+		cm.Metadata.ResourceVersion = s.resourceVersion
+		cm, err = s.client.UpdateConfigMap(cm)
+	}
+	if err != nil {
+		return errors.New(logging.STATE__COULD_NOT_BE_SAVED)
+	}
+
+	s.resourceVersion = cm.Metadata.ResourceVersion
+	return nil
+}
+
+func (s *configMapStore) connect() error {
+	if s.client != nil {
+		return nil
+	}
+	client, err := kube.NewDefaultClient()
+	if err != nil {
+		return err
+	}
+	s.client = client
+	return nil
+}
+
+func copyData(
+	data map[string]string,
+) map[string]string {
+	copy := make(map[string]string, len(data))
+	for key, value := range data {
+		copy[key] = value
+	}
+	return copy
+}
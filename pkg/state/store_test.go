@@ -0,0 +1,101 @@
+package state
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/utr1903/newrelic-kubernetes-endpoint-scraper/pkg/kube"
+)
+
+func Test_FileStoreKeepsState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "state.json")
+	store := &fileStore{path: path}
+
+	data, err := store.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(data))
+
+	err = store.Save(map[string]string{"key": "value"})
+	assert.Nil(t, err)
+
+	// A new store of the next run reads the same state
+	data, err = (&fileStore{path: path}).Load()
+	assert.Nil(t, err)
+	assert.Equal(t, "value", data["key"])
+}
+
+func Test_ConfigMapStoreKeepsState(t *testing.T) {
+	var stored *kube.ConfigMap
+	apiMock := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.Method == http.MethodGet && r.URL.Path == "/api/v1/namespaces/monitoring/configmaps/scraper-state":
+				if stored == nil {
+					w.WriteHeader(http.StatusNotFound)
+					w.Write([]byte(`{"kind":"Status","message":"configmaps \"scraper-state\" not found"}`))
+					return
+				}
+				json.NewEncoder(w).Encode(stored)
+
+			case r.Method == http.MethodPost && r.URL.Path == "/api/v1/namespaces/monitoring/configmaps":
+				stored = &kube.ConfigMap{}
+				json.NewDecoder(r.Body).Decode(stored)
+				stored.Metadata.ResourceVersion = "1"
+				w.WriteHeader(http.StatusCreated)
+				json.NewEncoder(w).Encode(stored)
+
+			case r.Method == http.MethodPut && r.URL.Path == "/api/v1/namespaces/monitoring/configmaps/scraper-state":
+				update := &kube.ConfigMap{}
+				json.NewDecoder(r.Body).Decode(update)
+				if update.Metadata.ResourceVersion != stored.Metadata.ResourceVersion {
+					w.WriteHeader(http.StatusConflict)
+					return
+				}
+				stored = update
+				stored.Metadata.ResourceVersion = "2"
+				json.NewEncoder(w).Encode(stored)
+
+			default:
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			}
+		}))
+	defer apiMock.Close()
+
+	client := kube.NewClient(apiMock.URL, "", "monitoring", apiMock.Client())
+	store := &configMapStore{name: "scraper-state", client: client}
+
+	// The config map is created on the first save
+	data, err := store.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(data))
+
+	err = store.Save(map[string]string{"key": "1"})
+	assert.Nil(t, err)
+	assert.Equal(t, "1", stored.Data["key"])
+
+	// The config map is updated afterwards
+	data, err = store.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, "1", data["key"])
+
+	err = store.Save(map[string]string{"key": "2"})
+	assert.Nil(t, err)
+	assert.Equal(t, "2", stored.Data["key"])
+	assert.Equal(t, "2", store.resourceVersion)
+}
+
+func Test_MemoryStoreCopiesState(t *testing.T) {
+	store := NewMemoryStore()
+
+	data := map[string]string{"key": "1"}
+	store.Save(data)
+	data["key"] = "2"
+
+	loaded, err := store.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, "1", loaded["key"])
+}